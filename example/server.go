@@ -34,7 +34,7 @@ func main() {
 	)
 
 	flag.StringVar(&addr, "addr", "localhost:8080",
-		"Address to listen on.")
+		"Address to listen on. IP addresses are served as localhost, since browsers don't accept them as relying party IDs.")
 	flag.BoolVar(&watch, "watch", false,
 		"When provided, live reload web assests rather than serving them statically.")
 	flag.StringVar(&dbPath, "db", filepath.Join(os.TempDir(), "passkey.db"),
@@ -45,10 +45,14 @@ func main() {
 	if watch {
 		staticFS = os.DirFS(".")
 	}
-	host, _, err := net.SplitHostPort(addr)
+	host, port, err := net.SplitHostPort(addr)
 	if err != nil {
 		log.Fatalf("Splitting host and port: %v", err)
 	}
+	if host == "" || net.ParseIP(host) != nil {
+		// WebAuthn doesn't allow IP addresses as relying party IDs.
+		host = "localhost"
+	}
 
 	ctx := context.Background()
 	st, err := newStorage(ctx, dbPath)
@@ -56,16 +60,23 @@ func main() {
 		log.Fatalf("Initializing database: %v", err)
 	}
 
+	rp := &webauthn.RelyingParty{
+		ID:               host,
+		Name:             "go-webauthn",
+		Origin:           "http://" + net.JoinHostPort(host, port),
+		UserVerification: webauthn.UserVerificationRequired,
+	}
+	if err := rp.Validate(); err != nil {
+		log.Fatalf("Invalid relying party configuration: %v", err)
+	}
+
 	s := &server{
 		storage:  st,
 		staticFS: staticFS,
-		rp: &webauthn.RelyingParty{
-			ID:     host,
-			Origin: "http://" + addr,
-		},
+		rp:       rp,
 	}
 	log.Printf("Using database: %s", dbPath)
-	log.Printf("Listening on %s", addr)
+	log.Printf("Listening on %s, visit %s", addr, rp.Origin)
 	log.Fatal(http.ListenAndServe(":8080", s))
 }
 
@@ -306,11 +317,9 @@ func (s *server) handleLoginStart(w http.ResponseWriter, r *http.Request) {
 	// Set the login cookie.
 	s.setCookie(w, r, cookieLoginID, loginID, exp)
 
-	resp := struct {
-		Challenge []byte `json:"challenge"`
-	}{
-		Challenge: challenge,
-	}
+	// The user hasn't been identified yet, so don't provide any credentials
+	// and let the browser prompt for a discoverable credential.
+	resp := s.rp.NewRequestOptions(challenge)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(resp)
 }
@@ -329,24 +338,19 @@ func (s *server) handleLoginFinish(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var req struct {
-		AuthenticatorData []byte `json:"authenticatorData"`
-		ClientDataJSON    []byte `json:"clientDataJSON"`
-		Signature         []byte `json:"signature"`
-		UserHandle        []byte `json:"userHandle"`
-	}
+	var req webauthn.AuthenticationResponse
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "Decoding request: "+err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	p, err := s.storage.getPasskey(r.Context(), req.UserHandle)
+	p, err := s.storage.getPasskey(r.Context(), req.Response.UserHandle)
 	if err != nil {
 		http.Error(w, "Looking up passkey: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	if _, err := s.rp.VerifyAssertion(p.publicKey, p.algorithm, l.challenge, req.ClientDataJSON, req.AuthenticatorData, req.Signature); err != nil {
+	if _, err := s.rp.VerifyAuthenticationResponse(p.publicKey, p.algorithm, l.challenge, &req); err != nil {
 		http.Error(w, "Verifying passkey: "+err.Error(), http.StatusUnauthorized)
 		return
 	}
@@ -412,19 +416,30 @@ func (s *server) handleRegistrationStart(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	resp := struct {
-		Challenge []byte `json:"challenge"`
-		UserID    []byte `json:"userID"`
-	}{
-		Challenge: challenge,
-		UserID:    userHandle,
-	}
+	resp := s.creationOptions(challenge, webauthn.UserEntity{
+		ID:          userHandle,
+		Name:        req.Username,
+		DisplayName: req.Username,
+	})
 
 	s.setCookie(w, r, cookieRegistrationID, registrationID, exp)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(resp)
 }
 
+// creationOptions returns the options passed to navigator.credentials.create()
+// for registering a passkey. The frontend may override the attestation
+// preferences based on the user's selection.
+func (s *server) creationOptions(challenge []byte, user webauthn.UserEntity) *webauthn.CreationOptions {
+	opts := s.rp.NewCreationOptions(challenge, user)
+	opts.AuthenticatorSelection = &webauthn.AuthenticatorSelection{
+		ResidentKey:        webauthn.ResidentKeyRequired,
+		RequireResidentKey: true,
+		UserVerification:   s.rp.UserVerification,
+	}
+	return opts
+}
+
 // handleRegistrationFinish verifies the attestation data of the passkey
 // creation and creates an account if the data is valid.
 func (s *server) handleRegistrationFinish(w http.ResponseWriter, r *http.Request) {
@@ -439,25 +454,28 @@ func (s *server) handleRegistrationFinish(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	var req struct {
-		Transports        []string `json:"transports"`
-		AttestationObject []byte   `json:"attestationObject"`
-		ClientDataJSON    []byte   `json:"clientDataJSON"`
-	}
+	var req webauthn.RegistrationResponse
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "Decoding request: "+err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	authData, err := s.rp.VerifyAttestation(reg.challenge, req.ClientDataJSON, req.AttestationObject)
+	// Attestation statements aren't verified so that passkeys of any provider
+	// can be registered. Use VerifyRegistrationResponse to require trusted
+	// attestation.
+	authData, err := s.rp.VerifyAttestation(reg.challenge, req.Response.ClientDataJSON, req.Response.AttestationObject)
 	if err != nil {
 		http.Error(w, "Failed to verify attestation: "+err.Error(), http.StatusBadRequest)
 		return
 	}
+	if !bytes.Equal(authData.CredentialID, req.RawID) {
+		http.Error(w, "Credential ID doesn't match attestation", http.StatusBadRequest)
+		return
+	}
 
 	passkeyName := "Passkey"
-	if name, ok := authData.AAGUID.Name(); ok {
-		passkeyName = name
+	if info, ok := s.rp.ResolveAAGUID(authData.AAGUID); ok {
+		passkeyName = info.Name
 	}
 
 	p := &passkey{
@@ -468,9 +486,9 @@ func (s *server) handleRegistrationFinish(w http.ResponseWriter, r *http.Request
 		publicKey:         authData.PublicKey,
 		algorithm:         authData.Algorithm,
 		createdAt:         time.Now(),
-		transports:        req.Transports,
-		attestationObject: req.AttestationObject,
-		clientDataJSON:    req.ClientDataJSON,
+		transports:        req.Response.Transports,
+		attestationObject: req.Response.AttestationObject,
+		clientDataJSON:    req.Response.ClientDataJSON,
 	}
 	u := &user{
 		username: reg.username,
@@ -525,22 +543,13 @@ func (s *server) handleReauthStart(w http.ResponseWriter, r *http.Request) {
 	}
 	s.setCookie(w, r, cookieReauthID, reauthID, exp)
 
-	type reauthCredential struct {
-		ID         []byte   `json:"id"`
-		Transports []string `json:"transports"`
-	}
-
-	var creds []reauthCredential
+	resp := s.rp.NewRequestOptions(challenge)
 	for _, pk := range u.passkeys {
-		creds = append(creds, reauthCredential{pk.passkeyID, pk.transports})
-	}
-
-	resp := struct {
-		Credentials []reauthCredential `json:"credentials"`
-		Challenge   []byte             `json:"challenge"`
-	}{
-		Credentials: creds,
-		Challenge:   challenge,
+		resp.AllowCredentials = append(resp.AllowCredentials, webauthn.CredentialDescriptor{
+			Type:       webauthn.PublicKeyCredentialType,
+			ID:         pk.passkeyID,
+			Transports: pk.transports,
+		})
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -571,18 +580,13 @@ func (s *server) handleReauthFinish(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var req struct {
-		AuthenticatorData []byte `json:"authenticatorData"`
-		ClientDataJSON    []byte `json:"clientDataJSON"`
-		Signature         []byte `json:"signature"`
-		UserHandle        []byte `json:"userHandle"`
-	}
+	var req webauthn.AuthenticationResponse
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "Decoding request: "+err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	p, err := s.storage.getPasskey(r.Context(), req.UserHandle)
+	p, err := s.storage.getPasskey(r.Context(), req.Response.UserHandle)
 	if err != nil {
 		http.Error(w, "Looking up passkey: "+err.Error(), http.StatusInternalServerError)
 		return
@@ -592,7 +596,7 @@ func (s *server) handleReauthFinish(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if _, err := s.rp.VerifyAssertion(p.publicKey, p.algorithm, re.challenge, req.ClientDataJSON, req.AuthenticatorData, req.Signature); err != nil {
+	if _, err := s.rp.VerifyAuthenticationResponse(p.publicKey, p.algorithm, re.challenge, &req); err != nil {
 		http.Error(w, "Verifying passkey: "+err.Error(), http.StatusUnauthorized)
 		return
 	}
@@ -614,7 +618,6 @@ func (s *server) handleNewKeyStart(w http.ResponseWriter, r *http.Request) {
 
 	id := base64.RawURLEncoding.EncodeToString(randBytes(16))
 	challenge := randBytes(16)
-	credentialID := randBytes(16)
 	userHandle := randBytes(16)
 
 	exp := time.Now().Add(time.Hour)
@@ -630,23 +633,18 @@ func (s *server) handleNewKeyStart(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var credIDs [][]byte
+	resp := s.creationOptions(challenge, webauthn.UserEntity{
+		ID:          userHandle,
+		Name:        u.username,
+		DisplayName: u.username,
+	})
+	// Prevent the user from registering the same authenticator twice.
 	for _, pk := range u.passkeys {
-		credIDs = append(credIDs, pk.passkeyID)
-	}
-
-	resp := struct {
-		ID        []byte   `json:"credentialID"`
-		IDs       [][]byte `json:"credentialIDs"`
-		Username  string   `json:"username"`
-		UserID    []byte   `json:"userID"`
-		Challenge []byte   `json:"challenge"`
-	}{
-		ID:        credentialID,
-		IDs:       credIDs,
-		Username:  u.username,
-		UserID:    userHandle,
-		Challenge: challenge,
+		resp.ExcludeCredentials = append(resp.ExcludeCredentials, webauthn.CredentialDescriptor{
+			Type:       webauthn.PublicKeyCredentialType,
+			ID:         pk.passkeyID,
+			Transports: pk.transports,
+		})
 	}
 	body, err := json.Marshal(resp)
 	if err != nil {
@@ -683,24 +681,27 @@ func (s *server) handleNewKeyFinish(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var req struct {
-		Transports        []string `json:"transports"`
-		AttestationObject []byte   `json:"attestationObject"`
-		ClientDataJSON    []byte   `json:"clientDataJSON"`
-	}
+	var req webauthn.RegistrationResponse
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "Decoding request: "+err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	authData, err := s.rp.VerifyAttestation(reg.challenge, req.ClientDataJSON, req.AttestationObject)
+	// Attestation statements aren't verified so that passkeys of any provider
+	// can be registered. Use VerifyRegistrationResponse to require trusted
+	// attestation.
+	authData, err := s.rp.VerifyAttestation(reg.challenge, req.Response.ClientDataJSON, req.Response.AttestationObject)
 	if err != nil {
 		http.Error(w, "Failed to verify attestation: "+err.Error(), http.StatusBadRequest)
 		return
 	}
+	if !bytes.Equal(authData.CredentialID, req.RawID) {
+		http.Error(w, "Credential ID doesn't match attestation", http.StatusBadRequest)
+		return
+	}
 	passkeyName := "Passkey"
-	if name, ok := authData.AAGUID.Name(); ok {
-		passkeyName = name
+	if info, ok := s.rp.ResolveAAGUID(authData.AAGUID); ok {
+		passkeyName = info.Name
 	}
 
 	p := &passkey{
@@ -711,9 +712,9 @@ func (s *server) handleNewKeyFinish(w http.ResponseWriter, r *http.Request) {
 		publicKey:         authData.PublicKey,
 		algorithm:         authData.Algorithm,
 		createdAt:         time.Now(),
-		transports:        req.Transports,
-		attestationObject: req.AttestationObject,
-		clientDataJSON:    req.ClientDataJSON,
+		transports:        req.Response.Transports,
+		attestationObject: req.Response.AttestationObject,
+		clientDataJSON:    req.Response.ClientDataJSON,
 	}
 	if err := s.storage.insertPasskey(r.Context(), p); err != nil {
 		http.Error(w, "Saving passkey to database: "+err.Error(), http.StatusInternalServerError)
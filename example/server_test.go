@@ -28,8 +28,10 @@ func newTestServer(t *testing.T) (*http.Client, *httptest.Server, *server) {
 		staticFS: staticFSEmbed,
 		storage:  newTestStorage(t),
 		rp: &webauthn.RelyingParty{
-			ID:     "localhost",
-			Origin: "http://localhost:8080",
+			ID:               "localhost",
+			Name:             "go-webauthn",
+			Origin:           "http://localhost:8080",
+			UserVerification: webauthn.UserVerificationRequired,
 		},
 	}
 
@@ -67,6 +69,23 @@ func TestRegistrationStart(t *testing.T) {
 	if resp.StatusCode != http.StatusOK {
 		t.Fatalf("Response returned unexpected status code: %s: %s", resp.Status, body)
 	}
+
+	var opts webauthn.CreationOptions
+	if err := json.Unmarshal(body, &opts); err != nil {
+		t.Fatalf("Decoding response: %v", err)
+	}
+	if opts.RP.ID != "localhost" || opts.RP.Name != "go-webauthn" {
+		t.Errorf("Unexpected relying party in options: %+v", opts.RP)
+	}
+	if opts.User.Name != "testuser" || len(opts.User.ID) == 0 {
+		t.Errorf("Unexpected user in options: %+v", opts.User)
+	}
+	if len(opts.Challenge) < 16 {
+		t.Errorf("Options returned short challenge: %x", opts.Challenge)
+	}
+	if len(opts.PubKeyCredParams) == 0 {
+		t.Errorf("Options returned no credential parameters")
+	}
 }
 
 var yubikeyDirectAttestationObject = strings.ReplaceAll(`
@@ -95,6 +114,9 @@ BZyIsIm9yaWdpbiI6Imh0dHA6Ly9sb2NhbGhvc3Q6ODA4MCIsImNyb3NzT3JpZ2luIjpmYWxzZSwib3
 RoZXJfa2V5c19jYW5fYmVfYWRkZWRfaGVyZSI6ImRvIG5vdCBjb21wYXJlIGNsaWVudERhdGFKU09OI
 GFnYWluc3QgYSB0ZW1wbGF0ZS4gU2VlIGh0dHBzOi8vZ29vLmdsL3lhYlBleCJ9`, "\n", "")
 
+// Credential ID of the attestation object above.
+const yubikeyDirectCredentialID = "hHXoPh9w0LmLY8eifxLeZ7Mb_7ZLXbNEY53cU-Mdi4jehzrFS8XMwbocAj4JXT0Q"
+
 func TestRegistrationFinish(t *testing.T) {
 	ctx := context.Background()
 	client, srv, s := newTestServer(t)
@@ -117,9 +139,16 @@ func TestRegistrationFinish(t *testing.T) {
 	}
 
 	reqBody := strings.NewReader(`{
-		"transports": ["hybrid", "internal"],
-		"attestationObject": "` + yubikeyDirectAttestationObject + `",
-		"clientDataJSON": "` + yubikeyDirectClientDataJSON + `"
+		"id": "` + yubikeyDirectCredentialID + `",
+		"rawId": "` + yubikeyDirectCredentialID + `",
+		"type": "public-key",
+		"response": {
+			"transports": ["hybrid", "internal"],
+			"attestationObject": "` + base64URLEncode(base64Decode(t, yubikeyDirectAttestationObject)) + `",
+			"clientDataJSON": "` + base64URLEncode(base64Decode(t, yubikeyDirectClientDataJSON)) + `"
+		},
+		"authenticatorAttachment": "cross-platform",
+		"clientExtensionResults": {}
 	}`)
 	req, err := http.NewRequest("POST", srv.URL+"/registration-finish", reqBody)
 	if err != nil {
@@ -206,6 +235,10 @@ func base64Decode(t *testing.T, s string) []byte {
 	return data
 }
 
+func base64URLEncode(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
 func base64URLDecode(t *testing.T, s string) []byte {
 	t.Helper()
 
@@ -277,12 +310,17 @@ func TestLoginFinish(t *testing.T) {
 		t.Fatalf("Generating signature: %v", err)
 	}
 
-	reqBody := struct {
-		AuthenticatorData []byte `json:"authenticatorData"`
-		ClientDataJSON    []byte `json:"clientDataJSON"`
-		Signature         []byte `json:"signature"`
-		UserHandle        []byte `json:"userHandle"`
-	}{authenticatorData, clientDataJSON, sig, []byte("testuserhandle")}
+	reqBody := &webauthn.AuthenticationResponse{
+		ID:    base64URLEncode([]byte("testkeyid")),
+		RawID: []byte("testkeyid"),
+		Type:  webauthn.PublicKeyCredentialType,
+		Response: webauthn.AuthenticatorAssertionResponse{
+			ClientDataJSON:    clientDataJSON,
+			AuthenticatorData: authenticatorData,
+			Signature:         sig,
+			UserHandle:        []byte("testuserhandle"),
+		},
+	}
 	reqBodyBytes, err := json.Marshal(reqBody)
 	if err != nil {
 		t.Fatalf("Encoding request body: %v", err)
@@ -370,13 +408,16 @@ func TestReauth(t *testing.T) {
 		t.Fatalf("Response returned unexpected status code: %s: %s", resp.Status, body)
 	}
 
-	var respBody struct {
-		CredentialIDs [][]byte `json:"credentialIDs"`
-		Challenge     []byte   `json:"challenge"`
-	}
+	var respBody webauthn.RequestOptions
 	if err := json.Unmarshal(body, &respBody); err != nil {
 		t.Fatalf("Decoding response: %v", err)
 	}
+	if len(respBody.AllowCredentials) != 1 || string(respBody.AllowCredentials[0].ID) != "testkeyid" {
+		t.Errorf("Unexpected allowed credentials: %+v", respBody.AllowCredentials)
+	}
+	if respBody.UserVerification != webauthn.UserVerificationRequired {
+		t.Errorf("Unexpected user verification, got=%s, want=%s", respBody.UserVerification, webauthn.UserVerificationRequired)
+	}
 
 	authenticatorData := base64Decode(t, "SZYN5YgOjGh0NBcPZHZgW4/krrmihjLHmVzzuoMdl2MdAAAAAA==")
 
@@ -404,12 +445,17 @@ func TestReauth(t *testing.T) {
 		t.Fatalf("Generating signature: %v", err)
 	}
 
-	finishReqBody := struct {
-		AuthenticatorData []byte `json:"authenticatorData"`
-		ClientDataJSON    []byte `json:"clientDataJSON"`
-		Signature         []byte `json:"signature"`
-		UserHandle        []byte `json:"userHandle"`
-	}{authenticatorData, clientDataJSON, sig, []byte("testuserhandle")}
+	finishReqBody := &webauthn.AuthenticationResponse{
+		ID:    base64URLEncode([]byte("testkeyid")),
+		RawID: []byte("testkeyid"),
+		Type:  webauthn.PublicKeyCredentialType,
+		Response: webauthn.AuthenticatorAssertionResponse{
+			ClientDataJSON:    clientDataJSON,
+			AuthenticatorData: authenticatorData,
+			Signature:         sig,
+			UserHandle:        []byte("testuserhandle"),
+		},
+	}
 	finishReqBodyBytes, err := json.Marshal(finishReqBody)
 	if err != nil {
 		t.Fatalf("Encoding request body: %v", err)
@@ -497,9 +543,7 @@ func TestRegisterKey(t *testing.T) {
 		t.Fatalf("Response returned unexpected status code: %s: %s", resp.Status, body)
 	}
 
-	var respData struct {
-		Challenge []byte `json:"challenge"`
-	}
+	var respData webauthn.CreationOptions
 	if err := json.Unmarshal(body, &respData); err != nil {
 		t.Fatalf("Decoding response: %v", err)
 	}
@@ -508,8 +552,13 @@ func TestRegisterKey(t *testing.T) {
 	clientDataJSON := `{"type":"webauthn.create","challenge":"` + ch + `","origin":"http://localhost:8080","crossOrigin":false}`
 
 	finishReqBodyBytes := strings.NewReader(`{
-		"attestationObject": "` + yubikeyDirectAttestationObject + `",
-		"clientDataJSON": "` + base64.StdEncoding.EncodeToString([]byte(clientDataJSON)) + `"
+		"id": "` + yubikeyDirectCredentialID + `",
+		"rawId": "` + yubikeyDirectCredentialID + `",
+		"type": "public-key",
+		"response": {
+			"attestationObject": "` + base64URLEncode(base64Decode(t, yubikeyDirectAttestationObject)) + `",
+			"clientDataJSON": "` + base64URLEncode([]byte(clientDataJSON)) + `"
+		}
 	}`)
 
 	finishReq, err := http.NewRequest("POST", srv.URL+"/register-key-finish", finishReqBodyBytes)
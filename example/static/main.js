@@ -56,6 +56,86 @@ function appHideReauth() {
 }
 window.appHideReauth = appHideReauth;
 
+// base64urlDecode decodes an unpadded base64url string, as used by the JSON
+// encoding of WebAuthn options.
+function base64urlDecode(s) {
+	const b64 = s.replace(/-/g, "+").replace(/_/g, "/");
+	return Uint8Array.from(atob(b64), c => c.charCodeAt(0));
+}
+
+// base64urlEncode encodes bytes as an unpadded base64url string.
+function base64urlEncode(b) {
+	const b64 = btoa(String.fromCharCode(...new Uint8Array(b)));
+	return b64.replace(/\+/g, "-").replace(/\//g, "_").replace(/=+$/, "");
+}
+
+// credentialToJSON serializes the result of navigator.credentials.create() or
+// navigator.credentials.get() as RegistrationResponseJSON or
+// AuthenticationResponseJSON.
+//
+// https://developer.mozilla.org/en-US/docs/Web/API/PublicKeyCredential/toJSON
+function credentialToJSON(cred) {
+	if (cred.toJSON) {
+		return cred.toJSON();
+	}
+	const json = {
+		id: cred.id,
+		rawId: base64urlEncode(cred.rawId),
+		type: cred.type,
+		authenticatorAttachment: cred.authenticatorAttachment,
+		clientExtensionResults: cred.getClientExtensionResults(),
+		response: {
+			clientDataJSON: base64urlEncode(cred.response.clientDataJSON),
+		},
+	};
+	if (cred.response.attestationObject) {
+		json.response.attestationObject = base64urlEncode(cred.response.attestationObject);
+		json.response.transports = cred.response.getTransports();
+	} else {
+		json.response.authenticatorData = base64urlEncode(cred.response.authenticatorData);
+		json.response.signature = base64urlEncode(cred.response.signature);
+		if (cred.response.userHandle) {
+			json.response.userHandle = base64urlEncode(cred.response.userHandle);
+		}
+	}
+	return json;
+}
+
+// creationOptionsFromJSON converts PublicKeyCredentialCreationOptionsJSON
+// returned by the server into options for navigator.credentials.create().
+//
+// https://developer.mozilla.org/en-US/docs/Web/API/PublicKeyCredential/parseCreationOptionsFromJSON_static
+function creationOptionsFromJSON(json) {
+	if (PublicKeyCredential.parseCreationOptionsFromJSON) {
+		return PublicKeyCredential.parseCreationOptionsFromJSON(json);
+	}
+	return {
+		...json,
+		challenge: base64urlDecode(json.challenge),
+		user: {...json.user, id: base64urlDecode(json.user.id)},
+		excludeCredentials: (json.excludeCredentials || []).map((c) => {
+			return {...c, id: base64urlDecode(c.id)};
+		}),
+	};
+}
+
+// requestOptionsFromJSON converts PublicKeyCredentialRequestOptionsJSON
+// returned by the server into options for navigator.credentials.get().
+//
+// https://developer.mozilla.org/en-US/docs/Web/API/PublicKeyCredential/parseRequestOptionsFromJSON_static
+function requestOptionsFromJSON(json) {
+	if (PublicKeyCredential.parseRequestOptionsFromJSON) {
+		return PublicKeyCredential.parseRequestOptionsFromJSON(json);
+	}
+	return {
+		...json,
+		challenge: base64urlDecode(json.challenge),
+		allowCredentials: (json.allowCredentials || []).map((c) => {
+			return {...c, id: base64urlDecode(c.id)};
+		}),
+	};
+}
+
 window.appRegister = async function() {
 	appHideError();
     const username = document.getElementById("register_username").value;
@@ -76,61 +156,24 @@ window.appRegister = async function() {
             return;
         }
 
+        // Options are generated by the server, with attestation preferences
+        // taken from the page.
+        //
+        // https://developer.mozilla.org/en-US/docs/Web/API/PublicKeyCredentialCreationOptions
         const body = await resp.json();
-        const challenge = Uint8Array.from(atob(body.challenge), c => c.charCodeAt(0));
-        const userID = Uint8Array.from(atob(body.userID), c => c.charCodeAt(0));
-
-		const attestation = document.querySelector("input[name='attestation']:checked").value;
-		const attestationFormats = Array.from(
+		body.attestation = document.querySelector("input[name='attestation']:checked").value;
+		body.attestationFormats = Array.from(
 			document.querySelectorAll(".attestation-format:checked")).map((el) => el.value);
 
-        // https://developer.mozilla.org/en-US/docs/Web/API/PublicKeyCredential
-        // https://developer.mozilla.org/en-US/docs/Web/API/PublicKeyCredentialCreationOptions
         const opts = {
-            publicKey: {
-                challenge: challenge,
-                rp: {
-                    id: "localhost",
-                    name: "go-webauthn",
-                },
-                attestation: attestation,
-				attestationFormats: attestationFormats,
-                user: {
-                    id: userID,
-                    name: username,
-                    displayName: username,
-                },
-                // https://chromium.googlesource.com/chromium/src/+/main/content/browser/webauth/pub_key_cred_params.md
-                // https://www.w3.org/TR/webauthn-2/#typedefdef-cosealgorithmidentifier
-                pubKeyCredParams: [
-                    {
-                        type: "public-key",
-                        alg: -7,
-                    },
-                    {
-                        type: "public-key",
-                        alg: -257,
-                    },
-                ],
-                authenticatorSelection: {
-                    requireResidentKey: true,
-                    residentKey: "required",
-                },
-            },
+            publicKey: creationOptionsFromJSON(body),
         };
 		console.log(opts);
         let cred = await navigator.credentials.create(opts);
 
-        const attestationObject = btoa(String.fromCharCode(...new Uint8Array(cred.response.attestationObject)));
-        const clientDataJSON = btoa(String.fromCharCode(...new Uint8Array(cred.response.clientDataJSON)));
-
         const finishResp = await fetch("/registration-finish", {
             method: "POST",
-            body: JSON.stringify({
-				transports: cred.response.getTransports(),
-                attestationObject: attestationObject,
-                clientDataJSON: clientDataJSON,
-            }),
+            body: JSON.stringify(credentialToJSON(cred)),
         });
         if (!resp.ok) {
             err(await resp.text());
@@ -156,29 +199,13 @@ window.appLogin = async function() {
         }
 
         const body = await resp.json();
-        const challenge = Uint8Array.from(atob(body.challenge), c => c.charCodeAt(0));
-
 		const cred = await navigator.credentials.get({
-           publicKey: {
-			   challenge: challenge,
-               rpId: "localhost",
-               userVerification: "required",
-		   },
+           publicKey: requestOptionsFromJSON(body),
 		});
 
-        const authenticatorData = btoa(String.fromCharCode(...new Uint8Array(cred.response.authenticatorData)));
-        const clientDataJSON = btoa(String.fromCharCode(...new Uint8Array(cred.response.clientDataJSON)));
-        const signature = btoa(String.fromCharCode(...new Uint8Array(cred.response.signature)));
-        const userHandle = btoa(String.fromCharCode(...new Uint8Array(cred.response.userHandle)));
-
         const finishResp = await fetch("/login-finish", {
             method: "POST",
-            body: JSON.stringify({
-				authenticatorData: authenticatorData,
-				clientDataJSON: clientDataJSON,
-				signature: signature,
-				userHandle: userHandle,
-            }),
+            body: JSON.stringify(credentialToJSON(cred)),
         });
         if (!finishResp.ok) {
             err(await finishResp.text());
@@ -205,47 +232,27 @@ window.appReauth = async function() {
             return;
         }
 
+        // Options are generated by the server, listing all of the user's
+        // credentials. Narrow them to the keys and hints selected on the page.
         const body = await resp.json();
-        const challenge = Uint8Array.from(atob(body.challenge), c => c.charCodeAt(0));
-
-		const hints = Array.from(
+		body.hints = Array.from(
 			document.querySelectorAll(".reauth-hint:checked")).map((el) => el.value);
-		const creds = Array.from(document.getElementsByClassName("reauth-key-id")).
+		const selected = Array.from(document.getElementsByClassName("reauth-key-id")).
 			filter((el) => el.checked).
-			map((el) => {
-                return {
-				  id: Uint8Array.from(atob(el.getAttribute("value")), c => c.charCodeAt(0)),
-  			      type: "public-key",
-				  transports: el.getAttribute("data-transport").split(/[ ,]+/).filter((s) => s !== ''),
-				};
-			});
+			map((el) => el.getAttribute("value").replace(/\+/g, "-").replace(/\//g, "_").replace(/=+$/, ""));
+		body.allowCredentials = (body.allowCredentials || []).filter((c) => selected.includes(c.id));
 
 		const opts = {
-		   hints: hints,
-           publicKey: {
-			   challenge: challenge,
-               rpId: "localhost",
-               userVerification: "required",
-			   allowcredentials: creds,
-		   },
+           publicKey: requestOptionsFromJSON(body),
 		};
 		console.log(opts);
 
 		const cred = await navigator.credentials.get(opts);
 
-        const authenticatorData = btoa(String.fromCharCode(...new Uint8Array(cred.response.authenticatorData)));
-        const clientDataJSON = btoa(String.fromCharCode(...new Uint8Array(cred.response.clientDataJSON)));
-        const signature = btoa(String.fromCharCode(...new Uint8Array(cred.response.signature)));
-        const userHandle = btoa(String.fromCharCode(...new Uint8Array(cred.response.userHandle)));
-
+        const finishReq = credentialToJSON(cred);
         const finishResp = await fetch("/reauth-finish", {
             method: "POST",
-            body: JSON.stringify({
-				authenticatorData: authenticatorData,
-				clientDataJSON: clientDataJSON,
-				signature: signature,
-				userHandle: userHandle,
-            }),
+            body: JSON.stringify(finishReq),
         });
         if (!finishResp.ok) {
             err(await finishResp.text());
@@ -256,9 +263,9 @@ window.appReauth = async function() {
 
 		document.getElementById("reauth-challenge").textContent = body.challenge;
 		document.getElementById("reauth-client-data").textContent = dec.decode(cred.response.clientDataJSON);
-		document.getElementById("reauth-auth-data").textContent = authenticatorData;
-		document.getElementById("reauth-signature").textContent = signature;
-		document.getElementById("reauth-user-handle").textContent = userHandle;
+		document.getElementById("reauth-auth-data").textContent = finishReq.response.authenticatorData;
+		document.getElementById("reauth-signature").textContent = finishReq.response.signature;
+		document.getElementById("reauth-user-handle").textContent = finishReq.response.userHandle;
 		document.getElementById("reauth-dialog").style.display = "block";
     } catch (error) {
         err(error);
@@ -277,69 +284,25 @@ window.appRegisterKey = async function() {
             return;
         }
 
+        // Options are generated by the server, including the set of existing
+        // credentials to exclude, with attestation preferences taken from the
+        // page.
+        //
+        // https://developer.mozilla.org/en-US/docs/Web/API/PublicKeyCredentialCreationOptions
         const body = await resp.json();
-        const challenge = Uint8Array.from(atob(body.challenge), c => c.charCodeAt(0));
-        const credID = Uint8Array.from(atob(body.credentialID), c => c.charCodeAt(0));
-        const userID = Uint8Array.from(atob(body.userID), c => c.charCodeAt(0));
-		const creds = body.credentialIDs.map((credID) => {
-			return {
-				type: "public-key",
-			    id: Uint8Array.from(atob(credID), c => c.charCodeAt(0)),
-			};
-		});
-
-		const attestation = document.querySelector("input[name='attestation']:checked").value;
-		const attestationFormats = Array.from(
+		body.attestation = document.querySelector("input[name='attestation']:checked").value;
+		body.attestationFormats = Array.from(
 			document.querySelectorAll(".attestation-format:checked")).map((el) => el.value);
 
-        // https://developer.mozilla.org/en-US/docs/Web/API/PublicKeyCredential
-        // https://developer.mozilla.org/en-US/docs/Web/API/PublicKeyCredentialCreationOptions
         const opts = {
-            publicKey: {
-                challenge: challenge,
-				excludeCredentials: creds,
-                rp: {
-                    id: "localhost",
-                    name: "go-webauthn",
-                },
-                attestation: attestation,
-				attestationFormats: attestationFormats,
-                user: {
-                    id: userID,
-                    name: body.username,
-                    displayName: body.username,
-                },
-                // https://chromium.googlesource.com/chromium/src/+/main/content/browser/webauth/pub_key_cred_params.md
-                // https://www.w3.org/TR/webauthn-2/#typedefdef-cosealgorithmidentifier
-                pubKeyCredParams: [
-                    {
-                        type: "public-key",
-                        alg: -7,
-                    },
-                    {
-                        type: "public-key",
-                        alg: -257,
-                    },
-                ],
-                authenticatorSelection: {
-                    requireResidentKey: true,
-                    residentKey: "required",
-                },
-            },
-        }
+            publicKey: creationOptionsFromJSON(body),
+        };
 		console.log(opts);
         const cred = await navigator.credentials.create(opts);
 
-        const attestationObject = btoa(String.fromCharCode(...new Uint8Array(cred.response.attestationObject)));
-        const clientDataJSON = btoa(String.fromCharCode(...new Uint8Array(cred.response.clientDataJSON)));
-
         const finishResp = await fetch("/register-key-finish", {
             method: "POST",
-            body: JSON.stringify({
-				transports: cred.response.getTransports(),
-                attestationObject: attestationObject,
-                clientDataJSON: clientDataJSON,
-            }),
+            body: JSON.stringify(credentialToJSON(cred)),
         });
         if (!resp.ok) {
             err(await resp.text());
@@ -3,6 +3,7 @@ package webauthn
 import (
 	"encoding/hex"
 	"fmt"
+	"slices"
 )
 
 // AAGUID identifies an authenticator or specific authenticator model.
@@ -30,6 +31,78 @@ func (a AAGUID) Name() (string, bool) {
 	return "", false
 }
 
+// aaguidIcons holds data URIs of icons for light and dark themes.
+type aaguidIcons struct {
+	light string
+	dark  string
+}
+
+// Icons returns data URIs of icons for the authenticator model, intended for
+// light and dark themes respectively, as listed by
+// https://github.com/passkeydeveloper/passkey-authenticator-aaguids.
+func (a AAGUID) Icons() (light, dark string, ok bool) {
+	icons, ok := passkeyAuthenticatorIcons[a]
+	return icons.light, icons.dark, ok
+}
+
+// AuthenticatorVersion returns the latest firmware version of the
+// authenticator model listed by the FIDO Alliance Metadata Service.
+func (a AAGUID) AuthenticatorVersion() (uint32, bool) {
+	v, ok := metadataAuthenticatorVersions[a]
+	return v, ok
+}
+
+// Algorithms returns the credential algorithms supported by the authenticator
+// model, as listed by the FIDO Alliance Metadata Service.
+func (a AAGUID) Algorithms() ([]Algorithm, bool) {
+	algs, ok := metadataAlgorithms[a]
+	return slices.Clone(algs), ok
+}
+
+// KeyProtection returns how the authenticator model protects keys, such as
+// "hardware" or "secure_element", as listed by the FIDO Alliance Metadata
+// Service.
+//
+// https://fidoalliance.org/specs/common-specs/fido-registry-v2.2-ps-20220523.html#key-protection-types
+func (a AAGUID) KeyProtection() ([]string, bool) {
+	p, ok := metadataKeyProtection[a]
+	return slices.Clone(p), ok
+}
+
+// syncedPasskeyProviderAAGUIDs holds entries of
+// https://github.com/passkeydeveloper/passkey-authenticator-aaguids for
+// password managers and platforms that sync passkeys between a user's devices,
+// according to each provider's documentation. The upstream list doesn't record
+// this, so it's maintained by hand: when aaguid_names.go is regenerated, review
+// new entries of passkeyAuthenticatorAAGUIDs and add synced providers here.
+var syncedPasskeyProviderAAGUIDs = map[AAGUID]bool{
+	mustParseAAGUID("0ea242b4-43c4-4a1b-8b17-dd6d0b6baec6"): true, // Keeper
+	mustParseAAGUID("22248c4c-7a12-46e2-9a41-44291b373a4d"): true, // LogMeOnce
+	mustParseAAGUID("50726f74-6f6e-5061-7373-50726f746f6e"): true, // Proton Pass
+	mustParseAAGUID("531126d6-e717-415c-9320-3d9aa6981239"): true, // Dashlane
+	mustParseAAGUID("53414d53-554e-4700-0000-000000000000"): true, // Samsung Pass
+	mustParseAAGUID("a10c6dd9-465e-4226-8198-c7c44b91c555"): true, // Kaspersky Password Manager
+	mustParseAAGUID("b35a26b2-8f6e-4697-ab1d-d44db4da28c6"): true, // Zoho Vault
+	mustParseAAGUID("b78a0a55-6ef8-d246-a042-ba0f6d55050c"): true, // LastPass
+	mustParseAAGUID("b84e4048-15dc-4dd0-8640-f4f60813c8af"): true, // NordPass
+	mustParseAAGUID("bada5566-a7aa-401f-bd96-45619a55120d"): true, // 1Password
+	mustParseAAGUID("bfc748bb-3429-4faa-b9f9-7cfa9f3b76d0"): true, // iPasswords
+	mustParseAAGUID("d548826e-79b4-db40-a3d8-11116f7e8349"): true, // Bitwarden
+	mustParseAAGUID("dd4ec289-e01d-41c9-bb89-70fa845d4bf2"): true, // iCloud Keychain (Managed)
+	mustParseAAGUID("de503f9c-21a4-4f76-b4b7-558eb55c6f89"): true, // Devolutions
+	mustParseAAGUID("ea9b8d66-4d01-1d21-3ce4-b6b48cb575d4"): true, // Google Password Manager
+	mustParseAAGUID("f3809540-7f14-49c1-a8b3-8f813b225541"): true, // Enpass
+	mustParseAAGUID("fbfc3007-154e-4ecc-8c0b-6e020557d7bd"): true, // iCloud Keychain
+	mustParseAAGUID("fdb141b2-5d84-443e-8a35-4698c205a502"): true, // KeePassXC
+}
+
+// SyncedPasskeyProvider reports if the AAGUID belongs to a known password
+// manager or platform that syncs passkeys between a user's devices, such as
+// "iCloud Keychain" or "Google Password Manager".
+func (a AAGUID) SyncedPasskeyProvider() bool {
+	return syncedPasskeyProviderAAGUIDs[a]
+}
+
 func mustParseAAGUID(s string) AAGUID {
 	aaguid, err := ParseAAGUID(s)
 	if err != nil {
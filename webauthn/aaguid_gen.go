@@ -5,8 +5,9 @@ package main
 import (
 	"bytes"
 	"cmp"
-	"encoding/base64"
+	"crypto/x509"
 	"encoding/json"
+	"flag"
 	"fmt"
 	"go/format"
 	"io"
@@ -14,8 +15,11 @@ import (
 	"net/http"
 	"os"
 	"slices"
-	"strings"
 	"text/template"
+	"time"
+
+	"github.com/go-passkeys/go-passkeys/webauthn"
+	"github.com/go-passkeys/go-passkeys/webauthn/metadata"
 )
 
 var tmpl = template.Must(template.New("").Parse(`package webauthn
@@ -26,20 +30,77 @@ var tmpl = template.Must(template.New("").Parse(`package webauthn
 
 // AAGUIDs listed by https://github.com/passkeydeveloper/passkey-authenticator-aaguids.
 var passkeyAuthenticatorAAGUIDs = map[AAGUID]string{
-	{{ range .PasskeyAuthenticatorAAGUIDs }}mustParseAAGUID("{{ .AAGUID }}"): "{{ .Name }}",
+	{{ range .PasskeyAuthenticatorAAGUIDs }}mustParseAAGUID("{{ .AAGUID }}"): {{ printf "%q" .Name }},
+	{{ end }}
+}
+
+// Icons listed by https://github.com/passkeydeveloper/passkey-authenticator-aaguids.
+var passkeyAuthenticatorIcons = map[AAGUID]aaguidIcons{
+	{{ range .PasskeyAuthenticatorIcons }}mustParseAAGUID("{{ .AAGUID }}"): {light: {{ printf "%q" .Light }}, dark: {{ printf "%q" .Dark }}},
 	{{ end }}
 }
 
 // AAGUIDs listed by https://fidoalliance.org/metadata/.
 var metadataAAGUIDs = map[AAGUID]string{
-	{{ range .MetadataAAGUIDs }}mustParseAAGUID("{{ .AAGUID }}"): "{{ .Name }}",
+	{{ range .MetadataAAGUIDs }}mustParseAAGUID("{{ .AAGUID }}"): {{ printf "%q" .Name }},
 	{{ end }}
 }
+
+// Authenticator versions listed by https://fidoalliance.org/metadata/.
+var metadataAuthenticatorVersions = map[AAGUID]uint32{
+	{{ range .MetadataDetails }}mustParseAAGUID("{{ .AAGUID }}"): {{ .AuthenticatorVersion }},
+	{{ end }}
+}
+
+// Supported algorithms listed by https://fidoalliance.org/metadata/.
+var metadataAlgorithms = map[AAGUID][]Algorithm{
+	{{ range .MetadataDetails }}{{ if .Algorithms }}mustParseAAGUID("{{ .AAGUID }}"): { {{- range $i, $alg := .Algorithms }}{{ if $i }}, {{ end }}{{ printf "%d" $alg }}{{ end -}} },
+	{{ end }}{{ end }}
+}
+
+// Key protection listed by https://fidoalliance.org/metadata/.
+var metadataKeyProtection = map[AAGUID][]string{
+	{{ range .MetadataDetails }}{{ if .KeyProtection }}mustParseAAGUID("{{ .AAGUID }}"): { {{- range $i, $p := .KeyProtection }}{{ if $i }}, {{ end }}{{ printf "%q" $p }}{{ end -}} },
+	{{ end }}{{ end }}
+}
 `))
 
 const (
 	passkeyAuthenticatorGitHub = "https://raw.githubusercontent.com/passkeydeveloper/passkey-authenticator-aaguids/refs/heads/main/aaguid.json"
-	metadata                   = "https://mds3.fidoalliance.org/"
+)
+
+// globalSignRootCAR3 is the root certificate of the FIDO Alliance Metadata
+// Service.
+//
+// https://fidoalliance.org/metadata/
+const globalSignRootCAR3 = `-----BEGIN CERTIFICATE-----
+MIIDXzCCAkegAwIBAgILBAAAAAABIVhTCKIwDQYJKoZIhvcNAQELBQAwTDEgMB4G
+A1UECxMXR2xvYmFsU2lnbiBSb290IENBIC0gUjMxEzARBgNVBAoTCkdsb2JhbFNp
+Z24xEzARBgNVBAMTCkdsb2JhbFNpZ24wHhcNMDkwMzE4MTAwMDAwWhcNMjkwMzE4
+MTAwMDAwWjBMMSAwHgYDVQQLExdHbG9iYWxTaWduIFJvb3QgQ0EgLSBSMzETMBEG
+A1UEChMKR2xvYmFsU2lnbjETMBEGA1UEAxMKR2xvYmFsU2lnbjCCASIwDQYJKoZI
+hvcNAQEBBQADggEPADCCAQoCggEBAMwldpB5BngiFvXAg7aEyiie/QV2EcWtiHL8
+RgJDx7KKnQRfJMsuS+FggkbhUqsMgUdwbN1k0ev1LKMPgj0MK66X17YUhhB5uzsT
+gHeMCOFJ0mpiLx9e+pZo34knlTifBtc+ycsmWQ1z3rDI6SYOgxXG71uL0gRgykmm
+KPZpO/bLyCiR5Z2KYVc3rHQU3HTgOu5yLy6c+9C7v/U9AOEGM+iCK65TpjoWc4zd
+QQ4gOsC0p6Hpsk+QLjJg6VfLuQSSaGjlOCZgdbKfd/+RFO+uIEn8rUAVSNECMWEZ
+XriX7613t2Saer9fwRPvm2L7DWzgVGkWqQPabumDk3F2xmmFghcCAwEAAaNCMEAw
+DgYDVR0PAQH/BAQDAgEGMA8GA1UdEwEB/wQFMAMBAf8wHQYDVR0OBBYEFI/wS3+o
+LkUkrk1Q+mOai97i3Ru8MA0GCSqGSIb3DQEBCwUAA4IBAQBLQNvAUKr+yAzv95ZU
+RUm7lgAJQayzE4aGKAczymvmdLm6AC2upArT9fHxD4q/c2dKg8dEe3jgr25sbwMp
+jjM5RcOO5LlXbKr8EpbsU8Yt5CRsuZRj+9xTaGdWPoO4zzUhw8lo/s7awlOqzJCK
+6fBdRoyV3XpYKBovHd7NADdBj+1EbddTKJd+82cEHhXXipa0095MJ6RMG3NzdvQX
+mcIfeg7jLQitChws/zyrVQ4PkX4268NXSb7hLi18YIvDQVETI53O9zJrlAGomecs
+Mx86OyXShkDOOyyGeMlhLxS67ttVb9+E7gUJTb0o2HLO02JQZR7rkpeDMdmztcpH
+WD9f
+-----END CERTIFICATE-----`
+
+var (
+	flagPasskeyAAGUIDs = flag.String("passkey-aaguids", "", "Path to a local passkey-authenticator-aaguids aaguid.json file. If unset, the file is fetched from GitHub.")
+	flagMetadata       = flag.String("mds", "", "Path to a local FIDO Metadata Service BLOB. If unset, the BLOB is fetched from "+metadata.URL+".")
+	flagMetadataRoot   = flag.String("mds-root", "", "Path to a PEM encoded root certificate for the metadata BLOB. If unset, GlobalSign Root CA - R3 is used.")
+	flagMetadataTime   = flag.String("mds-time", "", "Time, in RFC 3339 format, to verify the metadata BLOB at. If unset, the current time is used.")
+	flagOut            = flag.String("o", "aaguid_names.go", "Path of the generated file.")
 )
 
 type aaguidName struct {
@@ -55,23 +116,44 @@ func compareAAGUIDName(a1, a2 aaguidName) int {
 	)
 }
 
+type aaguidIcons struct {
+	AAGUID string
+	Light  string
+	Dark   string
+}
+
+type metadataDetails struct {
+	AAGUID               string
+	AuthenticatorVersion uint32
+	Algorithms           []webauthn.Algorithm
+	KeyProtection        []string
+}
+
 func run() error {
-	passkeyAAGUIDs, err := parsePasskeyAuthenticatorAAGUIDs()
+	passkeyAAGUIDs, icons, err := parsePasskeyAuthenticatorAAGUIDs()
 	if err != nil {
 		return fmt.Errorf("parsing passkey authenticator aaguids: %v", err)
 	}
 	slices.SortFunc(passkeyAAGUIDs, compareAAGUIDName)
+	slices.SortFunc(icons, func(i1, i2 aaguidIcons) int {
+		return cmp.Compare(i1.AAGUID, i2.AAGUID)
+	})
 
-	mdAAGUIDs, err := parseMetadata()
+	mdAAGUIDs, details, err := parseMetadata()
 	if err != nil {
 		return fmt.Errorf("parsing metadata: %v", err)
 	}
 	slices.SortFunc(mdAAGUIDs, compareAAGUIDName)
+	slices.SortFunc(details, func(d1, d2 metadataDetails) int {
+		return cmp.Compare(d1.AAGUID, d2.AAGUID)
+	})
 
 	data := struct {
 		PasskeyAuthenticatorAAGUIDs []aaguidName
+		PasskeyAuthenticatorIcons   []aaguidIcons
 		MetadataAAGUIDs             []aaguidName
-	}{passkeyAAGUIDs, mdAAGUIDs}
+		MetadataDetails             []metadataDetails
+	}{passkeyAAGUIDs, icons, mdAAGUIDs, details}
 
 	buff := &bytes.Buffer{}
 	if err := tmpl.Execute(buff, data); err != nil {
@@ -83,16 +165,21 @@ func run() error {
 		return fmt.Errorf("formatting go file: %v", err)
 	}
 
-	if err := os.WriteFile("aaguid_names.go", srcBytes, 0644); err != nil {
+	if err := os.WriteFile(*flagOut, srcBytes, 0644); err != nil {
 		return fmt.Errorf("writing aaguid file: %v", err)
 	}
 	return nil
 }
 
-func parseMetadata() ([]aaguidName, error) {
-	resp, err := http.Get(metadata)
+// readSource reads a local file if a path is provided, or fetches the URL
+// otherwise.
+func readSource(path, url string) ([]byte, error) {
+	if path != "" {
+		return os.ReadFile(path)
+	}
+	resp, err := http.Get(url)
 	if err != nil {
-		return nil, fmt.Errorf("metadata: %v", err)
+		return nil, fmt.Errorf("fetching %s: %v", url, err)
 	}
 	body, err := io.ReadAll(resp.Body)
 	resp.Body.Close()
@@ -100,68 +187,102 @@ func parseMetadata() ([]aaguidName, error) {
 		return nil, fmt.Errorf("reading response body: %v", err)
 	}
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code %s: %v", resp.Status, body)
+		return nil, fmt.Errorf("unexpected status code %s: %s", resp.Status, body)
 	}
+	return body, nil
+}
 
-	parts := bytes.Split(body, []byte{'.'})
-	if len(parts) != 3 {
-		return nil, fmt.Errorf("expected jwt with three parts, got %d", len(parts))
+func parseMetadata() ([]aaguidName, []metadataDetails, error) {
+	rootPEM := []byte(globalSignRootCAR3)
+	if *flagMetadataRoot != "" {
+		b, err := os.ReadFile(*flagMetadataRoot)
+		if err != nil {
+			return nil, nil, fmt.Errorf("reading root certificate: %v", err)
+		}
+		rootPEM = b
 	}
-	payload, err := base64.RawURLEncoding.AppendDecode(nil, parts[1])
-	if err != nil {
-		return nil, fmt.Errorf("decoding jwt payload: %v", err)
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(rootPEM) {
+		return nil, nil, fmt.Errorf("no certificates found in root certificate file")
+	}
+	opts := &metadata.VerifyOptions{Roots: roots}
+	if *flagMetadataTime != "" {
+		t, err := time.Parse(time.RFC3339, *flagMetadataTime)
+		if err != nil {
+			return nil, nil, fmt.Errorf("parsing time: %v", err)
+		}
+		opts.CurrentTime = t
 	}
-	var blob struct {
-		Entries []struct {
-			AAGUID   string `json:"aaguid"`
-			Metadata struct {
-				Description string `json:"description"`
-			} `json:"metadataStatement"`
-		} `json:"entries"`
+
+	body, err := readSource(*flagMetadata, metadata.URL)
+	if err != nil {
+		return nil, nil, fmt.Errorf("metadata: %v", err)
 	}
-	if err := json.Unmarshal(payload, &blob); err != nil {
-		return nil, fmt.Errorf("parsing blob: %v", err)
+	blob, err := metadata.Parse(bytes.NewReader(body), opts)
+	if err != nil {
+		return nil, nil, fmt.Errorf("verifying blob: %v", err)
 	}
 
-	var names []aaguidName
+	var (
+		names   []aaguidName
+		details []metadataDetails
+	)
 	for _, e := range blob.Entries {
-		aaguid := strings.TrimSpace(e.AAGUID)
-		if aaguid == "" {
+		if e.AAGUID == (webauthn.AAGUID{}) || e.MetadataStatement == nil {
 			continue
 		}
-		names = append(names, aaguidName{aaguid, e.Metadata.Description})
+		aaguid := e.AAGUID.String()
+		stmt := e.MetadataStatement
+		names = append(names, aaguidName{aaguid, stmt.Description})
+
+		d := metadataDetails{
+			AAGUID:               aaguid,
+			AuthenticatorVersion: stmt.AuthenticatorVersion,
+			KeyProtection:        stmt.KeyProtection,
+		}
+		if info := stmt.AuthenticatorGetInfo; info != nil {
+			for _, alg := range info.Algorithms {
+				d.Algorithms = append(d.Algorithms, alg.Alg)
+			}
+		}
+		details = append(details, d)
 	}
-	return names, nil
+	return names, details, nil
 }
 
-func parsePasskeyAuthenticatorAAGUIDs() ([]aaguidName, error) {
-	resp, err := http.Get(passkeyAuthenticatorGitHub)
+func parsePasskeyAuthenticatorAAGUIDs() ([]aaguidName, []aaguidIcons, error) {
+	body, err := readSource(*flagPasskeyAAGUIDs, passkeyAuthenticatorGitHub)
 	if err != nil {
-		return nil, fmt.Errorf("getting passkey authenticator aaguid: %v", err)
+		return nil, nil, err
 	}
-	body, err := io.ReadAll(resp.Body)
-	resp.Body.Close()
-	if err != nil {
-		return nil, fmt.Errorf("reading response body: %v", err)
-	}
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code %s: %v", resp.Status, body)
-	}
-
 	var aaguids map[string]struct {
-		Name string `json:"name"`
+		Name      string `json:"name"`
+		IconDark  string `json:"icon_dark"`
+		IconLight string `json:"icon_light"`
 	}
 	if err := json.Unmarshal(body, &aaguids); err != nil {
-		return nil, fmt.Errorf("parsing body: %v", err)
+		return nil, nil, fmt.Errorf("parsing body: %v", err)
 	}
-	var names []aaguidName
+	var (
+		names []aaguidName
+		icons []aaguidIcons
+	)
 	for k, v := range aaguids {
 		names = append(names, aaguidName{k, v.Name})
+		if v.IconLight != "" || v.IconDark != "" {
+			icons = append(icons, aaguidIcons{k, v.IconLight, v.IconDark})
+		}
+	}
+	// Every upstream entry carries icons. A file without them, such as a
+	// names-only copy, would silently drop icons from the generated tables.
+	if len(icons) == 0 {
+		return nil, nil, fmt.Errorf("no icons found, expected icon_light and icon_dark values")
 	}
-	return names, nil
+	return names, icons, nil
 }
 
 func main() {
+	flag.Parse()
 	if err := run(); err != nil {
 		log.Fatalf("Updating aaguid file: %v", err)
 	}
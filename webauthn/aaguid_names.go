@@ -39,6 +39,9 @@ var passkeyAuthenticatorAAGUIDs = map[AAGUID]string{
 	mustParseAAGUID("fdb141b2-5d84-443e-8a35-4698c205a502"): "KeePassXC",
 }
 
+// Icons listed by https://github.com/passkeydeveloper/passkey-authenticator-aaguids.
+var passkeyAuthenticatorIcons = map[AAGUID]aaguidIcons{}
+
 // AAGUIDs listed by https://fidoalliance.org/metadata/.
 var metadataAAGUIDs = map[AAGUID]string{
 	mustParseAAGUID("0076631b-d4a0-427f-5773-0ec71c9e0279"): "HYPR FIDO2 Authenticator",
@@ -265,3 +268,12 @@ var metadataAAGUIDs = map[AAGUID]string{
 	mustParseAAGUID("fec067a1-f1d0-4c5e-b4c0-cc3237475461"): "KX701 SmartToken FIDO",
 	mustParseAAGUID("ff4dac45-ede8-4ec2-aced-cf66103f4335"): "YubiKey 5 Series",
 }
+
+// Authenticator versions listed by https://fidoalliance.org/metadata/.
+var metadataAuthenticatorVersions = map[AAGUID]uint32{}
+
+// Supported algorithms listed by https://fidoalliance.org/metadata/.
+var metadataAlgorithms = map[AAGUID][]Algorithm{}
+
+// Key protection listed by https://fidoalliance.org/metadata/.
+var metadataKeyProtection = map[AAGUID][]string{}
@@ -0,0 +1,140 @@
+package webauthn
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+// AuthenticatorInfo holds presentable information about an authenticator model.
+type AuthenticatorInfo struct {
+	// Name is a human readable name, such as "YubiKey 5 Series" or
+	// "iCloud Keychain".
+	Name string
+	// IconLight and IconDark are data URIs of icons intended for light and
+	// dark themes respectively. Either may be empty.
+	IconLight string
+	IconDark  string
+}
+
+// AAGUIDResolver looks up information about an authenticator model.
+type AAGUIDResolver interface {
+	ResolveAAGUID(aaguid AAGUID) (*AuthenticatorInfo, bool)
+}
+
+// AAGUIDResolvers queries a list of resolvers in order. Fields not provided by
+// a resolver, such as icons, are filled in by later resolvers.
+type AAGUIDResolvers []AAGUIDResolver
+
+// ResolveAAGUID implements the [AAGUIDResolver] interface.
+func (r AAGUIDResolvers) ResolveAAGUID(aaguid AAGUID) (*AuthenticatorInfo, bool) {
+	var info AuthenticatorInfo
+	found := false
+	for _, resolver := range r {
+		if resolver == nil {
+			continue
+		}
+		got, ok := resolver.ResolveAAGUID(aaguid)
+		if !ok {
+			continue
+		}
+		found = true
+		if info.Name == "" {
+			info.Name = got.Name
+		}
+		if info.IconLight == "" {
+			info.IconLight = got.IconLight
+		}
+		if info.IconDark == "" {
+			info.IconDark = got.IconDark
+		}
+	}
+	if !found {
+		return nil, false
+	}
+	return &info, true
+}
+
+// BuiltinAAGUIDResolver resolves AAGUIDs using the tables compiled into this
+// package. See [AAGUID.Name] and [AAGUID.Icons].
+var BuiltinAAGUIDResolver AAGUIDResolver = builtinAAGUIDResolver{}
+
+type builtinAAGUIDResolver struct{}
+
+func (builtinAAGUIDResolver) ResolveAAGUID(aaguid AAGUID) (*AuthenticatorInfo, bool) {
+	name, nameOK := aaguid.Name()
+	light, dark, iconsOK := aaguid.Icons()
+	if !nameOK && !iconsOK {
+		return nil, false
+	}
+	return &AuthenticatorInfo{Name: name, IconLight: light, IconDark: dark}, true
+}
+
+// DefaultAAGUIDResolver returns a resolver that consults the compiled-in
+// tables, then the FIDO Metadata Service, then the community maintained
+// passkey-authenticator-aaguids list. Either argument may be nil, including a
+// nil *metadata.Cache.
+//
+// The metadata package's Cache can be passed as the mds argument, and
+// [ParsePasskeyAuthenticatorAAGUIDs] loads the community list.
+func DefaultAAGUIDResolver(mds, community AAGUIDResolver) AAGUIDResolver {
+	return AAGUIDResolvers{BuiltinAAGUIDResolver, mds, community}
+}
+
+// PasskeyAuthenticatorAAGUIDs holds a parsed passkey-authenticator-aaguids
+// list.
+//
+// https://github.com/passkeydeveloper/passkey-authenticator-aaguids
+type PasskeyAuthenticatorAAGUIDs map[AAGUID]*AuthenticatorInfo
+
+// ParsePasskeyAuthenticatorAAGUIDs parses the "aaguid.json" file of the
+// passkey-authenticator-aaguids repo.
+//
+// https://github.com/passkeydeveloper/passkey-authenticator-aaguids/blob/main/aaguid.json
+func ParsePasskeyAuthenticatorAAGUIDs(r io.Reader) (PasskeyAuthenticatorAAGUIDs, error) {
+	var entries map[AAGUID]struct {
+		Name      string `json:"name"`
+		IconDark  string `json:"icon_dark"`
+		IconLight string `json:"icon_light"`
+	}
+	if err := json.NewDecoder(r).Decode(&entries); err != nil {
+		return nil, fmt.Errorf("parsing aaguids: %v", err)
+	}
+	p := make(PasskeyAuthenticatorAAGUIDs, len(entries))
+	for aaguid, e := range entries {
+		p[aaguid] = &AuthenticatorInfo{
+			Name:      e.Name,
+			IconLight: e.IconLight,
+			IconDark:  e.IconDark,
+		}
+	}
+	return p, nil
+}
+
+// LoadPasskeyAuthenticatorAAGUIDs parses a passkey-authenticator-aaguids file
+// from the local filesystem.
+func LoadPasskeyAuthenticatorAAGUIDs(path string) (PasskeyAuthenticatorAAGUIDs, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParsePasskeyAuthenticatorAAGUIDs(f)
+}
+
+// ResolveAAGUID implements the [AAGUIDResolver] interface.
+func (p PasskeyAuthenticatorAAGUIDs) ResolveAAGUID(aaguid AAGUID) (*AuthenticatorInfo, bool) {
+	info, ok := p[aaguid]
+	return info, ok
+}
+
+// ResolveAAGUID returns information about an authenticator model using the
+// relying party's AAGUIDResolver, or the compiled-in tables if none is
+// configured.
+func (rp *RelyingParty) ResolveAAGUID(aaguid AAGUID) (*AuthenticatorInfo, bool) {
+	if rp.AAGUIDResolver != nil {
+		return rp.AAGUIDResolver.ResolveAAGUID(aaguid)
+	}
+	return BuiltinAAGUIDResolver.ResolveAAGUID(aaguid)
+}
@@ -0,0 +1,110 @@
+package webauthn
+
+import (
+	"strings"
+	"testing"
+)
+
+type testAAGUIDResolver map[AAGUID]*AuthenticatorInfo
+
+func (r testAAGUIDResolver) ResolveAAGUID(aaguid AAGUID) (*AuthenticatorInfo, bool) {
+	info, ok := r[aaguid]
+	return info, ok
+}
+
+func TestAAGUIDResolver(t *testing.T) {
+	windowsHello := mustParseAAGUID("08987058-cadc-4b81-b6e1-30de50dcbe96")
+	newAuthenticator := mustParseAAGUID("00000000-1111-2222-3333-444444444444")
+	unknown := mustParseAAGUID("ffffffff-1111-2222-3333-444444444444")
+
+	community, err := ParsePasskeyAuthenticatorAAGUIDs(strings.NewReader(`{
+		"08987058-cadc-4b81-b6e1-30de50dcbe96": {
+			"name": "Windows Hello (community)",
+			"icon_dark": "data:image/svg+xml;base64,ZGFyaw==",
+			"icon_light": "data:image/svg+xml;base64,bGlnaHQ="
+		},
+		"00000000-1111-2222-3333-444444444444": {
+			"name": "New Authenticator",
+			"icon_light": "data:image/svg+xml;base64,bmV3"
+		}
+	}`))
+	if err != nil {
+		t.Fatalf("Parsing aaguids: %v", err)
+	}
+	mds := testAAGUIDResolver{
+		newAuthenticator: {Name: "New Authenticator (metadata)"},
+	}
+
+	testCases := []struct {
+		name     string
+		rp       *RelyingParty
+		aaguid   AAGUID
+		want     *AuthenticatorInfo
+		wantNone bool
+	}{
+		{
+			name:   "Builtin",
+			rp:     &RelyingParty{},
+			aaguid: windowsHello,
+			want:   &AuthenticatorInfo{Name: "Windows Hello"},
+		},
+		{
+			name:     "Builtin unknown",
+			rp:       &RelyingParty{},
+			aaguid:   newAuthenticator,
+			wantNone: true,
+		},
+		{
+			name:   "Builtin name with community icons",
+			rp:     &RelyingParty{AAGUIDResolver: DefaultAAGUIDResolver(mds, community)},
+			aaguid: windowsHello,
+			want: &AuthenticatorInfo{
+				Name:      "Windows Hello",
+				IconLight: "data:image/svg+xml;base64,bGlnaHQ=",
+				IconDark:  "data:image/svg+xml;base64,ZGFyaw==",
+			},
+		},
+		{
+			name:   "Metadata before community",
+			rp:     &RelyingParty{AAGUIDResolver: DefaultAAGUIDResolver(mds, community)},
+			aaguid: newAuthenticator,
+			want: &AuthenticatorInfo{
+				Name:      "New Authenticator (metadata)",
+				IconLight: "data:image/svg+xml;base64,bmV3",
+			},
+		},
+		{
+			name:   "Community only",
+			rp:     &RelyingParty{AAGUIDResolver: DefaultAAGUIDResolver(nil, community)},
+			aaguid: newAuthenticator,
+			want: &AuthenticatorInfo{
+				Name:      "New Authenticator",
+				IconLight: "data:image/svg+xml;base64,bmV3",
+			},
+		},
+		{
+			name:     "Unknown",
+			rp:       &RelyingParty{AAGUIDResolver: DefaultAAGUIDResolver(mds, community)},
+			aaguid:   unknown,
+			wantNone: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, ok := tc.rp.ResolveAAGUID(tc.aaguid)
+			if tc.wantNone {
+				if ok {
+					t.Fatalf("Expected aaguid to not resolve, got=%+v", got)
+				}
+				return
+			}
+			if !ok {
+				t.Fatalf("AAGUID didn't resolve")
+			}
+			if *got != *tc.want {
+				t.Errorf("Unexpected authenticator info, got=%+v, want=%+v", got, tc.want)
+			}
+		})
+	}
+}
@@ -42,3 +42,49 @@ func TestParseAAGUID(t *testing.T) {
 		}
 	}
 }
+
+func TestAAGUIDSyncedPasskeyProvider(t *testing.T) {
+	testCases := []struct {
+		aaguid string
+		want   bool
+	}{
+		{"fbfc3007-154e-4ecc-8c0b-6e020557d7bd", true},  // iCloud Keychain
+		{"ea9b8d66-4d01-1d21-3ce4-b6b48cb575d4", true},  // Google Password Manager
+		{"08987058-cadc-4b81-b6e1-30de50dcbe96", false}, // Windows Hello
+		{"cb69481e-8ff7-4039-93ec-0a2729a154a8", false}, // YubiKey 5 Series
+	}
+	for _, tc := range testCases {
+		if got := mustParseAAGUID(tc.aaguid).SyncedPasskeyProvider(); got != tc.want {
+			t.Errorf("SyncedPasskeyProvider(%s) returned unexpected result, got=%t, want=%t", tc.aaguid, got, tc.want)
+		}
+	}
+}
+
+func TestAAGUIDName(t *testing.T) {
+	testCases := []struct {
+		aaguid string
+		want   string
+	}{
+		{"ea9b8d66-4d01-1d21-3ce4-b6b48cb575d4", "Google Password Manager"},
+		{"fbfc3007-154e-4ecc-8c0b-6e020557d7bd", "iCloud Keychain"},
+		{"24673149-6c86-42e7-98d9-433fb5b73296", "YubiKey 5 Series with Lightning"},
+		{"34744913-4f57-4e6e-a527-e9ec3c4b94e6", "YubiKey Bio Series - Multi-protocol Edition"},
+		{"050dd0bc-ff20-4265-8d5d-305c4b215192", "eToken Fusion FIPS"},
+	}
+	for _, tc := range testCases {
+		got, ok := mustParseAAGUID(tc.aaguid).Name()
+		if !ok || got != tc.want {
+			t.Errorf("Name(%s) returned unexpected result, got=(%q, %t), want=(%q, true)", tc.aaguid, got, ok, tc.want)
+		}
+	}
+}
+
+func TestSyncedPasskeyProviderAAGUIDs(t *testing.T) {
+	// Synced providers are maintained by hand, and must stay a subset of the
+	// generated passkey-authenticator-aaguids table.
+	for aaguid := range syncedPasskeyProviderAAGUIDs {
+		if _, ok := passkeyAuthenticatorAAGUIDs[aaguid]; !ok {
+			t.Errorf("Synced passkey provider %s not listed by passkey-authenticator-aaguids", aaguid)
+		}
+	}
+}
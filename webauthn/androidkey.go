@@ -0,0 +1,410 @@
+package webauthn
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"fmt"
+	"slices"
+)
+
+var idAndroidKeyAttestation = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 11129, 2, 1, 17}
+
+// Authorization list values defined by Android Keymaster.
+//
+// https://source.android.com/docs/security/features/keystore/attestation#authorizationlist-fields
+const (
+	androidKeyPurposeSign     = 2
+	androidKeyOriginGenerated = 0
+)
+
+// AndroidSecurityLevel indicates where an Android key or its attestation is
+// enforced.
+//
+// https://source.android.com/docs/security/features/keystore/attestation#securitylevel-values
+type AndroidSecurityLevel int
+
+// Security levels defined by Android Keymaster.
+const (
+	AndroidSecurityLevelSoftware           AndroidSecurityLevel = 0
+	AndroidSecurityLevelTrustedEnvironment AndroidSecurityLevel = 1
+	AndroidSecurityLevelStrongBox          AndroidSecurityLevel = 2
+)
+
+var androidSecurityLevelStrings = map[AndroidSecurityLevel]string{
+	AndroidSecurityLevelSoftware:           "Software",
+	AndroidSecurityLevelTrustedEnvironment: "TrustedEnvironment",
+	AndroidSecurityLevelStrongBox:          "StrongBox",
+}
+
+// String returns a human readable representation of the security level.
+func (l AndroidSecurityLevel) String() string {
+	if s, ok := androidSecurityLevelStrings[l]; ok {
+		return s
+	}
+	return fmt.Sprintf("AndroidSecurityLevel(%d)", int(l))
+}
+
+// AndroidKeyOptions allows configuration for validating android-key
+// attestation statements.
+//
+// https://www.w3.org/TR/webauthn-3/#sctn-android-key-attestation
+type AndroidKeyOptions struct {
+	// When set, only accept keys whose properties are enforced by a Trusted
+	// Execution Environment or StrongBox. Authorization values from the
+	// software enforced list are ignored.
+	RequireHardware bool
+
+	// GetRoots returns the root certificates for a given AAGUID. For example, by
+	// parsing the FIDO Alliance Metadata Service, or by returning the Google
+	// hardware attestation roots.
+	//
+	// https://developer.android.com/privacy-and-security/security-key-attestation#root_certificate
+	GetRoots func(aaguid AAGUID) (*x509.CertPool, error)
+}
+
+// AndroidKey holds a parsed android-key attestation format.
+//
+// https://www.w3.org/TR/webauthn-3/#sctn-android-key-attestation
+type AndroidKey struct {
+	// Parsed and validated authenticator data.
+	AttestationData *Attestation
+
+	// AttestationCertificate is the certificate of the credential public key,
+	// which chains up to a root certificate within the configuration.
+	AttestationCertificate *x509.Certificate
+
+	// Version and security levels of the attestation and Keymaster
+	// implementation.
+	//
+	// https://source.android.com/docs/security/features/keystore/attestation#keydescription-fields
+	AttestationVersion       int
+	AttestationSecurityLevel AndroidSecurityLevel
+	KeymasterVersion         int
+	KeymasterSecurityLevel   AndroidSecurityLevel
+
+	// Authorizations of the key enforced by the Android system, and by secure
+	// hardware.
+	SoftwareEnforced *AndroidAuthorizationList
+	TEEEnforced      *AndroidAuthorizationList
+}
+
+// AndroidAuthorizationList holds the properties of a key as reported by the
+// Android Key Attestation extension. Optional values are nil when not present.
+//
+// https://source.android.com/docs/security/features/keystore/attestation#authorizationlist-fields
+type AndroidAuthorizationList struct {
+	Purpose         []int64
+	Algorithm       *int64
+	KeySize         *int64
+	Digest          []int64
+	Padding         []int64
+	ECCurve         *int64
+	NoAuthRequired  bool
+	AllApplications bool
+	Origin          *int64
+	RootOfTrust     *AndroidRootOfTrust
+	OSVersion       *int64
+	OSPatchLevel    *int64
+
+	// AttestationApplicationID is the DER encoded package information of the
+	// application that created the key.
+	AttestationApplicationID []byte
+}
+
+// AndroidRootOfTrust describes the verified boot state of the device.
+//
+// https://source.android.com/docs/security/features/keystore/attestation#rootoftrust-fields
+type AndroidRootOfTrust struct {
+	VerifiedBootKey   []byte
+	DeviceLocked      bool
+	VerifiedBootState int
+	VerifiedBootHash  []byte
+}
+
+// VerifyAttestationAndroidKey is similar to VerifyAttestation, but additionally
+// performs validation of "android-key" attestation statements.
+//
+// https://www.w3.org/TR/webauthn-3/#sctn-android-key-attestation
+func (rp *RelyingParty) VerifyAttestationAndroidKey(challenge, clientDataJSON, attestationObject []byte, opts *AndroidKeyOptions) (*AndroidKey, error) {
+	cd, err := rp.verifyClientData(clientDataJSON, "webauthn.create", challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	attObj, err := parseAttestationObject(attestationObject)
+	if err != nil {
+		return nil, fmt.Errorf("parsing attestation object: %v", err)
+	}
+
+	data, err := attObj.VerifyAndroidKey(rp.ID, clientDataJSON, opts)
+	if err != nil {
+		return nil, fmt.Errorf("parsing authenticator data: %v", err)
+	}
+	if err := rp.verifyAttestationData(cd, data.AttestationData); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// VerifyAndroidKey validates an attestation object and client JSON data against
+// an android-key attestation statement.
+//
+// https://www.w3.org/TR/webauthn-3/#sctn-android-key-attestation
+func (o *attestationObject) VerifyAndroidKey(rpid string, clientDataJSON []byte, opts *AndroidKeyOptions) (*AndroidKey, error) {
+	ad, err := parseAuthData(o.authData, rpid)
+	if err != nil {
+		return nil, fmt.Errorf("invalid auth data: %v", err)
+	}
+	k, _, err := verifyAndroidKey(ad, o.authData, o.attestationStatement, clientDataJSON, opts)
+	return k, err
+}
+
+// verifyAndroidKey validates an android-key attestation statement against
+// already parsed authenticator data, returning the verified certificate chain,
+// leaf first.
+func verifyAndroidKey(ad *Attestation, authData, attStmt, clientDataJSON []byte, opts *AndroidKeyOptions) (*AndroidKey, []*x509.Certificate, error) {
+	if opts == nil || opts.GetRoots == nil {
+		return nil, nil, fmt.Errorf("no root certificates provided")
+	}
+
+	// android-key uses the same statement structure as packed.
+	p, err := parsePacked(attStmt)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid attestation statement: %v", err)
+	}
+	if len(p.x5c) == 0 {
+		return nil, nil, fmt.Errorf("attestation statement contains no certificates")
+	}
+	x5c, err := parseCertificates(p.x5c)
+	if err != nil {
+		return nil, nil, err
+	}
+	credCert := x5c[0]
+
+	// "Verify that sig is a valid signature over the concatenation of
+	// authenticatorData and clientDataHash using the public key in the first
+	// certificate in x5c with the algorithm specified in alg."
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	data := append([]byte{}, authData...)
+	data = append(data, clientDataHash[:]...)
+	if err := verifySignature(credCert.PublicKey, Algorithm(p.alg), data, p.sig); err != nil {
+		return nil, nil, fmt.Errorf("verifying with attestation certificate: %v", err)
+	}
+
+	// "Verify that the public key in the first certificate in x5c matches the
+	// credentialPublicKey in the attestedCredentialData in authenticatorData."
+	credPub, ok := ad.PublicKey.(interface{ Equal(crypto.PublicKey) bool })
+	if !ok || !credPub.Equal(credCert.PublicKey) {
+		return nil, nil, fmt.Errorf("attestation certificate key doesn't match credential public key")
+	}
+
+	var ext []byte
+	for _, e := range credCert.Extensions {
+		if e.Id.Equal(idAndroidKeyAttestation) {
+			ext = e.Value
+			break
+		}
+	}
+	if len(ext) == 0 {
+		return nil, nil, fmt.Errorf("attestation certificate has no android key attestation extension")
+	}
+	kd, err := parseAndroidKeyDescription(ext)
+	if err != nil {
+		return nil, nil, fmt.Errorf("parsing android key attestation extension: %v", err)
+	}
+
+	// "Verify that the attestationChallenge field in the attestation certificate
+	// extension data is identical to clientDataHash."
+	if !bytes.Equal(kd.challenge, clientDataHash[:]) {
+		return nil, nil, fmt.Errorf("attestation challenge doesn't match client data hash")
+	}
+
+	// "The AuthorizationList.allApplications field is not present on either
+	// authorization list (softwareEnforced nor teeEnforced), since
+	// PublicKeyCredential MUST be scoped to the RP ID."
+	if kd.SoftwareEnforced.AllApplications || kd.TEEEnforced.AllApplications {
+		return nil, nil, fmt.Errorf("key is not scoped to the relying party, allApplications set")
+	}
+
+	// "For the following, use only the teeEnforced authorization list if the RP
+	// wants to accept only keys from a trusted execution environment, otherwise
+	// use the union of teeEnforced and softwareEnforced."
+	var (
+		origin  *int64
+		purpose []int64
+	)
+	if opts.RequireHardware {
+		if kd.AttestationSecurityLevel == AndroidSecurityLevelSoftware {
+			return nil, nil, fmt.Errorf("attestation security level is software, hardware required")
+		}
+		origin = kd.TEEEnforced.Origin
+		purpose = kd.TEEEnforced.Purpose
+	} else {
+		origin = kd.TEEEnforced.Origin
+		if origin == nil {
+			origin = kd.SoftwareEnforced.Origin
+		}
+		purpose = append(slices.Clone(kd.TEEEnforced.Purpose), kd.SoftwareEnforced.Purpose...)
+	}
+	if origin == nil || *origin != androidKeyOriginGenerated {
+		return nil, nil, fmt.Errorf("key wasn't generated by keymaster")
+	}
+	if !slices.Contains(purpose, androidKeyPurposeSign) {
+		return nil, nil, fmt.Errorf("key purpose doesn't include signing")
+	}
+
+	roots, err := opts.GetRoots(ad.AAGUID)
+	if err != nil {
+		return nil, nil, err
+	}
+	v := x509.VerifyOptions{
+		Roots:     roots,
+		KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	}
+	if len(x5c) > 1 {
+		v.Intermediates = x509.NewCertPool()
+		for _, cert := range x5c[1:] {
+			v.Intermediates.AddCert(cert)
+		}
+	}
+	if _, err := credCert.Verify(v); err != nil {
+		return nil, nil, fmt.Errorf("failed to verify attestation certificate for provider %s: %v", ad.AAGUID, err)
+	}
+
+	kd.AttestationData = ad
+	kd.AttestationCertificate = credCert
+	return &kd.AndroidKey, x5c, nil
+}
+
+type androidKeyDescription struct {
+	AndroidKey
+	challenge []byte
+}
+
+// parseAndroidKeyDescription parses the KeyDescription value of the Android
+// Key Attestation extension.
+//
+// https://source.android.com/docs/security/features/keystore/attestation#schema
+func parseAndroidKeyDescription(b []byte) (*androidKeyDescription, error) {
+	var desc struct {
+		AttestationVersion       int
+		AttestationSecurityLevel asn1.Enumerated
+		KeymasterVersion         int
+		KeymasterSecurityLevel   asn1.Enumerated
+		AttestationChallenge     []byte
+		UniqueID                 []byte
+		SoftwareEnforced         asn1.RawValue
+		TEEEnforced              asn1.RawValue
+	}
+	if rest, err := asn1.Unmarshal(b, &desc); err != nil {
+		return nil, err
+	} else if len(rest) != 0 {
+		return nil, fmt.Errorf("trailing data after key description")
+	}
+
+	sw, err := parseAndroidAuthorizationList(desc.SoftwareEnforced)
+	if err != nil {
+		return nil, fmt.Errorf("parsing software enforced authorization list: %v", err)
+	}
+	tee, err := parseAndroidAuthorizationList(desc.TEEEnforced)
+	if err != nil {
+		return nil, fmt.Errorf("parsing tee enforced authorization list: %v", err)
+	}
+	return &androidKeyDescription{
+		AndroidKey: AndroidKey{
+			AttestationVersion:       desc.AttestationVersion,
+			AttestationSecurityLevel: AndroidSecurityLevel(desc.AttestationSecurityLevel),
+			KeymasterVersion:         desc.KeymasterVersion,
+			KeymasterSecurityLevel:   AndroidSecurityLevel(desc.KeymasterSecurityLevel),
+			SoftwareEnforced:         sw,
+			TEEEnforced:              tee,
+		},
+		challenge: desc.AttestationChallenge,
+	}, nil
+}
+
+// parseAndroidAuthorizationList parses an AuthorizationList sequence. Fields
+// are explicitly tagged and all optional, so elements are iterated over rather
+// than unmarshaled into a struct, ignoring any unrecognized tags.
+func parseAndroidAuthorizationList(raw asn1.RawValue) (*AndroidAuthorizationList, error) {
+	if raw.Class != asn1.ClassUniversal || raw.Tag != asn1.TagSequence {
+		return nil, fmt.Errorf("expected sequence")
+	}
+
+	l := &AndroidAuthorizationList{}
+	optionalInt := func(b []byte, n **int64) error {
+		var v int64
+		if _, err := asn1.Unmarshal(b, &v); err != nil {
+			return err
+		}
+		*n = &v
+		return nil
+	}
+	intSet := func(b []byte, n *[]int64) error {
+		_, err := asn1.UnmarshalWithParams(b, n, "set")
+		return err
+	}
+
+	rest := raw.Bytes
+	for len(rest) > 0 {
+		var field asn1.RawValue
+		var err error
+		rest, err = asn1.Unmarshal(rest, &field)
+		if err != nil {
+			return nil, err
+		}
+		if field.Class != asn1.ClassContextSpecific {
+			return nil, fmt.Errorf("unexpected field class %d", field.Class)
+		}
+
+		b := field.Bytes
+		switch field.Tag {
+		case 1:
+			err = intSet(b, &l.Purpose)
+		case 2:
+			err = optionalInt(b, &l.Algorithm)
+		case 3:
+			err = optionalInt(b, &l.KeySize)
+		case 5:
+			err = intSet(b, &l.Digest)
+		case 6:
+			err = intSet(b, &l.Padding)
+		case 10:
+			err = optionalInt(b, &l.ECCurve)
+		case 503:
+			l.NoAuthRequired = true
+		case 600:
+			l.AllApplications = true
+		case 702:
+			err = optionalInt(b, &l.Origin)
+		case 704:
+			var rot struct {
+				VerifiedBootKey   []byte
+				DeviceLocked      bool
+				VerifiedBootState asn1.Enumerated
+				VerifiedBootHash  []byte `asn1:"optional"`
+			}
+			if _, err = asn1.Unmarshal(b, &rot); err == nil {
+				l.RootOfTrust = &AndroidRootOfTrust{
+					VerifiedBootKey:   rot.VerifiedBootKey,
+					DeviceLocked:      rot.DeviceLocked,
+					VerifiedBootState: int(rot.VerifiedBootState),
+					VerifiedBootHash:  rot.VerifiedBootHash,
+				}
+			}
+		case 705:
+			err = optionalInt(b, &l.OSVersion)
+		case 706:
+			err = optionalInt(b, &l.OSPatchLevel)
+		case 709:
+			_, err = asn1.Unmarshal(b, &l.AttestationApplicationID)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("parsing field [%d]: %v", field.Tag, err)
+		}
+	}
+	return l, nil
+}
@@ -0,0 +1,217 @@
+package webauthn
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// testAndroidAuthorizationList encodes an AuthorizationList from explicitly
+// tagged fields.
+func testAndroidAuthorizationList(t *testing.T, fields map[int]any) asn1.RawValue {
+	t.Helper()
+	var tags []int
+	for tag := range fields {
+		tags = append(tags, tag)
+	}
+	// Fields must be ordered by tag.
+	slices.Sort(tags)
+
+	var b []byte
+	for _, tag := range tags {
+		var params string
+		if _, ok := fields[tag].([]int); ok {
+			params = "set"
+		}
+		inner, err := asn1.MarshalWithParams(fields[tag], params)
+		if err != nil {
+			t.Fatalf("Encoding field %d: %v", tag, err)
+		}
+		field, err := asn1.Marshal(asn1.RawValue{
+			Class:      asn1.ClassContextSpecific,
+			Tag:        tag,
+			IsCompound: true,
+			Bytes:      inner,
+		})
+		if err != nil {
+			t.Fatalf("Encoding field %d: %v", tag, err)
+		}
+		b = append(b, field...)
+	}
+	return asn1.RawValue{Class: asn1.ClassUniversal, Tag: asn1.TagSequence, IsCompound: true, Bytes: b}
+}
+
+func TestVerifyAttestationAndroidKey(t *testing.T) {
+	const (
+		rpID   = "localhost"
+		origin = "http://localhost:8080"
+	)
+	aaguid := mustParseAAGUID("b93fd961-f2e6-462f-b122-82002247de78")
+
+	testCases := []struct {
+		name          string
+		opts          AndroidKeyOptions
+		securityLevel asn1.Enumerated
+		software      map[int]any
+		tee           map[int]any
+		badChallenge  bool
+		wantErr       string
+	}{
+		{
+			name:          "Hardware enforced",
+			opts:          AndroidKeyOptions{RequireHardware: true},
+			securityLevel: 1,
+			tee: map[int]any{
+				1:   []int{androidKeyPurposeSign},
+				702: androidKeyOriginGenerated,
+			},
+		},
+		{
+			name:          "Software enforced",
+			securityLevel: 0,
+			software: map[int]any{
+				1:   []int{androidKeyPurposeSign},
+				702: androidKeyOriginGenerated,
+			},
+		},
+		{
+			name:          "Software enforced with hardware required",
+			opts:          AndroidKeyOptions{RequireHardware: true},
+			securityLevel: 0,
+			software: map[int]any{
+				1:   []int{androidKeyPurposeSign},
+				702: androidKeyOriginGenerated,
+			},
+			wantErr: "hardware required",
+		},
+		{
+			name:          "Software origin with hardware required",
+			opts:          AndroidKeyOptions{RequireHardware: true},
+			securityLevel: 1,
+			software: map[int]any{
+				702: androidKeyOriginGenerated,
+			},
+			tee: map[int]any{
+				1: []int{androidKeyPurposeSign},
+			},
+			wantErr: "wasn't generated",
+		},
+		{
+			name:          "All applications",
+			securityLevel: 1,
+			tee: map[int]any{
+				1:   []int{androidKeyPurposeSign},
+				600: asn1.NullRawValue,
+				702: androidKeyOriginGenerated,
+			},
+			wantErr: "allApplications",
+		},
+		{
+			name:          "Missing sign purpose",
+			securityLevel: 1,
+			tee: map[int]any{
+				1:   []int{3},
+				702: androidKeyOriginGenerated,
+			},
+			wantErr: "purpose",
+		},
+		{
+			name:          "Bad challenge",
+			securityLevel: 1,
+			tee: map[int]any{
+				1:   []int{androidKeyPurposeSign},
+				702: androidKeyOriginGenerated,
+			},
+			badChallenge: true,
+			wantErr:      "attestation challenge",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ca := newTestCA(t)
+			credKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+			if err != nil {
+				t.Fatalf("Generating key: %v", err)
+			}
+
+			challenge := []byte("android-key-test-challenge")
+			clientDataJSON := testClientDataJSON("webauthn.create", origin, challenge)
+			clientDataHash := sha256.Sum256(clientDataJSON)
+			attChallenge := clientDataHash[:]
+			if tc.badChallenge {
+				attChallenge = []byte("bad")
+			}
+
+			desc, err := asn1.Marshal(struct {
+				AttestationVersion       int
+				AttestationSecurityLevel asn1.Enumerated
+				KeymasterVersion         int
+				KeymasterSecurityLevel   asn1.Enumerated
+				AttestationChallenge     []byte
+				UniqueID                 []byte
+				SoftwareEnforced         asn1.RawValue
+				TEEEnforced              asn1.RawValue
+			}{
+				AttestationVersion:       3,
+				AttestationSecurityLevel: tc.securityLevel,
+				KeymasterVersion:         4,
+				KeymasterSecurityLevel:   tc.securityLevel,
+				AttestationChallenge:     attChallenge,
+				UniqueID:                 []byte{},
+				SoftwareEnforced:         testAndroidAuthorizationList(t, tc.software),
+				TEEEnforced:              testAndroidAuthorizationList(t, tc.tee),
+			})
+			if err != nil {
+				t.Fatalf("Encoding key description: %v", err)
+			}
+
+			credCert := ca.issue(t, &x509.Certificate{
+				Subject: pkix.Name{CommonName: "Android Keystore Key"},
+				ExtraExtensions: []pkix.Extension{
+					{Id: idAndroidKeyAttestation, Value: desc},
+				},
+			}, credKey.Public())
+
+			authData := testAuthData(t, rpID, 0x05, aaguid, []byte("credential"), &credKey.PublicKey, ES256)
+			attStmt := cborMap(
+				cborString("alg"), cborInt(int64(ES256)),
+				cborString("sig"), cborBytes(signES256(t, credKey, append(append([]byte{}, authData...), clientDataHash[:]...))),
+				cborString("x5c"), cborCertificates(credCert),
+			)
+			attestationObject := testAttestationObject(FormatAndroidKey, attStmt, authData)
+
+			rp := &RelyingParty{ID: rpID, Origin: origin}
+			opts := tc.opts
+			opts.GetRoots = func(AAGUID) (*x509.CertPool, error) {
+				return ca.pool(), nil
+			}
+			got, err := rp.VerifyAttestationAndroidKey(challenge, clientDataJSON, attestationObject, &opts)
+			if tc.wantErr != "" {
+				if err == nil {
+					t.Fatalf("Expected error verifying attestation")
+				}
+				if !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("Verifying attestation returned unexpected error, got=%v, want=%s", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Verifying attestation: %v", err)
+			}
+			if got.AttestationSecurityLevel != AndroidSecurityLevel(tc.securityLevel) {
+				t.Errorf("Unexpected security level, got=%s, want=%d", got.AttestationSecurityLevel, tc.securityLevel)
+			}
+			if got.AttestationData.AAGUID != aaguid {
+				t.Errorf("Unexpected aaguid, got=%s, want=%s", got.AttestationData.AAGUID, aaguid)
+			}
+		})
+	}
+}
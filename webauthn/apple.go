@@ -0,0 +1,189 @@
+package webauthn
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"fmt"
+
+	"github.com/go-passkeys/go-passkeys/webauthn/internal/cbor"
+)
+
+var idAppleAnonymousAttestation = asn1.ObjectIdentifier{1, 2, 840, 113635, 100, 8, 2}
+
+// AppleOptions allows configuration for validating Apple anonymous attestation
+// statements.
+//
+// https://www.w3.org/TR/webauthn-3/#sctn-apple-anonymous-attestation
+type AppleOptions struct {
+	// Roots holds the Apple WebAuthn Root CA, which can be downloaded from
+	// Apple's certificate authority page.
+	//
+	// https://www.apple.com/certificateauthority/private/
+	Roots *x509.CertPool
+}
+
+// Apple holds a parsed Apple anonymous attestation format.
+//
+// https://www.w3.org/TR/webauthn-3/#sctn-apple-anonymous-attestation
+type Apple struct {
+	// Parsed and validated authenticator data.
+	AttestationData *Attestation
+
+	// AttestationCertificate is the per-credential certificate issued by Apple's
+	// anonymization CA, which chains up to a root certificate within the
+	// configuration.
+	//
+	// https://www.w3.org/TR/webauthn-3/#anonca
+	AttestationCertificate *x509.Certificate
+}
+
+// VerifyAttestationApple is similar to VerifyAttestation, but additionally
+// performs validation of "apple" attestation statements.
+//
+// Apple anonymous attestations are returned by iOS and macOS devices when the
+// relying party requests attestation.
+//
+// https://www.w3.org/TR/webauthn-3/#sctn-apple-anonymous-attestation
+func (rp *RelyingParty) VerifyAttestationApple(challenge, clientDataJSON, attestationObject []byte, opts *AppleOptions) (*Apple, error) {
+	cd, err := rp.verifyClientData(clientDataJSON, "webauthn.create", challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	attObj, err := parseAttestationObject(attestationObject)
+	if err != nil {
+		return nil, fmt.Errorf("parsing attestation object: %v", err)
+	}
+
+	data, err := attObj.VerifyApple(rp.ID, clientDataJSON, opts)
+	if err != nil {
+		return nil, fmt.Errorf("parsing authenticator data: %v", err)
+	}
+	if err := rp.verifyAttestationData(cd, data.AttestationData); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// VerifyApple validates an attestation object and client JSON data against an
+// Apple anonymous attestation statement.
+//
+// https://www.w3.org/TR/webauthn-3/#sctn-apple-anonymous-attestation
+func (o *attestationObject) VerifyApple(rpid string, clientDataJSON []byte, opts *AppleOptions) (*Apple, error) {
+	ad, err := parseAuthData(o.authData, rpid)
+	if err != nil {
+		return nil, fmt.Errorf("invalid auth data: %v", err)
+	}
+	a, _, err := verifyApple(ad, o.authData, o.attestationStatement, clientDataJSON, opts)
+	return a, err
+}
+
+// verifyApple validates an apple attestation statement against already parsed
+// authenticator data, returning the verified certificate chain, leaf first.
+func verifyApple(ad *Attestation, authData, attStmt, clientDataJSON []byte, opts *AppleOptions) (*Apple, []*x509.Certificate, error) {
+	if opts == nil || opts.Roots == nil {
+		return nil, nil, fmt.Errorf("no root certificates provided")
+	}
+
+	rawX5C, err := parseApple(attStmt)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid attestation statement: %v", err)
+	}
+	x5c, err := parseCertificates(rawX5C)
+	if err != nil {
+		return nil, nil, err
+	}
+	credCert := x5c[0]
+
+	// "Concatenate authenticatorData and clientDataHash to form
+	// nonceToHash. Perform SHA-256 hash of nonceToHash to produce nonce."
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	h := sha256.New()
+	h.Write(authData)
+	h.Write(clientDataHash[:])
+	nonce := h.Sum(nil)
+
+	// "Verify that nonce equals the value of the extension with OID
+	// 1.2.840.113635.100.8.2 in credCert."
+	var ext []byte
+	for _, e := range credCert.Extensions {
+		if e.Id.Equal(idAppleAnonymousAttestation) {
+			ext = e.Value
+			break
+		}
+	}
+	if len(ext) == 0 {
+		return nil, nil, fmt.Errorf("attestation certificate has no apple anonymous attestation extension")
+	}
+	var certNonce struct {
+		Nonce []byte `asn1:"tag:1,explicit"`
+	}
+	if rest, err := asn1.Unmarshal(ext, &certNonce); err != nil || len(rest) != 0 {
+		return nil, nil, fmt.Errorf("parsing apple anonymous attestation extension")
+	}
+	if !bytes.Equal(certNonce.Nonce, nonce) {
+		return nil, nil, fmt.Errorf("attestation certificate nonce doesn't match attested data")
+	}
+
+	// "Verify that the credential public key equals the Subject Public Key of
+	// credCert."
+	credPub, ok := ad.PublicKey.(interface{ Equal(crypto.PublicKey) bool })
+	if !ok || !credPub.Equal(credCert.PublicKey) {
+		return nil, nil, fmt.Errorf("attestation certificate key doesn't match credential public key")
+	}
+
+	v := x509.VerifyOptions{
+		Roots:     opts.Roots,
+		KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	}
+	if len(x5c) > 1 {
+		v.Intermediates = x509.NewCertPool()
+		for _, cert := range x5c[1:] {
+			v.Intermediates.AddCert(cert)
+		}
+	}
+	if _, err := credCert.Verify(v); err != nil {
+		return nil, nil, fmt.Errorf("failed to verify attestation certificate: %v", err)
+	}
+	return &Apple{
+		AttestationData:        ad,
+		AttestationCertificate: credCert,
+	}, x5c, nil
+}
+
+// parseApple returns the certificate chain of an apple attestation statement.
+//
+// https://www.w3.org/TR/webauthn-3/#sctn-apple-anonymous-attestation
+func parseApple(b []byte) ([][]byte, error) {
+	d := cbor.NewDecoder(b)
+	var x5c [][]byte
+	ok := d.Map(func(kv *cbor.Decoder) bool {
+		var key string
+		if !kv.String(&key) {
+			return false
+		}
+		switch key {
+		case "x5c":
+			return kv.Array(func(d *cbor.Decoder) bool {
+				var cert []byte
+				if !d.Bytes(&cert) {
+					return false
+				}
+				x5c = append(x5c, cert)
+				return true
+			})
+		default:
+			return kv.Skip()
+		}
+	}) && d.Done()
+	if !ok {
+		return nil, fmt.Errorf("attestation statement was not valid cbor")
+	}
+	if len(x5c) == 0 {
+		return nil, fmt.Errorf("attestation statement contains no certificates")
+	}
+	return x5c, nil
+}
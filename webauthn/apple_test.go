@@ -0,0 +1,101 @@
+package webauthn
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"strings"
+	"testing"
+)
+
+func TestVerifyAttestationApple(t *testing.T) {
+	const (
+		rpID   = "localhost"
+		origin = "http://localhost:8080"
+	)
+
+	testCases := []struct {
+		name     string
+		badNonce bool
+		otherKey bool
+		wantErr  string
+	}{
+		{
+			name: "Valid",
+		},
+		{
+			name:     "Bad nonce",
+			badNonce: true,
+			wantErr:  "nonce doesn't match",
+		},
+		{
+			name:     "Mismatched certificate key",
+			otherKey: true,
+			wantErr:  "doesn't match credential public key",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ca := newTestCA(t)
+			credKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+			if err != nil {
+				t.Fatalf("Generating key: %v", err)
+			}
+			certKey := credKey
+			if tc.otherKey {
+				certKey, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+				if err != nil {
+					t.Fatalf("Generating key: %v", err)
+				}
+			}
+
+			challenge := []byte("apple-test-challenge")
+			clientDataJSON := testClientDataJSON("webauthn.create", origin, challenge)
+			clientDataHash := sha256.Sum256(clientDataJSON)
+			authData := testAuthData(t, rpID, 0x45, AAGUID{}, []byte("credential"), &credKey.PublicKey, ES256)
+
+			nonce := sha256.Sum256(append(append([]byte{}, authData...), clientDataHash[:]...))
+			if tc.badNonce {
+				nonce = sha256.Sum256([]byte("bad"))
+			}
+			ext, err := asn1.Marshal(struct {
+				Nonce []byte `asn1:"tag:1,explicit"`
+			}{nonce[:]})
+			if err != nil {
+				t.Fatalf("Encoding nonce extension: %v", err)
+			}
+			credCert := ca.issue(t, &x509.Certificate{
+				Subject: pkix.Name{CommonName: "credential"},
+				ExtraExtensions: []pkix.Extension{
+					{Id: idAppleAnonymousAttestation, Value: ext},
+				},
+			}, certKey.Public())
+
+			attStmt := cborMap(cborString("x5c"), cborCertificates(credCert))
+			attestationObject := testAttestationObject(FormatApple, attStmt, authData)
+
+			rp := &RelyingParty{ID: rpID, Origin: origin}
+			got, err := rp.VerifyAttestationApple(challenge, clientDataJSON, attestationObject, &AppleOptions{Roots: ca.pool()})
+			if tc.wantErr != "" {
+				if err == nil {
+					t.Fatalf("Expected error verifying attestation")
+				}
+				if !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("Verifying attestation returned unexpected error, got=%v, want=%s", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Verifying attestation: %v", err)
+			}
+			if got.AttestationCertificate.Subject.CommonName != "credential" {
+				t.Errorf("Unexpected attestation certificate: %s", got.AttestationCertificate.Subject)
+			}
+		})
+	}
+}
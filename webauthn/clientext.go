@@ -0,0 +1,306 @@
+package webauthn
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Base64URL is binary data encoded as an unpadded base64url string in JSON, the
+// encoding used by the JSON serializations of the WebAuthn API.
+//
+// https://www.w3.org/TR/webauthn-3/#typedefdef-base64urlstring
+type Base64URL []byte
+
+// MarshalJSON implements the json.Marshaler interface.
+func (b Base64URL) MarshalJSON() ([]byte, error) {
+	return json.Marshal(base64.RawURLEncoding.EncodeToString(b))
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface. Padding is
+// permitted, but not required.
+func (b *Base64URL) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("base64url value doesn't parse into string: %v", err)
+	}
+	v, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+	if err != nil {
+		return err
+	}
+	*b = v
+	return nil
+}
+
+// ClientExtensionInputs holds the client extensions requested by the relying
+// party, as passed to the "extensions" member of creation or request options.
+//
+// https://www.w3.org/TR/webauthn-3/#dictdef-authenticationextensionsclientinputs
+type ClientExtensionInputs struct {
+	// CredProps requests the properties of a new credential.
+	//
+	// https://www.w3.org/TR/webauthn-3/#sctn-authenticator-credential-properties-extension
+	CredProps bool `json:"credProps,omitempty"`
+
+	// PRF requests the evaluation of a pseudo-random function associated
+	// with the credential.
+	//
+	// https://www.w3.org/TR/webauthn-3/#prf-extension
+	PRF *PRFInputs `json:"prf,omitempty"`
+
+	// LargeBlob requests support for, or access to, a blob stored alongside
+	// the credential.
+	//
+	// https://www.w3.org/TR/webauthn-3/#sctn-large-blob-extension
+	LargeBlob *LargeBlobInputs `json:"largeBlob,omitempty"`
+
+	// AppID is a FIDO AppID used to authenticate with U2F credentials. See
+	// [RelyingParty.AppID].
+	//
+	// https://www.w3.org/TR/webauthn-3/#sctn-appid-extension
+	AppID string `json:"appid,omitempty"`
+
+	// AppIDExclude is a FIDO AppID whose U2F credentials are excluded during
+	// registration.
+	//
+	// https://www.w3.org/TR/webauthn-3/#sctn-appid-exclude-extension
+	AppIDExclude string `json:"appidExclude,omitempty"`
+}
+
+// PRFValues holds one or two inputs, or outputs, of a pseudo-random function.
+//
+// https://www.w3.org/TR/webauthn-3/#dictdef-authenticationextensionsprfvalues
+type PRFValues struct {
+	First  Base64URL `json:"first"`
+	Second Base64URL `json:"second,omitempty"`
+}
+
+// PRFInputs holds the inputs of the "prf" extension.
+//
+// https://www.w3.org/TR/webauthn-3/#dictdef-authenticationextensionsprfinputs
+type PRFInputs struct {
+	// Eval holds the values to evaluate for any credential.
+	Eval *PRFValues `json:"eval,omitempty"`
+	// EvalByCredential maps base64url encoded credential IDs to values to
+	// evaluate for that credential. Only valid during authentication.
+	EvalByCredential map[string]PRFValues `json:"evalByCredential,omitempty"`
+}
+
+// LargeBlobInputs holds the inputs of the "largeBlob" extension.
+//
+// https://www.w3.org/TR/webauthn-3/#dictdef-authenticationextensionslargeblobinputs
+type LargeBlobInputs struct {
+	// Support is "required" or "preferred". Only valid during registration.
+	Support string `json:"support,omitempty"`
+	// Read requests the blob be returned. Only valid during authentication.
+	Read bool `json:"read,omitempty"`
+	// Write is a blob to store. Only valid during authentication.
+	Write Base64URL `json:"write,omitempty"`
+}
+
+// ClientExtensionResults holds the client extension outputs returned by the
+// browser's getClientExtensionResults() method. Fields are nil if the client
+// didn't return the extension.
+//
+// https://www.w3.org/TR/webauthn-3/#dictdef-authenticationextensionsclientoutputs
+type ClientExtensionResults struct {
+	CredProps *CredentialPropertiesOutput `json:"credProps,omitempty"`
+	PRF       *PRFOutputs                 `json:"prf,omitempty"`
+	LargeBlob *LargeBlobOutputs           `json:"largeBlob,omitempty"`
+	// AppID reports if the client used the AppID in place of the RP ID.
+	AppID *bool `json:"appid,omitempty"`
+	// AppIDExclude reports if the client acted on the appidExclude input.
+	AppIDExclude *bool `json:"appidExclude,omitempty"`
+}
+
+// CredentialPropertiesOutput holds the output of the "credProps" extension.
+//
+// https://www.w3.org/TR/webauthn-3/#dictdef-credentialpropertiesoutput
+type CredentialPropertiesOutput struct {
+	// ResidentKey reports if the credential is a discoverable credential. Nil
+	// if the client couldn't determine this.
+	ResidentKey *bool `json:"rk,omitempty"`
+}
+
+// PRFOutputs holds the output of the "prf" extension.
+//
+// https://www.w3.org/TR/webauthn-3/#dictdef-authenticationextensionsprfoutputs
+type PRFOutputs struct {
+	// Enabled reports if a new credential supports PRF evaluation. Only
+	// returned during registration.
+	Enabled *bool `json:"enabled,omitempty"`
+	// Results holds the evaluated outputs.
+	Results *PRFValues `json:"results,omitempty"`
+}
+
+// LargeBlobOutputs holds the output of the "largeBlob" extension.
+//
+// https://www.w3.org/TR/webauthn-3/#dictdef-authenticationextensionslargebloboutputs
+type LargeBlobOutputs struct {
+	// Supported reports if a new credential supports large blobs. Only
+	// returned during registration.
+	Supported *bool `json:"supported,omitempty"`
+	// Blob holds the blob read during authentication.
+	Blob Base64URL `json:"blob,omitempty"`
+	// Written reports if the blob provided during authentication was stored.
+	Written *bool `json:"written,omitempty"`
+}
+
+// ParseClientExtensionResults parses the JSON serialization of client
+// extension results, such as returned by PublicKeyCredential.toJSON(). Binary
+// values are expected to be base64url encoded.
+//
+//	const cred = await navigator.credentials.get({
+//		publicKey: {
+//			// ...
+//		},
+//	});
+//	console.log(JSON.stringify(cred.toJSON().clientExtensionResults));
+func ParseClientExtensionResults(b []byte) (*ClientExtensionResults, error) {
+	var r ClientExtensionResults
+	if err := json.Unmarshal(b, &r); err != nil {
+		return nil, fmt.Errorf("parsing client extension results: %v", err)
+	}
+	return &r, nil
+}
+
+// VerifyRegistrationExtensions checks that client extension results returned
+// during registration are consistent with the extensions requested. Clients
+// must not return outputs for extensions that weren't requested, and the
+// "appidExclude" input must be the relying party's AppID.
+//
+// https://www.w3.org/TR/webauthn-3/#sctn-registering-a-new-credential
+func (rp *RelyingParty) VerifyRegistrationExtensions(inputs *ClientExtensionInputs, results *ClientExtensionResults) error {
+	if inputs == nil {
+		inputs = &ClientExtensionInputs{}
+	}
+	if results == nil {
+		return nil
+	}
+	if results.CredProps != nil && !inputs.CredProps {
+		return fmt.Errorf("client returned unrequested extension: credProps")
+	}
+	if results.AppID != nil {
+		return fmt.Errorf("appid returned during registration")
+	}
+	if results.AppIDExclude != nil {
+		if inputs.AppIDExclude == "" {
+			return fmt.Errorf("client returned unrequested extension: appidExclude")
+		}
+		if err := rp.verifyAppIDInput("appidExclude", inputs.AppIDExclude); err != nil {
+			return err
+		}
+	}
+	if prf := results.PRF; prf != nil {
+		if inputs.PRF == nil {
+			return fmt.Errorf("client returned unrequested extension: prf")
+		}
+		if prf.Results != nil {
+			if err := verifyPRFResults(inputs.PRF.Eval, prf.Results); err != nil {
+				return err
+			}
+		}
+	}
+	if lb := results.LargeBlob; lb != nil {
+		if inputs.LargeBlob == nil {
+			return fmt.Errorf("client returned unrequested extension: largeBlob")
+		}
+		if lb.Blob != nil || lb.Written != nil {
+			return fmt.Errorf("largeBlob returned blob or written during registration")
+		}
+	}
+	return nil
+}
+
+// VerifyAuthenticationExtensions checks that client extension results returned
+// during authentication are consistent with the extensions requested. The
+// credential ID is used to select "prf" inputs provided by credential, and the
+// "appid" input must be the relying party's AppID.
+//
+// https://www.w3.org/TR/webauthn-3/#sctn-verifying-assertion
+func (rp *RelyingParty) VerifyAuthenticationExtensions(inputs *ClientExtensionInputs, credentialID []byte, results *ClientExtensionResults) error {
+	if inputs == nil {
+		inputs = &ClientExtensionInputs{}
+	}
+	if results == nil {
+		return nil
+	}
+	if results.CredProps != nil {
+		return fmt.Errorf("credProps returned during authentication")
+	}
+	if results.AppIDExclude != nil {
+		return fmt.Errorf("appidExclude returned during authentication")
+	}
+	if results.AppID != nil {
+		if inputs.AppID == "" {
+			return fmt.Errorf("client returned unrequested extension: appid")
+		}
+		if err := rp.verifyAppIDInput("appid", inputs.AppID); err != nil {
+			return err
+		}
+	}
+	if prf := results.PRF; prf != nil {
+		if inputs.PRF == nil {
+			return fmt.Errorf("client returned unrequested extension: prf")
+		}
+		if prf.Enabled != nil {
+			return fmt.Errorf("prf returned enabled during authentication")
+		}
+		if prf.Results != nil {
+			eval := inputs.PRF.Eval
+			id := base64.RawURLEncoding.EncodeToString(credentialID)
+			if v, ok := inputs.PRF.EvalByCredential[id]; ok {
+				eval = &v
+			}
+			if err := verifyPRFResults(eval, prf.Results); err != nil {
+				return err
+			}
+		}
+	}
+	if lb := results.LargeBlob; lb != nil {
+		in := inputs.LargeBlob
+		if in == nil {
+			return fmt.Errorf("client returned unrequested extension: largeBlob")
+		}
+		if lb.Supported != nil {
+			return fmt.Errorf("largeBlob returned supported during authentication")
+		}
+		if lb.Blob != nil && !in.Read {
+			return fmt.Errorf("largeBlob returned blob without read being requested")
+		}
+		if lb.Written != nil && in.Write == nil {
+			return fmt.Errorf("largeBlob returned written without write being requested")
+		}
+	}
+	return nil
+}
+
+// verifyAppIDInput checks that an AppID passed to the client as an extension
+// input is the relying party's AppID.
+func (rp *RelyingParty) verifyAppIDInput(ext, appID string) error {
+	if rp.AppID == "" {
+		return fmt.Errorf("%s extension requested, but relying party has no AppID", ext)
+	}
+	if appID != rp.AppID {
+		return fmt.Errorf("%s extension input %q doesn't match relying party AppID %q", ext, appID, rp.AppID)
+	}
+	return nil
+}
+
+// verifyPRFResults checks that PRF outputs correspond to the requested inputs.
+func verifyPRFResults(eval, results *PRFValues) error {
+	if eval == nil {
+		return fmt.Errorf("prf returned results without inputs to evaluate")
+	}
+	if len(results.First) == 0 {
+		return fmt.Errorf("prf results missing first output")
+	}
+	if results.Second != nil && eval.Second == nil {
+		return fmt.Errorf("prf returned second output without second input")
+	}
+	if results.Second == nil && eval.Second != nil {
+		return fmt.Errorf("prf results missing second output")
+	}
+	return nil
+}
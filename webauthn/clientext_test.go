@@ -0,0 +1,208 @@
+package webauthn
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestBase64URL(t *testing.T) {
+	want := Base64URL{0xfb, 0xff}
+	b, err := json.Marshal(want)
+	if err != nil {
+		t.Fatalf("Marshaling value: %v", err)
+	}
+	if string(b) != `"-_8"` {
+		t.Errorf("Unexpected encoding, got=%s, want=%s", b, `"-_8"`)
+	}
+	for _, s := range []string{`"-_8"`, `"-_8="`} {
+		var got Base64URL
+		if err := json.Unmarshal([]byte(s), &got); err != nil {
+			t.Fatalf("Unmarshaling %s: %v", s, err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("Unmarshaling %s returned unexpected value, got=%x, want=%x", s, got, want)
+		}
+	}
+	var got Base64URL
+	if err := json.Unmarshal([]byte(`"+/8"`), &got); err == nil {
+		t.Errorf("Expected error unmarshaling standard base64")
+	}
+}
+
+func TestParseClientExtensionResults(t *testing.T) {
+	r, err := ParseClientExtensionResults([]byte(`{
+		"credProps": {"rk": true},
+		"prf": {"enabled": true, "results": {"first": "Zmlyc3Q"}},
+		"largeBlob": {"supported": false}
+	}`))
+	if err != nil {
+		t.Fatalf("Parsing client extension results: %v", err)
+	}
+	if r.CredProps == nil || r.CredProps.ResidentKey == nil || !*r.CredProps.ResidentKey {
+		t.Errorf("Expected credProps.rk to be true")
+	}
+	if r.PRF == nil || r.PRF.Enabled == nil || !*r.PRF.Enabled {
+		t.Errorf("Expected prf.enabled to be true")
+	}
+	if r.PRF == nil || r.PRF.Results == nil || string(r.PRF.Results.First) != "first" {
+		t.Errorf("Unexpected prf results: %+v", r.PRF)
+	}
+	if r.LargeBlob == nil || r.LargeBlob.Supported == nil || *r.LargeBlob.Supported {
+		t.Errorf("Expected largeBlob.supported to be false")
+	}
+	if r.AppIDExclude != nil {
+		t.Errorf("Unexpected appidExclude result")
+	}
+}
+
+func TestVerifyClientExtensions(t *testing.T) {
+	credID := []byte("credential")
+	testCases := []struct {
+		name         string
+		registration bool
+		inputs       string
+		results      string
+		wantErr      string
+	}{
+		{
+			name:         "Registration",
+			registration: true,
+			inputs:       `{"credProps": true, "prf": {}, "largeBlob": {"support": "preferred"}}`,
+			results:      `{"credProps": {"rk": true}, "prf": {"enabled": true}, "largeBlob": {"supported": true}}`,
+		},
+		{
+			name:         "Registration unrequested credProps",
+			registration: true,
+			inputs:       `{}`,
+			results:      `{"credProps": {"rk": true}}`,
+			wantErr:      "unrequested extension: credProps",
+		},
+		{
+			name:         "Registration prf results",
+			registration: true,
+			inputs:       `{"prf": {"eval": {"first": "AQ"}}}`,
+			results:      `{"prf": {"enabled": true, "results": {"first": "Ag"}}}`,
+		},
+		{
+			name:         "Registration prf results without eval",
+			registration: true,
+			inputs:       `{"prf": {}}`,
+			results:      `{"prf": {"enabled": true, "results": {"first": "Ag"}}}`,
+			wantErr:      "without inputs",
+		},
+		{
+			name:         "Registration largeBlob written",
+			registration: true,
+			inputs:       `{"largeBlob": {"support": "required"}}`,
+			results:      `{"largeBlob": {"written": true}}`,
+			wantErr:      "during registration",
+		},
+		{
+			name:         "Registration appidExclude",
+			registration: true,
+			inputs:       `{"appidExclude": "https://example.com/app-id.json"}`,
+			results:      `{"appidExclude": true}`,
+		},
+		{
+			name:         "Registration appidExclude other AppID",
+			registration: true,
+			inputs:       `{"appidExclude": "https://other.example.com/app-id.json"}`,
+			results:      `{"appidExclude": true}`,
+			wantErr:      "doesn't match relying party AppID",
+		},
+		{
+			name:    "Authentication prf",
+			inputs:  `{"prf": {"eval": {"first": "AQ", "second": "Ag"}}}`,
+			results: `{"prf": {"results": {"first": "Aw", "second": "BA"}}}`,
+		},
+		{
+			name:    "Authentication prf missing second",
+			inputs:  `{"prf": {"eval": {"first": "AQ", "second": "Ag"}}}`,
+			results: `{"prf": {"results": {"first": "Aw"}}}`,
+			wantErr: "missing second",
+		},
+		{
+			name:    "Authentication prf by credential",
+			inputs:  `{"prf": {"evalByCredential": {"Y3JlZGVudGlhbA": {"first": "AQ", "second": "Ag"}}}}`,
+			results: `{"prf": {"results": {"first": "Aw", "second": "BA"}}}`,
+		},
+		{
+			name:    "Authentication prf for other credential",
+			inputs:  `{"prf": {"evalByCredential": {"b3RoZXI": {"first": "AQ"}}}}`,
+			results: `{"prf": {"results": {"first": "Aw"}}}`,
+			wantErr: "without inputs",
+		},
+		{
+			name:    "Authentication unrequested prf",
+			inputs:  `{}`,
+			results: `{"prf": {"results": {"first": "Aw"}}}`,
+			wantErr: "unrequested extension: prf",
+		},
+		{
+			name:    "Authentication largeBlob read",
+			inputs:  `{"largeBlob": {"read": true}}`,
+			results: `{"largeBlob": {"blob": "AQID"}}`,
+		},
+		{
+			name:    "Authentication largeBlob unrequested write",
+			inputs:  `{"largeBlob": {"read": true}}`,
+			results: `{"largeBlob": {"written": true}}`,
+			wantErr: "without write",
+		},
+		{
+			name:    "Authentication appid",
+			inputs:  `{"appid": "https://example.com/app-id.json"}`,
+			results: `{"appid": true}`,
+		},
+		{
+			name:    "Authentication unrequested appid",
+			inputs:  `{}`,
+			results: `{"appid": true}`,
+			wantErr: "unrequested extension: appid",
+		},
+		{
+			name:    "Authentication appid other AppID",
+			inputs:  `{"appid": "https://other.example.com/app-id.json"}`,
+			results: `{"appid": true}`,
+			wantErr: "doesn't match relying party AppID",
+		},
+		{
+			name:    "Authentication credProps",
+			inputs:  `{"credProps": true}`,
+			results: `{"credProps": {"rk": true}}`,
+			wantErr: "during authentication",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var inputs ClientExtensionInputs
+			if err := json.Unmarshal([]byte(tc.inputs), &inputs); err != nil {
+				t.Fatalf("Parsing inputs: %v", err)
+			}
+			results, err := ParseClientExtensionResults([]byte(tc.results))
+			if err != nil {
+				t.Fatalf("Parsing results: %v", err)
+			}
+			rp := &RelyingParty{AppID: "https://example.com/app-id.json"}
+			if tc.registration {
+				err = rp.VerifyRegistrationExtensions(&inputs, results)
+			} else {
+				err = rp.VerifyAuthenticationExtensions(&inputs, credID, results)
+			}
+			if tc.wantErr != "" {
+				if err == nil {
+					t.Fatalf("Expected error verifying extensions")
+				}
+				if !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("Verifying extensions returned unexpected error, got=%v, want=%s", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Verifying extensions: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,249 @@
+package webauthn
+
+import (
+	"fmt"
+
+	"github.com/go-passkeys/go-passkeys/webauthn/internal/cbor"
+)
+
+// CompoundOptions allows configuration for validating compound attestation
+// statements. Each nested statement is validated using the options for its
+// format. Statements using a format without options are rejected.
+//
+// https://www.w3.org/TR/webauthn-3/#sctn-compound-attestation
+type CompoundOptions struct {
+	Packed     *PackedOptions
+	TPM        *TPMOptions
+	AndroidKey *AndroidKeyOptions
+	Apple      *AppleOptions
+	FIDOU2F    *FIDOU2FOptions
+	SafetyNet  *SafetyNetOptions
+}
+
+// Compound holds a parsed compound attestation format, which contains multiple
+// attestation statements over the same authenticator data.
+//
+// https://www.w3.org/TR/webauthn-3/#sctn-compound-attestation
+type Compound struct {
+	// Parsed and validated authenticator data.
+	AttestationData *Attestation
+
+	// Statements holds the result of validating each nested statement, in the
+	// order they appear in the attestation.
+	Statements []*CompoundStatement
+}
+
+// CompoundStatement holds the result of a single validated statement within a
+// compound attestation. Only the field corresponding to Format is set.
+type CompoundStatement struct {
+	// Format of the statement, such as "packed" or "tpm".
+	Format string
+
+	Packed     *Packed
+	TPM        *TPM
+	AndroidKey *AndroidKey
+	Apple      *Apple
+	FIDOU2F    *FIDOU2F
+	SafetyNet  *SafetyNet
+}
+
+// VerifyAttestationCompound is similar to VerifyAttestation, but additionally
+// performs validation of "compound" attestation statements.
+//
+// https://www.w3.org/TR/webauthn-3/#sctn-compound-attestation
+func (rp *RelyingParty) VerifyAttestationCompound(challenge, clientDataJSON, attestationObject []byte, opts *CompoundOptions) (*Compound, error) {
+	cd, err := rp.verifyClientData(clientDataJSON, "webauthn.create", challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	attObj, err := parseAttestationObject(attestationObject)
+	if err != nil {
+		return nil, fmt.Errorf("parsing attestation object: %v", err)
+	}
+
+	data, err := attObj.VerifyCompound(rp.ID, clientDataJSON, opts)
+	if err != nil {
+		return nil, fmt.Errorf("parsing authenticator data: %v", err)
+	}
+	if err := rp.verifyAttestationData(cd, data.AttestationData); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// VerifyCompound validates an attestation object and client JSON data against
+// a compound attestation statement. Every nested statement must be valid, and
+// at least two statements must be present.
+//
+// https://www.w3.org/TR/webauthn-3/#sctn-compound-attestation
+func (o *attestationObject) VerifyCompound(rpid string, clientDataJSON []byte, opts *CompoundOptions) (*Compound, error) {
+	ad, err := parseAuthData(o.authData, rpid)
+	if err != nil {
+		return nil, fmt.Errorf("invalid auth data: %v", err)
+	}
+	c, _, err := verifyCompound(ad, o.authData, o.attestationStatement, clientDataJSON, opts)
+	return c, err
+}
+
+// VerifyAttestationStatement implements [AttestationVerifier] for "compound"
+// attestation statements. The result's Details holds a [*Compound]. Since
+// [AttestationResult] conveys a single attestation type, the type and trust
+// path of the first nested statement are used.
+func (o *CompoundOptions) VerifyAttestationStatement(stmt *AttestationStatement) (*AttestationResult, error) {
+	ad, err := stmt.attestationData()
+	if err != nil {
+		return nil, err
+	}
+	c, results, err := verifyCompound(ad, stmt.AuthData, stmt.Statement, stmt.ClientDataJSON, o)
+	if err != nil {
+		return nil, err
+	}
+	return &AttestationResult{
+		Type:      results[0].Type,
+		TrustPath: results[0].TrustPath,
+		Details:   c,
+	}, nil
+}
+
+// verifier returns the verifier configured for a nested statement format.
+func (o *CompoundOptions) verifier(format string) (AttestationVerifier, error) {
+	var v AttestationVerifier
+	switch format {
+	case FormatPacked:
+		if o.Packed != nil {
+			v = o.Packed
+		}
+	case FormatTPM:
+		if o.TPM != nil {
+			v = o.TPM
+		}
+	case FormatAndroidKey:
+		if o.AndroidKey != nil {
+			v = o.AndroidKey
+		}
+	case FormatApple:
+		if o.Apple != nil {
+			v = o.Apple
+		}
+	case FormatFIDOU2F:
+		if o.FIDOU2F != nil {
+			v = o.FIDOU2F
+		}
+	case FormatAndroidSafetyNet:
+		if o.SafetyNet != nil {
+			v = o.SafetyNet
+		}
+	default:
+		return nil, fmt.Errorf("unsupported format")
+	}
+	if v == nil {
+		return nil, fmt.Errorf("no options configured for format")
+	}
+	return v, nil
+}
+
+// verifyCompound validates a compound attestation statement against already
+// parsed authenticator data. It returns the result of each nested statement,
+// in order.
+func verifyCompound(ad *Attestation, authData, attStmt, clientDataJSON []byte, opts *CompoundOptions) (*Compound, []*AttestationResult, error) {
+	if opts == nil {
+		return nil, nil, fmt.Errorf("options must be provided")
+	}
+
+	stmts, err := parseCompound(attStmt)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid attestation statement: %v", err)
+	}
+	if len(stmts) < 2 {
+		return nil, nil, fmt.Errorf("compound attestation must contain at least two statements, got %d", len(stmts))
+	}
+
+	c := &Compound{AttestationData: ad}
+	var results []*AttestationResult
+	for i, stmt := range stmts {
+		v, err := opts.verifier(stmt.format)
+		if err != nil {
+			return nil, nil, fmt.Errorf("statement %d (%s): %v", i, stmt.format, err)
+		}
+
+		// "Verify that attStmt is a valid attestation statement using the
+		// verification procedure of the attestation statement format
+		// identified by fmt", using the same authenticator and client data.
+		res, err := v.VerifyAttestationStatement(&AttestationStatement{
+			Format:          stmt.format,
+			Statement:       stmt.attStmt,
+			AuthData:        authData,
+			ClientDataJSON:  clientDataJSON,
+			AttestationData: ad,
+		})
+		if err != nil {
+			return nil, nil, fmt.Errorf("statement %d (%s): %v", i, stmt.format, err)
+		}
+		s := &CompoundStatement{Format: stmt.format}
+		switch d := res.Details.(type) {
+		case *Packed:
+			s.Packed = d
+		case *TPM:
+			s.TPM = d
+		case *AndroidKey:
+			s.AndroidKey = d
+		case *Apple:
+			s.Apple = d
+		case *FIDOU2F:
+			s.FIDOU2F = d
+		case *SafetyNet:
+			s.SafetyNet = d
+		}
+		c.Statements = append(c.Statements, s)
+		results = append(results, res)
+	}
+	return c, results, nil
+}
+
+type compoundStatement struct {
+	format  string
+	attStmt []byte
+}
+
+// parseCompound parses the array of nested statements of a compound
+// attestation statement.
+//
+// https://www.w3.org/TR/webauthn-3/#sctn-compound-attestation
+func parseCompound(b []byte) ([]compoundStatement, error) {
+	d := cbor.NewDecoder(b)
+	var stmts []compoundStatement
+	ok := d.Array(func(d *cbor.Decoder) bool {
+		var s compoundStatement
+		if !d.Map(func(kv *cbor.Decoder) bool {
+			var key string
+			if !kv.String(&key) {
+				return false
+			}
+			switch key {
+			case "fmt":
+				return kv.String(&s.format)
+			case "attStmt":
+				return kv.Raw(&s.attStmt)
+			default:
+				return kv.Skip()
+			}
+		}) {
+			return false
+		}
+		stmts = append(stmts, s)
+		return true
+	}) && d.Done()
+	if !ok {
+		return nil, fmt.Errorf("attestation statement was not valid cbor")
+	}
+	for i, s := range stmts {
+		if s.format == "" || len(s.attStmt) == 0 {
+			return nil, fmt.Errorf("statement %d missing format or attestation statement", i)
+		}
+		if s.format == FormatCompound {
+			return nil, fmt.Errorf("statement %d is a nested compound attestation", i)
+		}
+	}
+	return stmts, nil
+}
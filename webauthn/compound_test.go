@@ -0,0 +1,159 @@
+package webauthn
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"strings"
+	"testing"
+)
+
+func TestVerifyAttestationCompound(t *testing.T) {
+	const (
+		rpID   = "localhost"
+		origin = "http://localhost:8080"
+	)
+
+	ca := newTestCA(t)
+	credKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Generating key: %v", err)
+	}
+
+	challenge := []byte("compound-test-challenge")
+	clientDataJSON := testClientDataJSON("webauthn.create", origin, challenge)
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	authData := testAuthData(t, rpID, 0x45, AAGUID{}, []byte("credential"), &credKey.PublicKey, ES256)
+	signedData := append(append([]byte{}, authData...), clientDataHash[:]...)
+
+	packedStmt := cborMap(
+		cborString("alg"), cborInt(int64(ES256)),
+		cborString("sig"), cborBytes(signES256(t, credKey, signedData)),
+	)
+
+	nonce := sha256.Sum256(signedData)
+	ext, err := asn1.Marshal(struct {
+		Nonce []byte `asn1:"tag:1,explicit"`
+	}{nonce[:]})
+	if err != nil {
+		t.Fatalf("Encoding nonce extension: %v", err)
+	}
+	credCert := ca.issue(t, &x509.Certificate{
+		Subject: pkix.Name{CommonName: "credential"},
+		ExtraExtensions: []pkix.Extension{
+			{Id: idAppleAnonymousAttestation, Value: ext},
+		},
+	}, credKey.Public())
+	appleStmt := cborMap(cborString("x5c"), cborCertificates(credCert))
+
+	entry := func(format string, attStmt []byte) []byte {
+		return cborMap(cborString("fmt"), cborString(format), cborString("attStmt"), attStmt)
+	}
+
+	testCases := []struct {
+		name    string
+		attStmt []byte
+		opts    *CompoundOptions
+		wantErr string
+	}{
+		{
+			name:    "Valid",
+			attStmt: cborArray(entry(FormatPacked, packedStmt), entry(FormatApple, appleStmt)),
+			opts: &CompoundOptions{
+				Packed: &PackedOptions{AllowSelfAttested: true},
+				Apple:  &AppleOptions{Roots: ca.pool()},
+			},
+		},
+		{
+			name:    "Single statement",
+			attStmt: cborArray(entry(FormatPacked, packedStmt)),
+			opts: &CompoundOptions{
+				Packed: &PackedOptions{AllowSelfAttested: true},
+			},
+			wantErr: "at least two statements",
+		},
+		{
+			name:    "Nested compound",
+			attStmt: cborArray(entry(FormatPacked, packedStmt), entry(FormatCompound, cborArray())),
+			opts: &CompoundOptions{
+				Packed: &PackedOptions{AllowSelfAttested: true},
+			},
+			wantErr: "nested compound",
+		},
+		{
+			name:    "Format not configured",
+			attStmt: cborArray(entry(FormatPacked, packedStmt), entry(FormatApple, appleStmt)),
+			opts: &CompoundOptions{
+				Packed: &PackedOptions{AllowSelfAttested: true},
+			},
+			wantErr: "no options configured",
+		},
+		{
+			name:    "Invalid nested statement",
+			attStmt: cborArray(entry(FormatPacked, packedStmt), entry(FormatApple, appleStmt)),
+			opts: &CompoundOptions{
+				Packed: &PackedOptions{AllowSelfAttested: true},
+				Apple:  &AppleOptions{Roots: x509.NewCertPool()},
+			},
+			wantErr: "statement 1 (apple)",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			attestationObject := testAttestationObject(FormatCompound, tc.attStmt, authData)
+			rp := &RelyingParty{
+				ID:     rpID,
+				Origin: origin,
+				AttestationVerifiers: map[string]AttestationVerifier{
+					FormatCompound: tc.opts,
+				},
+			}
+			got, err := rp.VerifyAttestationCompound(challenge, clientDataJSON, attestationObject, tc.opts)
+			reg, regErr := rp.VerifyRegistration(challenge, clientDataJSON, attestationObject)
+			if tc.wantErr != "" {
+				if err == nil {
+					t.Fatalf("Expected error verifying attestation")
+				}
+				if !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("Verifying attestation returned unexpected error, got=%v, want=%s", err, tc.wantErr)
+				}
+				if regErr == nil || !strings.Contains(regErr.Error(), tc.wantErr) {
+					t.Fatalf("Verifying registration returned unexpected error, got=%v, want=%s", regErr, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Verifying attestation: %v", err)
+			}
+			if regErr != nil {
+				t.Fatalf("Verifying registration: %v", regErr)
+			}
+			if reg.Format != FormatCompound {
+				t.Errorf("Unexpected registration format, got=%s, want=%s", reg.Format, FormatCompound)
+			}
+			if reg.Type != AttestationTypeSelf {
+				t.Errorf("Unexpected registration attestation type, got=%s, want=%s", reg.Type, AttestationTypeSelf)
+			}
+			if c, ok := reg.Details.(*Compound); !ok || len(c.Statements) != 2 {
+				t.Errorf("Registration details don't hold both compound statements: %#v", reg.Details)
+			}
+			if len(got.Statements) != 2 {
+				t.Fatalf("Unexpected number of statements, got=%d, want=2", len(got.Statements))
+			}
+			if got.Statements[0].Packed == nil || !got.Statements[0].Packed.SelfAttested {
+				t.Errorf("Expected first statement to be self attested packed")
+			}
+			if got.Statements[1].Apple == nil {
+				t.Errorf("Expected second statement to be apple")
+			}
+			if !credKey.PublicKey.Equal(got.AttestationData.PublicKey) {
+				t.Errorf("Attestation returned unexpected public key")
+			}
+		})
+	}
+}
@@ -0,0 +1,170 @@
+package webauthn
+
+import (
+	"fmt"
+
+	"github.com/go-passkeys/go-passkeys/webauthn/internal/cbor"
+)
+
+// CredentialProtectionPolicy is the level of protection of a credential, as
+// reported by the "credProtect" authenticator extension.
+//
+// https://fidoalliance.org/specs/fido-v2.1-ps-20210615/fido-client-to-authenticator-protocol-v2.1-ps-errata-20220621.html#sctn-credProtect-extension
+type CredentialProtectionPolicy int
+
+// Credential protection policies defined by CTAP.
+const (
+	// The credential can be used without user verification.
+	CredentialProtectionUserVerificationOptional CredentialProtectionPolicy = 1
+	// The credential can only be discovered without user verification if its
+	// credential ID is provided by the relying party.
+	CredentialProtectionUserVerificationOptionalWithCredentialIDList CredentialProtectionPolicy = 2
+	// The credential can only be used with user verification.
+	CredentialProtectionUserVerificationRequired CredentialProtectionPolicy = 3
+)
+
+var credentialProtectionPolicyStrings = map[CredentialProtectionPolicy]string{
+	CredentialProtectionUserVerificationOptional:                     "userVerificationOptional",
+	CredentialProtectionUserVerificationOptionalWithCredentialIDList: "userVerificationOptionalWithCredentialIDList",
+	CredentialProtectionUserVerificationRequired:                     "userVerificationRequired",
+}
+
+// String returns the WebAuthn name of the policy, such as
+// "userVerificationRequired".
+func (p CredentialProtectionPolicy) String() string {
+	if s, ok := credentialProtectionPolicyStrings[p]; ok {
+		return s
+	}
+	return fmt.Sprintf("CredentialProtectionPolicy(%d)", int(p))
+}
+
+// AuthenticatorExtensions holds the authenticator extension outputs included in
+// authenticator data. Fields are only set if the authenticator returned the
+// corresponding extension.
+//
+// https://www.w3.org/TR/webauthn-3/#authenticator-extension-output
+type AuthenticatorExtensions struct {
+	// CredProtect is the protection policy applied to a new credential.
+	// Only returned during registration.
+	CredProtect CredentialProtectionPolicy
+
+	// HMACSecret reports if a new credential supports the "hmac-secret"
+	// extension. Only returned during registration.
+	//
+	// https://fidoalliance.org/specs/fido-v2.1-ps-20210615/fido-client-to-authenticator-protocol-v2.1-ps-errata-20220621.html#sctn-hmac-secret-extension
+	HMACSecret bool
+	// HMACSecretOutput holds the encrypted "hmac-secret" output of an
+	// assertion. Browsers decrypt this value and return it to the relying
+	// party through the "prf" client extension.
+	HMACSecretOutput []byte
+
+	// CredBlobStored reports if the authenticator stored the "credBlob"
+	// provided during registration.
+	//
+	// https://fidoalliance.org/specs/fido-v2.1-ps-20210615/fido-client-to-authenticator-protocol-v2.1-ps-errata-20220621.html#sctn-credBlob-extension
+	CredBlobStored bool
+	// CredBlob holds the blob stored with the credential, returned during
+	// assertions.
+	CredBlob []byte
+
+	// MinPINLength is the minimum PIN length configured on the authenticator.
+	// Only returned during registration, and only to allow listed relying
+	// parties.
+	//
+	// https://fidoalliance.org/specs/fido-v2.1-ps-20210615/fido-client-to-authenticator-protocol-v2.1-ps-errata-20220621.html#sctn-minpinlength-extension
+	MinPINLength int
+
+	// LargeBlobKey holds the key used to encrypt the credential's large blob.
+	//
+	// https://fidoalliance.org/specs/fido-v2.1-ps-20210615/fido-client-to-authenticator-protocol-v2.1-ps-errata-20220621.html#sctn-largeBlobKey-extension
+	LargeBlobKey []byte
+
+	// Unknown holds the raw CBOR values of extensions not parsed by this
+	// package, keyed by extension identifier.
+	Unknown map[string][]byte
+}
+
+// parseExtensions parses the CBOR map of authenticator extension outputs. The
+// map must be the last value of the authenticator data.
+//
+// https://www.w3.org/TR/webauthn-3/#sctn-extensions-in-authenticator-data
+func parseExtensions(b []byte) (*AuthenticatorExtensions, error) {
+	var ext AuthenticatorExtensions
+	var (
+		key string
+		err error
+	)
+	d := cbor.NewDecoder(b)
+	ok := d.Map(func(kv *cbor.Decoder) bool {
+		if !kv.String(&key) {
+			err = fmt.Errorf("invalid extension identifier")
+			return false
+		}
+		switch key {
+		case "credProtect":
+			var n uint64
+			if !kv.PositiveInteger(&n) {
+				return false
+			}
+			ext.CredProtect = CredentialProtectionPolicy(n)
+		case "hmac-secret":
+			// A boolean during registration, and an encrypted output during
+			// assertions.
+			if kv.Peek() == cbor.TypeByteString {
+				return kv.Bytes(&ext.HMACSecretOutput)
+			}
+			return kv.Bool(&ext.HMACSecret)
+		case "credBlob":
+			if kv.Peek() == cbor.TypeByteString {
+				return kv.Bytes(&ext.CredBlob)
+			}
+			return kv.Bool(&ext.CredBlobStored)
+		case "minPinLength":
+			var n uint64
+			if !kv.PositiveInteger(&n) {
+				return false
+			}
+			ext.MinPINLength = int(n)
+		case "largeBlobKey":
+			return kv.Bytes(&ext.LargeBlobKey)
+		default:
+			var raw []byte
+			if !kv.Raw(&raw) {
+				return false
+			}
+			if ext.Unknown == nil {
+				ext.Unknown = map[string][]byte{}
+			}
+			ext.Unknown[key] = raw
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		if key == "" {
+			return nil, fmt.Errorf("extensions were not valid cbor")
+		}
+		return nil, fmt.Errorf("invalid value for extension %q", key)
+	}
+	if !d.Done() {
+		return nil, fmt.Errorf("unexpected data after extensions")
+	}
+	return &ext, nil
+}
+
+// verifyExtensions checks that the extension data flag is consistent with the
+// trailing data of authenticator data, and parses any extensions present.
+func verifyExtensions(flags Flags, b []byte) (*AuthenticatorExtensions, error) {
+	if !flags.Extensions() {
+		if len(b) > 0 {
+			return nil, fmt.Errorf("unexpected data after authenticator data without extension data flag")
+		}
+		return nil, nil
+	}
+	if len(b) == 0 {
+		return nil, fmt.Errorf("extension data flag set but no extensions present")
+	}
+	return parseExtensions(b)
+}
@@ -0,0 +1,190 @@
+package webauthn
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"strings"
+	"testing"
+)
+
+func TestParseExtensions(t *testing.T) {
+	testCases := []struct {
+		name    string
+		data    []byte
+		check   func(t *testing.T, ext *AuthenticatorExtensions)
+		wantErr string
+	}{
+		{
+			name: "Registration",
+			data: cborMap(
+				cborString("credProtect"), cborInt(3),
+				cborString("hmac-secret"), cborBool(true),
+				cborString("credBlob"), cborBool(true),
+				cborString("minPinLength"), cborInt(6),
+			),
+			check: func(t *testing.T, ext *AuthenticatorExtensions) {
+				if ext.CredProtect != CredentialProtectionUserVerificationRequired {
+					t.Errorf("Unexpected credProtect, got=%s, want=%s", ext.CredProtect, CredentialProtectionUserVerificationRequired)
+				}
+				if !ext.HMACSecret {
+					t.Errorf("Expected hmac-secret to be set")
+				}
+				if !ext.CredBlobStored {
+					t.Errorf("Expected credBlob to be stored")
+				}
+				if ext.MinPINLength != 6 {
+					t.Errorf("Unexpected minPinLength, got=%d, want=6", ext.MinPINLength)
+				}
+			},
+		},
+		{
+			name: "Assertion",
+			data: cborMap(
+				cborString("hmac-secret"), cborBytes([]byte("encrypted")),
+				cborString("credBlob"), cborBytes([]byte("blob")),
+				cborString("largeBlobKey"), cborBytes([]byte("key")),
+			),
+			check: func(t *testing.T, ext *AuthenticatorExtensions) {
+				if !bytes.Equal(ext.HMACSecretOutput, []byte("encrypted")) {
+					t.Errorf("Unexpected hmac-secret output, got=%x", ext.HMACSecretOutput)
+				}
+				if !bytes.Equal(ext.CredBlob, []byte("blob")) {
+					t.Errorf("Unexpected credBlob, got=%x", ext.CredBlob)
+				}
+				if !bytes.Equal(ext.LargeBlobKey, []byte("key")) {
+					t.Errorf("Unexpected largeBlobKey, got=%x", ext.LargeBlobKey)
+				}
+			},
+		},
+		{
+			name: "Unknown extension",
+			data: cborMap(
+				cborString("example.ext"), cborArray(cborInt(1), cborInt(2)),
+			),
+			check: func(t *testing.T, ext *AuthenticatorExtensions) {
+				want := cborArray(cborInt(1), cborInt(2))
+				if got := ext.Unknown["example.ext"]; !bytes.Equal(got, want) {
+					t.Errorf("Unexpected raw extension, got=%x, want=%x", got, want)
+				}
+			},
+		},
+		{
+			name:    "Invalid credProtect",
+			data:    cborMap(cborString("credProtect"), cborString("required")),
+			wantErr: "credProtect",
+		},
+		{
+			name:    "Not a map",
+			data:    cborArray(),
+			wantErr: "not valid cbor",
+		},
+		{
+			name:    "Trailing data",
+			data:    append(cborMap(), 0x00),
+			wantErr: "unexpected data",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ext, err := parseExtensions(tc.data)
+			if tc.wantErr != "" {
+				if err == nil {
+					t.Fatalf("Expected error parsing extensions")
+				}
+				if !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("Parsing extensions returned unexpected error, got=%v, want=%s", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parsing extensions: %v", err)
+			}
+			tc.check(t, ext)
+		})
+	}
+}
+
+func TestExtensionDataFlag(t *testing.T) {
+	const (
+		rpID   = "localhost"
+		origin = "http://localhost:8080"
+	)
+	credKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Generating key: %v", err)
+	}
+	rp := &RelyingParty{ID: rpID, Origin: origin}
+	ext := cborMap(cborString("credProtect"), cborInt(2))
+	const ed = Flags(1 << 7)
+
+	testCases := []struct {
+		name    string
+		flags   Flags
+		ext     []byte
+		wantErr string
+	}{
+		{
+			name:  "No extensions",
+			flags: 0x05,
+		},
+		{
+			name:  "Extensions",
+			flags: 0x05 | ed,
+			ext:   ext,
+		},
+		{
+			name:    "Extensions without flag",
+			flags:   0x05,
+			ext:     ext,
+			wantErr: "without extension data flag",
+		},
+		{
+			name:    "Flag without extensions",
+			flags:   0x05 | ed,
+			wantErr: "no extensions present",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			checkErr := func(t *testing.T, err error) bool {
+				t.Helper()
+				if tc.wantErr != "" {
+					if err == nil {
+						t.Fatalf("Expected error")
+					}
+					if !strings.Contains(err.Error(), tc.wantErr) {
+						t.Fatalf("Unexpected error, got=%v, want=%s", err, tc.wantErr)
+					}
+					return false
+				}
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				return true
+			}
+
+			challenge := []byte("registration")
+			clientDataJSON := testClientDataJSON("webauthn.create", origin, challenge)
+			authData := testAuthData(t, rpID, tc.flags, AAGUID{}, []byte("credential"), &credKey.PublicKey, ES256)
+			authData = append(authData, tc.ext...)
+			att, err := rp.VerifyAttestation(challenge, clientDataJSON, testAttestationObject(FormatNone, cborMap(), authData))
+			if checkErr(t, err) && tc.ext != nil {
+				if att.AuthenticatorExtensions == nil || att.AuthenticatorExtensions.CredProtect != CredentialProtectionUserVerificationOptionalWithCredentialIDList {
+					t.Errorf("Registration returned unexpected extensions: %+v", att.AuthenticatorExtensions)
+				}
+			}
+
+			challenge = []byte("assertion")
+			clientDataJSON = testClientDataJSON("webauthn.get", origin, challenge)
+			authData, sig := testAssertion(t, credKey, rpID, tc.flags, tc.ext, clientDataJSON)
+			a, err := rp.VerifyAssertion(&credKey.PublicKey, ES256, challenge, clientDataJSON, authData, sig)
+			if checkErr(t, err) && tc.ext != nil {
+				if a.AuthenticatorExtensions == nil || a.AuthenticatorExtensions.CredProtect != CredentialProtectionUserVerificationOptionalWithCredentialIDList {
+					t.Errorf("Assertion returned unexpected extensions: %+v", a.AuthenticatorExtensions)
+				}
+			}
+		})
+	}
+}
@@ -226,11 +226,12 @@ func (d *Decoder) Map(fn func(keyval *Decoder) bool) bool {
 	return true
 }
 
+// Peek returns the major type of the next value without consuming it.
 func (d *Decoder) Peek() byte {
 	if d.len() == 0 {
 		return 0xff
 	}
-	return d.buff[0] << 5
+	return d.buff[d.pos] >> 5
 }
 
 func (d *Decoder) Array(fn func(val *Decoder) bool) bool {
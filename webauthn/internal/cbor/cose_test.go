@@ -3,6 +3,7 @@ package cbor
 import (
 	"crypto/ecdsa"
 	"crypto/elliptic"
+	"crypto/rsa"
 	"encoding/hex"
 	"math/big"
 	"strings"
@@ -44,3 +45,32 @@ func TestParseECKey(t *testing.T) {
 		t.Errorf("Unexpected algorithm, got=%v, want=%v", got.Algorithm, ES256)
 	}
 }
+
+var rsaTestKeyHex, _ = hex.DecodeString(strings.Join(strings.Fields(`A4
+   01  03
+
+   03  39 0100
+
+   20  50  c5f3a1b2d4e6f80911223344556677ab
+
+   21  43  010001`), ""))
+
+func TestParseRSAKey(t *testing.T) {
+	d := NewDecoder(rsaTestKeyHex)
+	got, err := d.PublicKey()
+	if err != nil {
+		t.Fatalf("Parsing public key: %v", err)
+	}
+
+	wantN, _ := hex.DecodeString("c5f3a1b2d4e6f80911223344556677ab")
+	want := &rsa.PublicKey{
+		N: big.NewInt(0).SetBytes(wantN),
+		E: 65537,
+	}
+	if !want.Equal(got.Public) {
+		t.Errorf("Public keys didn't match, got=%#v, want=%#v", got.Public, want)
+	}
+	if got.Algorithm != RS256 {
+		t.Errorf("Unexpected algorithm, got=%v, want=%v", got.Algorithm, RS256)
+	}
+}
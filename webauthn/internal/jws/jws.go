@@ -0,0 +1,147 @@
+// The jws package implements verification of JSON Web Signatures using the
+// compact serialization, as used by Android SafetyNet and the FIDO Alliance
+// Metadata Service.
+//
+// https://datatracker.ietf.org/doc/html/rfc7515
+package jws
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+)
+
+// Header holds the JOSE header fields used by this package.
+//
+// https://datatracker.ietf.org/doc/html/rfc7515#section-4.1
+type Header struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ"`
+	// X5C is the DER encoded certificate chain, leaf first, used to sign the
+	// value.
+	X5C [][]byte `json:"x5c"`
+}
+
+// JWS is a parsed, unverified, JSON Web Signature.
+type JWS struct {
+	Header  Header
+	Payload []byte
+
+	signingInput []byte
+	signature    []byte
+}
+
+// Parse decodes a compact serialized JWS. The signature is not verified.
+func Parse(b []byte) (*JWS, error) {
+	b = bytes.TrimSpace(b)
+	parts := bytes.Split(b, []byte{'.'})
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("expected jws with three parts, got %d", len(parts))
+	}
+	header, err := base64.RawURLEncoding.AppendDecode(nil, parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("decoding jws header: %v", err)
+	}
+	payload, err := base64.RawURLEncoding.AppendDecode(nil, parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("decoding jws payload: %v", err)
+	}
+	sig, err := base64.RawURLEncoding.AppendDecode(nil, parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("decoding jws signature: %v", err)
+	}
+
+	j := &JWS{
+		Payload:      payload,
+		signingInput: b[:len(parts[0])+1+len(parts[1])],
+		signature:    sig,
+	}
+	if err := json.Unmarshal(header, &j.Header); err != nil {
+		return nil, fmt.Errorf("parsing jws header: %v", err)
+	}
+	return j, nil
+}
+
+// Verify validates the signature of the JWS using the provided public key and
+// the algorithm specified by the header.
+//
+// https://datatracker.ietf.org/doc/html/rfc7518#section-3.1
+func (j *JWS) Verify(pub crypto.PublicKey) error {
+	switch j.Header.Alg {
+	case "RS256":
+		return verifyRSA(pub, crypto.SHA256, j.signingInput, j.signature)
+	case "RS384":
+		return verifyRSA(pub, crypto.SHA384, j.signingInput, j.signature)
+	case "RS512":
+		return verifyRSA(pub, crypto.SHA512, j.signingInput, j.signature)
+	case "ES256":
+		return verifyECDSA(pub, crypto.SHA256, j.signingInput, j.signature)
+	case "ES384":
+		return verifyECDSA(pub, crypto.SHA384, j.signingInput, j.signature)
+	case "ES512":
+		return verifyECDSA(pub, crypto.SHA512, j.signingInput, j.signature)
+	default:
+		return fmt.Errorf("unsupported jws algorithm: '%s'", j.Header.Alg)
+	}
+}
+
+func digest(h crypto.Hash, data []byte) []byte {
+	switch h {
+	case crypto.SHA256:
+		sum := sha256.Sum256(data)
+		return sum[:]
+	case crypto.SHA384:
+		sum := sha512.Sum384(data)
+		return sum[:]
+	default:
+		sum := sha512.Sum512(data)
+		return sum[:]
+	}
+}
+
+func verifyRSA(pub crypto.PublicKey, h crypto.Hash, data, sig []byte) error {
+	rsaPub, ok := pub.(*rsa.PublicKey)
+	if !ok {
+		return fmt.Errorf("invalid public key type for rsa algorithm: %T", pub)
+	}
+	if err := rsa.VerifyPKCS1v15(rsaPub, h, digest(h, data), sig); err != nil {
+		return fmt.Errorf("invalid signature: %v", err)
+	}
+	return nil
+}
+
+// verifyECDSA validates an ECDSA signature, which JWS encodes as the
+// concatenation of the R and S values rather than ASN.1.
+//
+// https://datatracker.ietf.org/doc/html/rfc7518#section-3.4
+func verifyECDSA(pub crypto.PublicKey, h crypto.Hash, data, sig []byte) error {
+	ecdsaPub, ok := pub.(*ecdsa.PublicKey)
+	if !ok {
+		return fmt.Errorf("invalid public key type for ecdsa algorithm: %T", pub)
+	}
+	size := (ecdsaPub.Curve.Params().BitSize + 7) / 8
+	if len(sig) != 2*size {
+		return fmt.Errorf("invalid signature length %d", len(sig))
+	}
+	asn1Sig, err := asn1.Marshal(struct {
+		R, S *big.Int
+	}{
+		new(big.Int).SetBytes(sig[:size]),
+		new(big.Int).SetBytes(sig[size:]),
+	})
+	if err != nil {
+		return fmt.Errorf("encoding signature: %v", err)
+	}
+	if !ecdsa.VerifyASN1(ecdsaPub, digest(h, data), asn1Sig) {
+		return fmt.Errorf("invalid signature")
+	}
+	return nil
+}
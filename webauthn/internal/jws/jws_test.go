@@ -0,0 +1,77 @@
+package jws
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"testing"
+)
+
+func sign(t *testing.T, alg string, key crypto.Signer, payload string) []byte {
+	t.Helper()
+	input := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"`+alg+`"}`)) + "." +
+		base64.RawURLEncoding.EncodeToString([]byte(payload))
+	h := sha256.Sum256([]byte(input))
+
+	var sig []byte
+	switch key := key.(type) {
+	case *rsa.PrivateKey:
+		s, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, h[:])
+		if err != nil {
+			t.Fatalf("Signing: %v", err)
+		}
+		sig = s
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, key, h[:])
+		if err != nil {
+			t.Fatalf("Signing: %v", err)
+		}
+		sig = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	}
+	return []byte(input + "." + base64.RawURLEncoding.EncodeToString(sig))
+}
+
+func TestVerify(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Generating key: %v", err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Generating key: %v", err)
+	}
+
+	testCases := []struct {
+		name string
+		alg  string
+		key  crypto.Signer
+	}{
+		{"RS256", "RS256", rsaKey},
+		{"ES256", "ES256", ecKey},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			token := sign(t, tc.alg, tc.key, `{"hello":"world"}`)
+			j, err := Parse(token)
+			if err != nil {
+				t.Fatalf("Parsing jws: %v", err)
+			}
+			if string(j.Payload) != `{"hello":"world"}` {
+				t.Errorf("Unexpected payload: %s", j.Payload)
+			}
+			if err := j.Verify(tc.key.Public()); err != nil {
+				t.Errorf("Verifying jws: %v", err)
+			}
+
+			// Modify the payload and ensure verification fails.
+			j.signingInput = append(j.signingInput, 'x')
+			if err := j.Verify(tc.key.Public()); err == nil {
+				t.Errorf("Expected verification to fail for modified payload")
+			}
+		})
+	}
+}
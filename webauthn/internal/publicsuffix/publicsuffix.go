@@ -0,0 +1,213 @@
+// The publicsuffix package determines registrable domains using an embedded
+// copy of the Public Suffix List, as used by browsers when processing Related
+// Origin Requests.
+//
+// https://publicsuffix.org/
+package publicsuffix
+
+import (
+	_ "embed"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+//go:generate curl -sSfo public_suffix_list.dat https://publicsuffix.org/list/public_suffix_list.dat
+
+//go:embed public_suffix_list.dat
+var list string
+
+// Kinds of rules in the list. A suffix can have several kinds, such as both
+// "kawasaki.jp" and "*.kawasaki.jp".
+const (
+	ruleNormal = 1 << iota
+	ruleWildcard
+	ruleException
+)
+
+var (
+	rulesOnce sync.Once
+	rules     map[string]int
+)
+
+// loadRules parses the embedded list. Internationalized rules are converted
+// to their ASCII form so they match hostnames as they appear in URLs.
+//
+// https://github.com/publicsuffix/list/wiki/Format
+func loadRules() {
+	rules = make(map[string]int)
+	for _, line := range strings.Split(list, "\n") {
+		rule, _, _ := strings.Cut(strings.TrimSpace(line), " ")
+		if rule == "" || strings.HasPrefix(rule, "//") {
+			continue
+		}
+		kind := ruleNormal
+		if s, ok := strings.CutPrefix(rule, "!"); ok {
+			kind, rule = ruleException, s
+		} else if s, ok := strings.CutPrefix(rule, "*."); ok {
+			kind, rule = ruleWildcard, s
+		}
+		rule, err := toASCII(rule)
+		if err != nil {
+			continue
+		}
+		rules[rule] |= kind
+	}
+}
+
+// PublicSuffix returns the public suffix of a domain, such as "co.uk" for
+// "www.example.co.uk". Domains not covered by the list use their top-level
+// label as the suffix. The domain must be lowercase and use the ASCII form of
+// internationalized labels.
+func PublicSuffix(domain string) string {
+	rulesOnce.Do(loadRules)
+
+	labels := strings.Split(domain, ".")
+	for i := range labels {
+		suffix := strings.Join(labels[i:], ".")
+		if rules[suffix]&ruleException != 0 {
+			return strings.Join(labels[i+1:], ".")
+		}
+		if rules[suffix]&ruleNormal != 0 {
+			return suffix
+		}
+		if i+1 < len(labels) && rules[strings.Join(labels[i+1:], ".")]&ruleWildcard != 0 {
+			return suffix
+		}
+	}
+	return labels[len(labels)-1]
+}
+
+// EffectiveTLDPlusOne returns the registrable domain of a domain, the public
+// suffix plus one additional label, such as "example.co.uk" for
+// "www.example.co.uk". An error is returned if the domain is itself a public
+// suffix.
+func EffectiveTLDPlusOne(domain string) (string, error) {
+	if domain == "" || strings.HasPrefix(domain, ".") || strings.HasSuffix(domain, ".") || strings.Contains(domain, "..") {
+		return "", fmt.Errorf("invalid domain: %q", domain)
+	}
+	suffix := PublicSuffix(domain)
+	if len(domain) <= len(suffix) {
+		return "", fmt.Errorf("domain %q is a public suffix", domain)
+	}
+	i := strings.LastIndexByte(domain[:len(domain)-len(suffix)-1], '.')
+	return domain[i+1:], nil
+}
+
+// toASCII converts the internationalized labels of a domain to their
+// punycode form, such as "xn--55qx5d.cn" for "公司.cn".
+//
+// https://datatracker.ietf.org/doc/html/rfc5891#section-4.4
+func toASCII(domain string) (string, error) {
+	labels := strings.Split(domain, ".")
+	for i, label := range labels {
+		ascii := true
+		for _, r := range label {
+			if r >= 0x80 {
+				ascii = false
+				break
+			}
+		}
+		if ascii {
+			continue
+		}
+		encoded, err := punycode(label)
+		if err != nil {
+			return "", err
+		}
+		labels[i] = "xn--" + encoded
+	}
+	return strings.Join(labels, "."), nil
+}
+
+// punycode encodes a Unicode label.
+//
+// https://datatracker.ietf.org/doc/html/rfc3492#section-6.3
+func punycode(label string) (string, error) {
+	const (
+		base        = 36
+		tMin        = 1
+		tMax        = 26
+		skew        = 38
+		damp        = 700
+		initialBias = 72
+		initialN    = 0x80
+	)
+	adapt := func(delta, numPoints int, first bool) int {
+		if first {
+			delta /= damp
+		} else {
+			delta /= 2
+		}
+		delta += delta / numPoints
+		k := 0
+		for delta > ((base-tMin)*tMax)/2 {
+			delta /= base - tMin
+			k += base
+		}
+		return k + (base-tMin+1)*delta/(delta+skew)
+	}
+	digit := func(d int) byte {
+		if d < 26 {
+			return byte('a' + d)
+		}
+		return byte('0' + d - 26)
+	}
+
+	runes := []rune(label)
+	var out []byte
+	for _, r := range runes {
+		if r < 0x80 {
+			out = append(out, byte(r))
+		}
+	}
+	b := len(out)
+	h := b
+	if b > 0 {
+		out = append(out, '-')
+	}
+
+	n, delta, bias := initialN, 0, initialBias
+	for h < len(runes) {
+		m := -1
+		for _, r := range runes {
+			if int(r) >= n && (m < 0 || int(r) < m) {
+				m = int(r)
+			}
+		}
+		if (m-n)*(h+1) < 0 || delta+(m-n)*(h+1) < delta {
+			return "", fmt.Errorf("punycode overflow encoding %q", label)
+		}
+		delta += (m - n) * (h + 1)
+		n = m
+		for _, r := range runes {
+			if int(r) < n {
+				delta++
+			}
+			if int(r) != n {
+				continue
+			}
+			q := delta
+			for k := base; ; k += base {
+				t := k - bias
+				if t < tMin {
+					t = tMin
+				} else if t > tMax {
+					t = tMax
+				}
+				if q < t {
+					break
+				}
+				out = append(out, digit(t+(q-t)%(base-t)))
+				q = (q - t) / (base - t)
+			}
+			out = append(out, digit(q))
+			bias = adapt(delta, h+1, h == b)
+			delta = 0
+			h++
+		}
+		delta++
+		n++
+	}
+	return string(out), nil
+}
@@ -0,0 +1,82 @@
+package publicsuffix
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEffectiveTLDPlusOne(t *testing.T) {
+	testCases := []struct {
+		domain     string
+		wantSuffix string
+		want       string
+		wantErr    string
+	}{
+		{domain: "example.com", wantSuffix: "com", want: "example.com"},
+		{domain: "www.example.com", wantSuffix: "com", want: "example.com"},
+		{domain: "a.b.example.co.uk", wantSuffix: "co.uk", want: "example.co.uk"},
+		{domain: "example.de", wantSuffix: "de", want: "example.de"},
+		// Private domains.
+		{domain: "foo.github.io", wantSuffix: "github.io", want: "foo.github.io"},
+		// Wildcard and exception rules.
+		{domain: "a.b.ck", wantSuffix: "b.ck", want: "a.b.ck"},
+		{domain: "a.www.ck", wantSuffix: "ck", want: "www.ck"},
+		{domain: "www.city.kawasaki.jp", wantSuffix: "kawasaki.jp", want: "city.kawasaki.jp"},
+		// Internationalized rules.
+		{domain: "example.xn--55qx5d.cn", wantSuffix: "xn--55qx5d.cn", want: "example.xn--55qx5d.cn"},
+		// Default rule.
+		{domain: "foo.localhost", wantSuffix: "localhost", want: "foo.localhost"},
+		{domain: "com", wantSuffix: "com", wantErr: "public suffix"},
+		{domain: "co.uk", wantSuffix: "co.uk", wantErr: "public suffix"},
+		{domain: "github.io", wantSuffix: "github.io", wantErr: "public suffix"},
+		{domain: "localhost", wantSuffix: "localhost", wantErr: "public suffix"},
+		{domain: ".example.com", wantSuffix: "com", wantErr: "invalid domain"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.domain, func(t *testing.T) {
+			if got := PublicSuffix(tc.domain); got != tc.wantSuffix {
+				t.Errorf("PublicSuffix(%q) returned unexpected value, got=%s, want=%s", tc.domain, got, tc.wantSuffix)
+			}
+			got, err := EffectiveTLDPlusOne(tc.domain)
+			if tc.wantErr != "" {
+				if err == nil {
+					t.Fatalf("EffectiveTLDPlusOne(%q) expected error, got=%s", tc.domain, got)
+				}
+				if !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("EffectiveTLDPlusOne(%q) returned unexpected error, got=%v, want=%s", tc.domain, err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("EffectiveTLDPlusOne(%q): %v", tc.domain, err)
+			}
+			if got != tc.want {
+				t.Errorf("EffectiveTLDPlusOne(%q) returned unexpected value, got=%s, want=%s", tc.domain, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestToASCII(t *testing.T) {
+	testCases := []struct {
+		domain string
+		want   string
+	}{
+		{"example.com", "example.com"},
+		{"公司.cn", "xn--55qx5d.cn"},
+		{"網絡.hk", "xn--od0alg.hk"},
+		{"bücher.de", "xn--bcher-kva.de"},
+		{"aéroport.ci", "xn--aroport-bya.ci"},
+		{"ελ", "xn--qxam"},
+	}
+	for _, tc := range testCases {
+		got, err := toASCII(tc.domain)
+		if err != nil {
+			t.Errorf("toASCII(%q): %v", tc.domain, err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("toASCII(%q) returned unexpected value, got=%s, want=%s", tc.domain, got, tc.want)
+		}
+	}
+}
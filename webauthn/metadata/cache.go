@@ -0,0 +1,281 @@
+package metadata
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-passkeys/go-passkeys/webauthn"
+)
+
+// Fetcher retrieves a raw, unverified, metadata BLOB.
+type Fetcher interface {
+	Fetch(ctx context.Context) ([]byte, error)
+}
+
+// FetcherFunc adapts a function to the [Fetcher] interface. For example, to
+// provide fixed BLOBs in tests.
+type FetcherFunc func(ctx context.Context) ([]byte, error)
+
+// Fetch calls f(ctx).
+func (f FetcherFunc) Fetch(ctx context.Context) ([]byte, error) {
+	return f(ctx)
+}
+
+// HTTPFetcher retrieves a metadata BLOB over HTTP.
+type HTTPFetcher struct {
+	// URL of the BLOB. If empty, the FIDO Alliance Metadata Service is used.
+	URL string
+	// Client used to make requests. If nil, http.DefaultClient is used.
+	Client *http.Client
+}
+
+// Fetch implements the [Fetcher] interface.
+func (f *HTTPFetcher) Fetch(ctx context.Context) ([]byte, error) {
+	url := f.URL
+	if url == "" {
+		url = URL
+	}
+	client := f.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %v", err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetching blob: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading response body: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %s: %s", resp.Status, body)
+	}
+	return body, nil
+}
+
+// FileFetcher reads a metadata BLOB from the local filesystem.
+type FileFetcher struct {
+	Path string
+}
+
+// Fetch implements the [Fetcher] interface.
+func (f *FileFetcher) Fetch(ctx context.Context) ([]byte, error) {
+	return os.ReadFile(f.Path)
+}
+
+// CacheOptions configures a [Cache].
+type CacheOptions struct {
+	// Fetcher retrieves new BLOBs. Required.
+	Fetcher Fetcher
+
+	// Roots holds the root certificates BLOBs must chain to. Required.
+	Roots *x509.CertPool
+
+	// SnapshotPath, if set, is a file the last verified BLOB is written to.
+	// When the cache is created and the fetcher fails, the snapshot is used
+	// instead, allowing replicas to start while the fetcher is unavailable.
+	// The snapshot's signature is verified, but it's accepted past its
+	// nextUpdate date.
+	SnapshotPath string
+
+	// RetryInterval is the time to wait after a failed refresh, or a refresh
+	// that didn't return a newer BLOB. If zero, one hour is used.
+	RetryInterval time.Duration
+
+	// Now returns the current time. If unset, time.Now is used.
+	Now func() time.Time
+}
+
+// Cache holds a verified metadata BLOB, refreshing it as new BLOBs are
+// published. Lookups are safe for concurrent use and don't block on refreshes.
+//
+// A Cache continues to serve its current BLOB past nextUpdate until a refresh
+// succeeds.
+type Cache struct {
+	opts    CacheOptions
+	payload atomic.Pointer[MetadataBLOBPayload]
+
+	// mu serializes refreshes.
+	mu sync.Mutex
+}
+
+// NewCache creates a cache and loads an initial BLOB from the fetcher, falling
+// back to the snapshot file if the fetcher fails.
+func NewCache(ctx context.Context, opts *CacheOptions) (*Cache, error) {
+	if opts == nil || opts.Fetcher == nil {
+		return nil, fmt.Errorf("fetcher must be provided")
+	}
+	if opts.Roots == nil {
+		return nil, fmt.Errorf("root certificates must be provided")
+	}
+	c := &Cache{opts: *opts}
+	if c.opts.RetryInterval == 0 {
+		c.opts.RetryInterval = time.Hour
+	}
+
+	fetchErr := c.Refresh(ctx)
+	if fetchErr == nil {
+		return c, nil
+	}
+	if c.opts.SnapshotPath == "" {
+		return nil, fetchErr
+	}
+	b, err := os.ReadFile(c.opts.SnapshotPath)
+	if err != nil {
+		return nil, fmt.Errorf("%v, reading snapshot: %v", fetchErr, err)
+	}
+	// The snapshot is only used when a newer BLOB can't be fetched, so it's
+	// accepted past its nextUpdate date, the same as a BLOB already held by
+	// the cache.
+	p, err := c.parse(b, true)
+	if err != nil {
+		return nil, fmt.Errorf("%v, parsing snapshot: %v", fetchErr, err)
+	}
+	c.payload.Store(p)
+	return c, nil
+}
+
+func (c *Cache) now() time.Time {
+	if c.opts.Now != nil {
+		return c.opts.Now()
+	}
+	return time.Now()
+}
+
+func (c *Cache) parse(b []byte, allowExpired bool) (*MetadataBLOBPayload, error) {
+	opts := &VerifyOptions{
+		Roots:       c.opts.Roots,
+		CurrentTime: c.now(),
+	}
+	return parse(b, opts, allowExpired)
+}
+
+// Payload returns the current BLOB, or nil if the cache is nil or no BLOB has
+// been loaded yet. Lookups through the cache behave as for a nil
+// [MetadataBLOBPayload] in that case.
+func (c *Cache) Payload() *MetadataBLOBPayload {
+	if c == nil {
+		return nil
+	}
+	return c.payload.Load()
+}
+
+// EntryByAAGUID returns the entry for a FIDO2 authenticator model from the
+// current BLOB.
+func (c *Cache) EntryByAAGUID(aaguid webauthn.AAGUID) (*MetadataBLOBPayloadEntry, bool) {
+	return c.Payload().EntryByAAGUID(aaguid)
+}
+
+// EntryByKeyIdentifier returns the entry for a FIDO U2F authenticator model
+// from the current BLOB.
+func (c *Cache) EntryByKeyIdentifier(keyID string) (*MetadataBLOBPayloadEntry, bool) {
+	return c.Payload().EntryByKeyIdentifier(keyID)
+}
+
+// ResolveAAGUID implements the webauthn.AAGUIDResolver interface using the
+// current BLOB. A nil Cache resolves no AAGUIDs, so it can be passed to
+// webauthn.DefaultAAGUIDResolver when metadata isn't configured.
+func (c *Cache) ResolveAAGUID(aaguid webauthn.AAGUID) (*webauthn.AuthenticatorInfo, bool) {
+	return c.Payload().ResolveAAGUID(aaguid)
+}
+
+// GetStatusReports returns the status reports of a FIDO2 authenticator model
+// from the current BLOB. It returns an error if no BLOB has been loaded. See
+// [MetadataBLOBPayload.GetStatusReports].
+func (c *Cache) GetStatusReports(aaguid webauthn.AAGUID) ([]webauthn.StatusReport, error) {
+	return c.Payload().GetStatusReports(aaguid)
+}
+
+// Refresh fetches and verifies a BLOB, replacing the current BLOB if the new
+// one has a higher serial number. BLOBs with a lower serial number than the
+// current BLOB are rejected.
+func (c *Cache) Refresh(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	b, err := c.opts.Fetcher.Fetch(ctx)
+	if err != nil {
+		return fmt.Errorf("fetching blob: %v", err)
+	}
+	p, err := c.parse(b, false)
+	if err != nil {
+		return err
+	}
+	if cur := c.payload.Load(); cur != nil {
+		if p.Number < cur.Number {
+			return fmt.Errorf("refusing to downgrade blob from %d to %d", cur.Number, p.Number)
+		}
+		if p.Number == cur.Number {
+			return nil
+		}
+	}
+	c.payload.Store(p)
+
+	if c.opts.SnapshotPath != "" {
+		if err := writeFile(c.opts.SnapshotPath, b); err != nil {
+			return fmt.Errorf("writing snapshot: %v", err)
+		}
+	}
+	return nil
+}
+
+// writeFile atomically replaces the contents of a file.
+func writeFile(path string, b []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// Run refreshes the cache once the current BLOB's nextUpdate date passes,
+// retrying until a newer BLOB is published. Run blocks until the context is
+// canceled. Refresh errors are passed to onError, which may be nil.
+func (c *Cache) Run(ctx context.Context, onError func(err error)) {
+	for {
+		wait := c.Payload().NextUpdate.Sub(c.now())
+		if wait <= 0 {
+			number := c.Payload().Number
+			if err := c.Refresh(ctx); err != nil {
+				if onError != nil {
+					onError(err)
+				}
+			}
+			if c.Payload().Number == number {
+				wait = c.opts.RetryInterval
+			}
+		}
+		if wait <= 0 {
+			continue
+		}
+
+		t := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return
+		case <-t.C:
+		}
+	}
+}
@@ -0,0 +1,227 @@
+package metadata
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-passkeys/go-passkeys/webauthn"
+)
+
+// stubFetcher returns a fixed BLOB, or an error if none is set.
+type stubFetcher struct {
+	mu   sync.Mutex
+	blob []byte
+}
+
+func (f *stubFetcher) set(blob []byte) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.blob = blob
+}
+
+func (f *stubFetcher) Fetch(ctx context.Context) ([]byte, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.blob == nil {
+		return nil, fmt.Errorf("fetcher unavailable")
+	}
+	return f.blob, nil
+}
+
+func TestCache(t *testing.T) {
+	ctx := context.Background()
+	signer := newTestSigner(t)
+	now := time.Date(2024, time.June, 1, 0, 0, 0, 0, time.UTC)
+	aaguid := "ee882879-721c-4913-9775-3dfcce97072a"
+
+	blob1 := signer.sign(t, testPayload(1, "2024-06-01", testEntry(aaguid, nil, "FIDO_CERTIFIED_L1")))
+	blob2 := signer.sign(t, testPayload(2, "2024-07-01", testEntry(aaguid, nil, "FIDO_CERTIFIED_L1", "REVOKED")))
+
+	snapshot := filepath.Join(t.TempDir(), "blob.jwt")
+	f := &stubFetcher{blob: blob1}
+	opts := &CacheOptions{
+		Fetcher:      f,
+		Roots:        signer.pool(),
+		SnapshotPath: snapshot,
+		Now:          func() time.Time { return now },
+	}
+	c, err := NewCache(ctx, opts)
+	if err != nil {
+		t.Fatalf("Creating cache: %v", err)
+	}
+	if got := c.Payload().Number; got != 1 {
+		t.Errorf("Unexpected blob number, got=%d, want=1", got)
+	}
+	if _, ok := c.EntryByAAGUID(mustParseAAGUID(t, aaguid)); !ok {
+		t.Errorf("Entry not found by aaguid")
+	}
+
+	f.set(blob2)
+	if err := c.Refresh(ctx); err != nil {
+		t.Fatalf("Refreshing cache: %v", err)
+	}
+	if got := c.Payload().Number; got != 2 {
+		t.Errorf("Unexpected blob number after refresh, got=%d, want=2", got)
+	}
+	got, err := os.ReadFile(snapshot)
+	if err != nil {
+		t.Fatalf("Reading snapshot: %v", err)
+	}
+	if string(got) != string(blob2) {
+		t.Errorf("Snapshot doesn't contain latest blob")
+	}
+
+	f.set(blob1)
+	if err := c.Refresh(ctx); err == nil || !strings.Contains(err.Error(), "downgrade") {
+		t.Errorf("Expected downgrade to be refused, got=%v", err)
+	}
+	if got := c.Payload().Number; got != 2 {
+		t.Errorf("Unexpected blob number after downgrade, got=%d, want=2", got)
+	}
+
+	// New replicas fall back to the snapshot when the fetcher is unavailable.
+	f.set(nil)
+	c, err = NewCache(ctx, opts)
+	if err != nil {
+		t.Fatalf("Creating cache from snapshot: %v", err)
+	}
+	if got := c.Payload().Number; got != 2 {
+		t.Errorf("Unexpected blob number from snapshot, got=%d, want=2", got)
+	}
+
+	opts.SnapshotPath = ""
+	if _, err := NewCache(ctx, opts); err == nil {
+		t.Errorf("Expected error creating cache without fetcher or snapshot")
+	}
+}
+
+func TestCacheExpiredSnapshot(t *testing.T) {
+	ctx := context.Background()
+	signer := newTestSigner(t)
+	aaguid := "ee882879-721c-4913-9775-3dfcce97072a"
+	blob := signer.sign(t, testPayload(1, "2024-06-01", testEntry(aaguid, nil, "FIDO_CERTIFIED_L1")))
+
+	snapshot := filepath.Join(t.TempDir(), "blob.jwt")
+	if err := os.WriteFile(snapshot, blob, 0644); err != nil {
+		t.Fatalf("Writing snapshot: %v", err)
+	}
+
+	// Restart while the fetcher is unavailable, well past nextUpdate.
+	opts := &CacheOptions{
+		Fetcher:      &stubFetcher{},
+		Roots:        signer.pool(),
+		SnapshotPath: snapshot,
+		Now: func() time.Time {
+			return time.Date(2024, time.August, 1, 0, 0, 0, 0, time.UTC)
+		},
+	}
+	c, err := NewCache(ctx, opts)
+	if err != nil {
+		t.Fatalf("Creating cache from expired snapshot: %v", err)
+	}
+	if got := c.Payload().Number; got != 1 {
+		t.Errorf("Unexpected blob number from snapshot, got=%d, want=1", got)
+	}
+
+	// The snapshot's signature must still verify.
+	opts.Roots = newTestSigner(t).pool()
+	if _, err := NewCache(ctx, opts); err == nil {
+		t.Errorf("Expected error creating cache from snapshot signed by an untrusted root")
+	}
+}
+
+func TestCacheRun(t *testing.T) {
+	signer := newTestSigner(t)
+	aaguid := "ee882879-721c-4913-9775-3dfcce97072a"
+
+	blob1 := signer.sign(t, testPayload(1, "2024-06-01", testEntry(aaguid, nil, "FIDO_CERTIFIED_L1")))
+	blob2 := signer.sign(t, testPayload(2, "2024-07-01", testEntry(aaguid, nil, "FIDO_CERTIFIED_L1")))
+
+	var mu sync.Mutex
+	now := time.Date(2024, time.May, 31, 0, 0, 0, 0, time.UTC)
+	f := &stubFetcher{blob: blob1}
+	c, err := NewCache(context.Background(), &CacheOptions{
+		Fetcher:       f,
+		Roots:         signer.pool(),
+		RetryInterval: time.Millisecond,
+		Now: func() time.Time {
+			mu.Lock()
+			defer mu.Unlock()
+			return now
+		},
+	})
+	if err != nil {
+		t.Fatalf("Creating cache: %v", err)
+	}
+
+	// Move past nextUpdate and publish a new BLOB.
+	mu.Lock()
+	now = time.Date(2024, time.June, 1, 12, 0, 0, 0, time.UTC)
+	mu.Unlock()
+	f.set(blob2)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		c.Run(ctx, nil)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	deadline := time.Now().Add(10 * time.Second)
+	for c.Payload().Number != 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Cache wasn't refreshed")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestNilCacheResolveAAGUID(t *testing.T) {
+	aaguid := mustParseAAGUID(t, "08987058-cadc-4b81-b6e1-30de50dcbe96") // Windows Hello
+
+	var c *Cache
+	if info, ok := c.ResolveAAGUID(aaguid); ok {
+		t.Errorf("Nil cache resolved aaguid: %+v", info)
+	}
+	var p *MetadataBLOBPayload
+	if info, ok := p.ResolveAAGUID(aaguid); ok {
+		t.Errorf("Nil payload resolved aaguid: %+v", info)
+	}
+
+	r := webauthn.DefaultAAGUIDResolver(c, nil)
+	info, ok := r.ResolveAAGUID(aaguid)
+	if !ok {
+		t.Fatalf("AAGUID didn't resolve with a nil cache")
+	}
+	if info.Name != "Windows Hello" {
+		t.Errorf("Unexpected name, got=%q, want=%q", info.Name, "Windows Hello")
+	}
+}
+
+func TestCacheNotLoaded(t *testing.T) {
+	aaguid := mustParseAAGUID(t, "ee882879-721c-4913-9775-3dfcce97072a")
+	for _, c := range []*Cache{nil, {}} {
+		if e, ok := c.EntryByAAGUID(aaguid); ok {
+			t.Errorf("Cache without a BLOB returned entry: %+v", e)
+		}
+		if e, ok := c.EntryByKeyIdentifier("bf7bcaa0d0c6187a8c6abbdd16a15640e7c7bde2"); ok {
+			t.Errorf("Cache without a BLOB returned entry: %+v", e)
+		}
+		if info, ok := c.ResolveAAGUID(aaguid); ok {
+			t.Errorf("Cache without a BLOB resolved aaguid: %+v", info)
+		}
+		if _, err := c.GetStatusReports(aaguid); err == nil {
+			t.Errorf("Expected error getting status reports from a cache without a BLOB")
+		}
+	}
+}
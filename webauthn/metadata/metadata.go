@@ -0,0 +1,313 @@
+// The metadata package implements parsing and verification of the FIDO
+// Alliance Metadata Service (MDS3) BLOB, which describes authenticator models,
+// their attestation roots, and their certification status.
+//
+// https://fidoalliance.org/specs/mds/fido-metadata-service-v3.0-ps-20210518.html
+package metadata
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/go-passkeys/go-passkeys/webauthn"
+	"github.com/go-passkeys/go-passkeys/webauthn/internal/jws"
+)
+
+// URL is the location of the FIDO Alliance Metadata Service BLOB.
+const URL = "https://mds3.fidoalliance.org/"
+
+// dateLayout is the format of dates within the BLOB, such as "2024-12-01".
+const dateLayout = time.DateOnly
+
+// VerifyOptions configures verification of a metadata BLOB.
+type VerifyOptions struct {
+	// Roots holds the root certificates that the BLOB's signing certificate
+	// must chain to. For the FIDO Alliance Metadata Service, this is the
+	// "GlobalSign Root CA - R3" certificate.
+	//
+	// https://fidoalliance.org/metadata/
+	Roots *x509.CertPool
+
+	// CurrentTime is used to validate the signing certificate and the BLOB's
+	// nextUpdate value. If zero, time.Now is used.
+	CurrentTime time.Time
+}
+
+// Parse reads and verifies a metadata BLOB. The BLOB's signature must chain to
+// the provided roots, and the BLOB must not be past its nextUpdate date.
+//
+// Revocation of the signing certificates is not checked.
+//
+// https://fidoalliance.org/specs/mds/fido-metadata-service-v3.0-ps-20210518.html#metadata-blob-object-processing-rules
+func Parse(r io.Reader, opts *VerifyOptions) (*MetadataBLOBPayload, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("reading blob: %v", err)
+	}
+	return parse(b, opts, false)
+}
+
+// parse verifies a metadata BLOB. If allowExpired is set, the BLOB's nextUpdate
+// date isn't checked, but its signature and certificate chain still are.
+func parse(b []byte, opts *VerifyOptions, allowExpired bool) (*MetadataBLOBPayload, error) {
+	if opts == nil || opts.Roots == nil {
+		return nil, fmt.Errorf("root certificates must be provided")
+	}
+	j, err := jws.Parse(b)
+	if err != nil {
+		return nil, fmt.Errorf("parsing blob: %v", err)
+	}
+
+	now := opts.CurrentTime
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	// "If the x5u attribute is present in the JWT Header then ... If the x5u
+	// attribute is missing, the chain should be retrieved from the x5c
+	// attribute."
+	if len(j.Header.X5C) == 0 {
+		return nil, fmt.Errorf("blob header contains no certificates")
+	}
+	var certs []*x509.Certificate
+	for _, raw := range j.Header.X5C {
+		cert, err := x509.ParseCertificate(raw)
+		if err != nil {
+			return nil, fmt.Errorf("parsing blob certificate: %v", err)
+		}
+		certs = append(certs, cert)
+	}
+	leaf := certs[0]
+	verifyOpts := x509.VerifyOptions{
+		Roots:         opts.Roots,
+		Intermediates: x509.NewCertPool(),
+		CurrentTime:   now,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	}
+	for _, cert := range certs[1:] {
+		verifyOpts.Intermediates.AddCert(cert)
+	}
+	if _, err := leaf.Verify(verifyOpts); err != nil {
+		return nil, fmt.Errorf("verifying blob certificate chain: %v", err)
+	}
+	if err := j.Verify(leaf.PublicKey); err != nil {
+		return nil, fmt.Errorf("verifying blob signature: %v", err)
+	}
+
+	var p MetadataBLOBPayload
+	if err := json.Unmarshal(j.Payload, &p); err != nil {
+		return nil, fmt.Errorf("parsing blob payload: %v", err)
+	}
+	if !allowExpired && !now.Before(p.NextUpdate.AddDate(0, 0, 1)) {
+		return nil, fmt.Errorf("blob expired, next update was %s", p.NextUpdate.Format(dateLayout))
+	}
+
+	p.byAAGUID = make(map[webauthn.AAGUID]*MetadataBLOBPayloadEntry)
+	p.byKeyIdentifier = make(map[string]*MetadataBLOBPayloadEntry)
+	for _, e := range p.Entries {
+		if e.AAGUID != (webauthn.AAGUID{}) {
+			p.byAAGUID[e.AAGUID] = e
+		}
+		for _, id := range e.AttestationCertificateKeyIdentifiers {
+			p.byKeyIdentifier[strings.ToLower(id)] = e
+		}
+	}
+	return &p, nil
+}
+
+// parseDate parses an optional date within the BLOB.
+func parseDate(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(dateLayout, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q", s)
+	}
+	return t, nil
+}
+
+// MetadataBLOBPayload is the verified content of a metadata BLOB.
+//
+// https://fidoalliance.org/specs/mds/fido-metadata-service-v3.0-ps-20210518.html#metadata-blob-payload-dictionary
+type MetadataBLOBPayload struct {
+	LegalHeader string `json:"legalHeader"`
+	// Number is the serial number of the BLOB, which increases with every
+	// update.
+	Number int `json:"no"`
+	// NextUpdate is the date by which a new BLOB will be published.
+	NextUpdate time.Time                   `json:"-"`
+	Entries    []*MetadataBLOBPayloadEntry `json:"entries"`
+
+	byAAGUID        map[webauthn.AAGUID]*MetadataBLOBPayloadEntry
+	byKeyIdentifier map[string]*MetadataBLOBPayloadEntry
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (p *MetadataBLOBPayload) UnmarshalJSON(b []byte) error {
+	type alias MetadataBLOBPayload
+	v := struct {
+		*alias
+		NextUpdate string `json:"nextUpdate"`
+	}{alias: (*alias)(p)}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	t, err := parseDate(v.NextUpdate)
+	if err != nil {
+		return fmt.Errorf("parsing nextUpdate: %v", err)
+	}
+	p.NextUpdate = t
+	return nil
+}
+
+// EntryByAAGUID returns the entry for a FIDO2 authenticator model. A nil
+// payload has no entries.
+func (p *MetadataBLOBPayload) EntryByAAGUID(aaguid webauthn.AAGUID) (*MetadataBLOBPayloadEntry, bool) {
+	if p == nil {
+		return nil, false
+	}
+	e, ok := p.byAAGUID[aaguid]
+	return e, ok
+}
+
+// EntryByKeyIdentifier returns the entry for a FIDO U2F authenticator model,
+// identified by the hex encoded SHA-1 hash of an attestation certificate's
+// public key. See [webauthn.FIDOU2F]. A nil payload has no entries.
+func (p *MetadataBLOBPayload) EntryByKeyIdentifier(keyID string) (*MetadataBLOBPayloadEntry, bool) {
+	if p == nil {
+		return nil, false
+	}
+	e, ok := p.byKeyIdentifier[strings.ToLower(keyID)]
+	return e, ok
+}
+
+// MetadataBLOBPayloadEntry describes a single authenticator model.
+//
+// https://fidoalliance.org/specs/mds/fido-metadata-service-v3.0-ps-20210518.html#metadata-blob-payload-entry-dictionary
+type MetadataBLOBPayloadEntry struct {
+	// AAID identifies UAF authenticators.
+	AAID string `json:"aaid"`
+	// AAGUID identifies FIDO2 authenticators.
+	AAGUID webauthn.AAGUID `json:"aaguid"`
+	// AttestationCertificateKeyIdentifiers identifies FIDO U2F authenticators
+	// by the hex encoded SHA-1 hash of their attestation certificates' public
+	// keys.
+	AttestationCertificateKeyIdentifiers []string `json:"attestationCertificateKeyIdentifiers"`
+
+	MetadataStatement *MetadataStatement `json:"metadataStatement"`
+	// StatusReports holds the certification and security history of the
+	// authenticator model.
+	StatusReports          []*StatusReport `json:"statusReports"`
+	TimeOfLastStatusChange time.Time       `json:"-"`
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (e *MetadataBLOBPayloadEntry) UnmarshalJSON(b []byte) error {
+	type alias MetadataBLOBPayloadEntry
+	v := struct {
+		*alias
+		TimeOfLastStatusChange string `json:"timeOfLastStatusChange"`
+	}{alias: (*alias)(e)}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	t, err := parseDate(v.TimeOfLastStatusChange)
+	if err != nil {
+		return fmt.Errorf("parsing timeOfLastStatusChange: %v", err)
+	}
+	e.TimeOfLastStatusChange = t
+	return nil
+}
+
+// StatusReport records a change in the certification or security status of an
+// authenticator model.
+//
+// https://fidoalliance.org/specs/mds/fido-metadata-service-v3.0-ps-20210518.html#statusreport-dictionary
+type StatusReport struct {
+	Status webauthn.AuthenticatorStatus `json:"status"`
+	// EffectiveDate is the date the status was set, or zero if unknown.
+	EffectiveDate time.Time `json:"-"`
+	// AuthenticatorVersion is the lowest version of the authenticator the
+	// status applies to. Zero applies to all versions.
+	AuthenticatorVersion uint32 `json:"authenticatorVersion"`
+	// Certificate is a base64 encoded DER attestation certificate that is
+	// affected by the status, such as a compromised attestation key.
+	Certificate string `json:"certificate"`
+	URL         string `json:"url"`
+
+	CertificationDescriptor          string `json:"certificationDescriptor"`
+	CertificateNumber                string `json:"certificateNumber"`
+	CertificationPolicyVersion       string `json:"certificationPolicyVersion"`
+	CertificationRequirementsVersion string `json:"certificationRequirementsVersion"`
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (s *StatusReport) UnmarshalJSON(b []byte) error {
+	type alias StatusReport
+	v := struct {
+		*alias
+		EffectiveDate string `json:"effectiveDate"`
+	}{alias: (*alias)(s)}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	t, err := parseDate(v.EffectiveDate)
+	if err != nil {
+		return fmt.Errorf("parsing effectiveDate: %v", err)
+	}
+	s.EffectiveDate = t
+	return nil
+}
+
+// ResolveAAGUID implements the webauthn.AAGUIDResolver interface using the
+// description and icon of an entry's metadata statement. The icon is used for
+// both light and dark themes. A nil payload resolves no AAGUIDs.
+func (p *MetadataBLOBPayload) ResolveAAGUID(aaguid webauthn.AAGUID) (*webauthn.AuthenticatorInfo, bool) {
+	if p == nil {
+		return nil, false
+	}
+	e, ok := p.EntryByAAGUID(aaguid)
+	if !ok || e.MetadataStatement == nil {
+		return nil, false
+	}
+	return &webauthn.AuthenticatorInfo{
+		Name:      e.MetadataStatement.Description,
+		IconLight: e.MetadataStatement.Icon,
+		IconDark:  e.MetadataStatement.Icon,
+	}, true
+}
+
+// GetStatusReports returns the status reports of a FIDO2 authenticator model,
+// suitable for the GetStatusReports fields of webauthn.AttestationPolicy and
+// webauthn.StatusPolicy. Models without an entry have no status reports.
+//
+// A nil payload returns an error rather than no reports, so that policies
+// don't silently accept every authenticator when metadata isn't loaded.
+func (p *MetadataBLOBPayload) GetStatusReports(aaguid webauthn.AAGUID) ([]webauthn.StatusReport, error) {
+	if p == nil {
+		return nil, fmt.Errorf("no metadata configured")
+	}
+	e, ok := p.EntryByAAGUID(aaguid)
+	if !ok {
+		return nil, nil
+	}
+	return e.statusReports(), nil
+}
+
+// statusReports converts the entry's status reports for use by the webauthn
+// package.
+func (e *MetadataBLOBPayloadEntry) statusReports() []webauthn.StatusReport {
+	reports := make([]webauthn.StatusReport, 0, len(e.StatusReports))
+	for _, s := range e.StatusReports {
+		reports = append(reports, webauthn.StatusReport{
+			Status:        s.Status,
+			EffectiveDate: s.EffectiveDate,
+		})
+	}
+	return reports
+}
@@ -0,0 +1,363 @@
+package metadata
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-passkeys/go-passkeys/webauthn"
+)
+
+// testSigner issues metadata BLOBs signed by a locally generated certificate
+// chain.
+type testSigner struct {
+	root *x509.Certificate
+	leaf *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestSigner(t *testing.T) *testSigner {
+	t.Helper()
+	rootKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Generating key: %v", err)
+	}
+	leafKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Generating key: %v", err)
+	}
+	notBefore := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	notAfter := time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	rootTmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test Metadata Root"},
+		NotBefore:             notBefore,
+		NotAfter:              notAfter,
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	rootDER, err := x509.CreateCertificate(rand.Reader, rootTmpl, rootTmpl, rootKey.Public(), rootKey)
+	if err != nil {
+		t.Fatalf("Creating root certificate: %v", err)
+	}
+	root, err := x509.ParseCertificate(rootDER)
+	if err != nil {
+		t.Fatalf("Parsing root certificate: %v", err)
+	}
+	leafTmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "Test Metadata Signer"},
+		NotBefore:    notBefore,
+		NotAfter:     notAfter,
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+	leafDER, err := x509.CreateCertificate(rand.Reader, leafTmpl, root, leafKey.Public(), rootKey)
+	if err != nil {
+		t.Fatalf("Creating leaf certificate: %v", err)
+	}
+	leaf, err := x509.ParseCertificate(leafDER)
+	if err != nil {
+		t.Fatalf("Parsing leaf certificate: %v", err)
+	}
+	return &testSigner{root: root, leaf: leaf, key: leafKey}
+}
+
+func (s *testSigner) pool() *x509.CertPool {
+	pool := x509.NewCertPool()
+	pool.AddCert(s.root)
+	return pool
+}
+
+// sign returns a compact serialized JWS of the provided payload.
+func (s *testSigner) sign(t *testing.T, payload any) []byte {
+	t.Helper()
+	header, err := json.Marshal(map[string]any{
+		"alg": "ES256",
+		"typ": "JWT",
+		"x5c": [][]byte{s.leaf.Raw},
+	})
+	if err != nil {
+		t.Fatalf("Encoding header: %v", err)
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		t.Fatalf("Encoding payload: %v", err)
+	}
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(body)
+	h := sha256.Sum256([]byte(signingInput))
+	r, ss, err := ecdsa.Sign(rand.Reader, s.key, h[:])
+	if err != nil {
+		t.Fatalf("Signing blob: %v", err)
+	}
+	sig := append(r.FillBytes(make([]byte, 32)), ss.FillBytes(make([]byte, 32))...)
+	return []byte(signingInput + "." + base64.RawURLEncoding.EncodeToString(sig))
+}
+
+// testPayload returns a BLOB payload describing a single authenticator.
+func testPayload(no int, nextUpdate string, entries ...map[string]any) map[string]any {
+	return map[string]any{
+		"legalHeader": "Test metadata",
+		"no":          no,
+		"nextUpdate":  nextUpdate,
+		"entries":     entries,
+	}
+}
+
+func testEntry(aaguid string, keyIDs []string, statuses ...string) map[string]any {
+	var reports []map[string]any
+	for _, s := range statuses {
+		reports = append(reports, map[string]any{
+			"status":        s,
+			"effectiveDate": "2024-01-01",
+		})
+	}
+	e := map[string]any{
+		"metadataStatement": map[string]any{
+			"description":    "Test Authenticator",
+			"protocolFamily": "fido2",
+			"schema":         3,
+		},
+		"statusReports":          reports,
+		"timeOfLastStatusChange": "2024-01-01",
+	}
+	if aaguid != "" {
+		e["aaguid"] = aaguid
+	}
+	if keyIDs != nil {
+		e["attestationCertificateKeyIdentifiers"] = keyIDs
+	}
+	return e
+}
+
+func TestParse(t *testing.T) {
+	signer := newTestSigner(t)
+	now := time.Date(2024, time.June, 1, 0, 0, 0, 0, time.UTC)
+	aaguid := "ee882879-721c-4913-9775-3dfcce97072a"
+	keyID := "bf7bcaa0d0c6187a8c6abbdd16a15640e7c7bde2"
+
+	valid := signer.sign(t, testPayload(3, "2024-06-01",
+		testEntry(aaguid, nil, "FIDO_CERTIFIED_L1"),
+		testEntry("", []string{strings.ToUpper(keyID)}, "FIDO_CERTIFIED"),
+	))
+
+	testCases := []struct {
+		name    string
+		blob    []byte
+		roots   *x509.CertPool
+		now     time.Time
+		wantErr string
+	}{
+		{
+			name:  "Valid",
+			blob:  valid,
+			roots: signer.pool(),
+			now:   now,
+		},
+		{
+			name:    "Untrusted root",
+			blob:    valid,
+			roots:   newTestSigner(t).pool(),
+			now:     now,
+			wantErr: "certificate chain",
+		},
+		{
+			name:    "Expired",
+			blob:    valid,
+			roots:   signer.pool(),
+			now:     now.AddDate(0, 0, 1),
+			wantErr: "blob expired",
+		},
+		{
+			name: "Tampered payload",
+			blob: func() []byte {
+				parts := bytes.Split(valid, []byte{'.'})
+				other := signer.sign(t, testPayload(4, "2024-06-01"))
+				parts[1] = bytes.Split(other, []byte{'.'})[1]
+				return bytes.Join(parts, []byte{'.'})
+			}(),
+			roots:   signer.pool(),
+			now:     now,
+			wantErr: "blob signature",
+		},
+		{
+			name:    "Invalid date",
+			blob:    signer.sign(t, testPayload(3, "June 1st")),
+			roots:   signer.pool(),
+			now:     now,
+			wantErr: "nextUpdate",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			p, err := Parse(bytes.NewReader(tc.blob), &VerifyOptions{Roots: tc.roots, CurrentTime: tc.now})
+			if tc.wantErr != "" {
+				if err == nil {
+					t.Fatalf("Expected error parsing blob")
+				}
+				if !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("Parsing blob returned unexpected error, got=%v, want=%s", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parsing blob: %v", err)
+			}
+			if p.Number != 3 {
+				t.Errorf("Unexpected blob number, got=%d, want=3", p.Number)
+			}
+			e, ok := p.EntryByAAGUID(mustParseAAGUID(t, aaguid))
+			if !ok {
+				t.Fatalf("Entry not found by aaguid")
+			}
+			if len(e.StatusReports) != 1 || e.StatusReports[0].Status != webauthn.StatusFIDOCertifiedL1 {
+				t.Errorf("Unexpected status reports: %+v", e.StatusReports)
+			}
+			if want := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC); !e.StatusReports[0].EffectiveDate.Equal(want) {
+				t.Errorf("Unexpected effective date, got=%s, want=%s", e.StatusReports[0].EffectiveDate, want)
+			}
+			if _, ok := p.EntryByKeyIdentifier(keyID); !ok {
+				t.Errorf("Entry not found by key identifier")
+			}
+		})
+	}
+}
+
+func mustParseAAGUID(t *testing.T, s string) webauthn.AAGUID {
+	t.Helper()
+	aaguid, err := webauthn.ParseAAGUID(s)
+	if err != nil {
+		t.Fatalf("Parsing aaguid: %v", err)
+	}
+	return aaguid
+}
+
+func TestParseFIDOMetadataService(t *testing.T) {
+	rootPEM, err := os.ReadFile("testdata/globalsign_root_ca_r3.pem")
+	if err != nil {
+		t.Fatalf("Reading root certificate: %v", err)
+	}
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(rootPEM) {
+		t.Fatalf("Parsing root certificate failed")
+	}
+	f, err := os.Open("../testdata/blob.jwt")
+	if err != nil {
+		t.Fatalf("Opening blob: %v", err)
+	}
+	defer f.Close()
+
+	opts := &VerifyOptions{
+		Roots:       roots,
+		CurrentTime: time.Date(2024, time.November, 1, 0, 0, 0, 0, time.UTC),
+	}
+	p, err := Parse(f, opts)
+	if err != nil {
+		t.Fatalf("Parsing blob: %v", err)
+	}
+	if p.Number != 112 {
+		t.Errorf("Unexpected blob number, got=%d, want=112", p.Number)
+	}
+	for _, e := range p.Entries {
+		if _, err := e.MetadataStatement.RootCertificates(); err != nil {
+			t.Errorf("Parsing attestation roots of %s: %v", e.MetadataStatement.Description, err)
+		}
+	}
+
+	e, ok := p.EntryByAAGUID(mustParseAAGUID(t, "cb69481e-8ff7-4039-93ec-0a2729a154a8"))
+	if !ok {
+		t.Fatalf("YubiKey 5 Series not found")
+	}
+	if got, want := e.MetadataStatement.Description, "YubiKey 5 Series"; got != want {
+		t.Errorf("Unexpected description, got=%s, want=%s", got, want)
+	}
+	attRoots, err := e.MetadataStatement.RootCertificates()
+	if err != nil {
+		t.Fatalf("Parsing attestation roots: %v", err)
+	}
+	if len(attRoots) == 0 {
+		t.Errorf("No attestation roots for entry")
+	}
+
+	info, ok := p.ResolveAAGUID(mustParseAAGUID(t, "cb69481e-8ff7-4039-93ec-0a2729a154a8"))
+	if !ok {
+		t.Fatalf("Resolving aaguid failed")
+	}
+	if info.Name != "YubiKey 5 Series" || !strings.HasPrefix(info.IconLight, "data:image/png;base64,") {
+		t.Errorf("Unexpected authenticator info, got name=%s, icon=%.30s", info.Name, info.IconLight)
+	}
+
+	e, ok = p.EntryByKeyIdentifier("bf7bcaa0d0c6187a8c6abbdd16a15640e7c7bde2")
+	if !ok {
+		t.Fatalf("Entry not found by key identifier")
+	}
+	if got, want := e.MetadataStatement.Description, "YubiKey 5 Series with Lightning"; got != want {
+		t.Errorf("Unexpected description, got=%s, want=%s", got, want)
+	}
+}
+
+func TestGetStatusReports(t *testing.T) {
+	signer := newTestSigner(t)
+	now := time.Date(2024, time.June, 1, 0, 0, 0, 0, time.UTC)
+
+	const (
+		certified   = "ee882879-721c-4913-9775-3dfcce97072a"
+		compromised = "08987058-cadc-4b81-b6e1-30de50dcbe96"
+		unknown     = "adce0002-35bc-c60a-648b-0b25f1f05503"
+	)
+	blob := signer.sign(t, testPayload(1, "2024-06-01",
+		testEntry(certified, nil, "FIDO_CERTIFIED_L1"),
+		testEntry(compromised, nil, "FIDO_CERTIFIED_L1", "ATTESTATION_KEY_COMPROMISE"),
+	))
+	p, err := Parse(bytes.NewReader(blob), &VerifyOptions{Roots: signer.pool(), CurrentTime: now})
+	if err != nil {
+		t.Fatalf("Parsing blob: %v", err)
+	}
+
+	policy := &webauthn.StatusPolicy{
+		GetStatusReports: p.GetStatusReports,
+		Reject:           true,
+		Now:              func() time.Time { return now },
+	}
+	for _, aaguid := range []string{certified, unknown} {
+		if _, err := policy.Check(mustParseAAGUID(t, aaguid)); err != nil {
+			t.Errorf("Checking status of %s: %v", aaguid, err)
+		}
+	}
+	_, err = policy.Check(mustParseAAGUID(t, compromised))
+	if err == nil {
+		t.Fatalf("Expected error checking status of compromised authenticator")
+	}
+	if !strings.Contains(err.Error(), "ATTESTATION_KEY_COMPROMISE effective 2024-01-01") {
+		t.Errorf("Checking status returned unexpected error: %v", err)
+	}
+}
+
+func TestNilPayload(t *testing.T) {
+	var p *MetadataBLOBPayload
+	aaguid := mustParseAAGUID(t, "ee882879-721c-4913-9775-3dfcce97072a")
+	if e, ok := p.EntryByAAGUID(aaguid); ok {
+		t.Errorf("Nil payload returned entry: %+v", e)
+	}
+	if e, ok := p.EntryByKeyIdentifier("bf7bcaa0d0c6187a8c6abbdd16a15640e7c7bde2"); ok {
+		t.Errorf("Nil payload returned entry: %+v", e)
+	}
+
+	policy := &webauthn.StatusPolicy{GetStatusReports: p.GetStatusReports}
+	if _, err := policy.Check(aaguid); err == nil {
+		t.Errorf("Expected error checking status with a nil payload")
+	}
+}
@@ -0,0 +1,119 @@
+package metadata
+
+import (
+	"crypto/x509"
+	"fmt"
+	"time"
+
+	"github.com/go-passkeys/go-passkeys/webauthn"
+)
+
+// compromisedStatuses indicate that attestations from an authenticator model
+// can no longer be trusted.
+var compromisedStatuses = []webauthn.AuthenticatorStatus{
+	webauthn.StatusRevoked,
+	webauthn.StatusAttestationKeyCompromise,
+	webauthn.StatusUserKeyRemoteCompromise,
+}
+
+// RootProvider looks up attestation root certificates from a metadata BLOB.
+// Its methods can be used directly as the GetRoots options of the webauthn
+// package:
+//
+//	p, err := metadata.Parse(r, opts)
+//	if err != nil {
+//		// ...
+//	}
+//	roots := &metadata.RootProvider{Payload: p, RefuseCompromised: true}
+//	packedOpts := &webauthn.PackedOptions{GetRoots: roots.GetRoots}
+//	u2fOpts := &webauthn.FIDOU2FOptions{GetRoots: roots.GetRootsByKeyIdentifier}
+type RootProvider struct {
+	Payload *MetadataBLOBPayload
+
+	// Cache, if set, is used to look up entries instead of Payload, so that
+	// refreshed BLOBs are used as they're published.
+	Cache *Cache
+
+	// RefuseCompromised causes authenticator models with an effective status
+	// report of REVOKED, ATTESTATION_KEY_COMPROMISE, or
+	// USER_KEY_REMOTE_COMPROMISE to be rejected, even if later reports
+	// re-certify the model. See [webauthn.FlaggedStatusReport].
+	RefuseCompromised bool
+
+	// Now returns the current time, used to ignore status reports that are not
+	// yet effective. If unset, the Cache's clock or time.Now is used.
+	Now func() time.Time
+}
+
+// GetRoots returns the attestation root certificates of a FIDO2 authenticator
+// model.
+func (r *RootProvider) GetRoots(aaguid webauthn.AAGUID) (*x509.CertPool, error) {
+	p, err := r.payload()
+	if err != nil {
+		return nil, err
+	}
+	e, ok := p.EntryByAAGUID(aaguid)
+	if !ok {
+		return nil, fmt.Errorf("no metadata for aaguid %s", aaguid)
+	}
+	return r.roots(e)
+}
+
+// GetRootsByKeyIdentifier returns the attestation root certificates of a FIDO
+// U2F authenticator model, identified by the key identifier of its
+// attestation certificate.
+func (r *RootProvider) GetRootsByKeyIdentifier(keyID string) (*x509.CertPool, error) {
+	p, err := r.payload()
+	if err != nil {
+		return nil, err
+	}
+	e, ok := p.EntryByKeyIdentifier(keyID)
+	if !ok {
+		return nil, fmt.Errorf("no metadata for key identifier %s", keyID)
+	}
+	return r.roots(e)
+}
+
+func (r *RootProvider) payload() (*MetadataBLOBPayload, error) {
+	p := r.Payload
+	if r.Cache != nil {
+		p = r.Cache.Payload()
+	}
+	if p == nil {
+		return nil, fmt.Errorf("no metadata configured")
+	}
+	return p, nil
+}
+
+func (r *RootProvider) now() time.Time {
+	if r.Now != nil {
+		return r.Now()
+	}
+	if r.Cache != nil {
+		return r.Cache.now()
+	}
+	return time.Now()
+}
+
+func (r *RootProvider) roots(e *MetadataBLOBPayloadEntry) (*x509.CertPool, error) {
+	if r.RefuseCompromised {
+		if s := webauthn.FlaggedStatusReport(e.statusReports(), compromisedStatuses, r.now()); s != nil {
+			return nil, fmt.Errorf("authenticator has status %s", s.Status)
+		}
+	}
+	if e.MetadataStatement == nil {
+		return nil, fmt.Errorf("entry has no metadata statement")
+	}
+	certs, err := e.MetadataStatement.RootCertificates()
+	if err != nil {
+		return nil, err
+	}
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("entry has no attestation root certificates")
+	}
+	pool := x509.NewCertPool()
+	for _, cert := range certs {
+		pool.AddCert(cert)
+	}
+	return pool, nil
+}
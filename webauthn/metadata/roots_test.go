@@ -0,0 +1,118 @@
+package metadata
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRootProvider(t *testing.T) {
+	signer := newTestSigner(t)
+	attRoot := newTestSigner(t).root
+	now := time.Date(2024, time.June, 1, 0, 0, 0, 0, time.UTC)
+
+	const (
+		certified   = "ee882879-721c-4913-9775-3dfcce97072a"
+		revoked     = "08987058-cadc-4b81-b6e1-30de50dcbe96"
+		recertified = "b93fd961-f2e6-462f-b122-82002247de78"
+		unknown     = "adce0002-35bc-c60a-648b-0b25f1f05503"
+		keyID       = "bf7bcaa0d0c6187a8c6abbdd16a15640e7c7bde2"
+	)
+
+	withRoots := func(e map[string]any) map[string]any {
+		stmt := e["metadataStatement"].(map[string]any)
+		stmt["attestationRootCertificates"] = []string{base64.StdEncoding.EncodeToString(attRoot.Raw)}
+		return e
+	}
+	blob := signer.sign(t, testPayload(1, "2024-06-01",
+		withRoots(testEntry(certified, nil, "FIDO_CERTIFIED_L1")),
+		withRoots(testEntry(revoked, nil, "FIDO_CERTIFIED_L1", "REVOKED")),
+		withRoots(testEntry(recertified, nil, "ATTESTATION_KEY_COMPROMISE", "FIDO_CERTIFIED_L2")),
+		withRoots(testEntry("", []string{keyID}, "FIDO_CERTIFIED")),
+	))
+	p, err := Parse(bytes.NewReader(blob), &VerifyOptions{Roots: signer.pool(), CurrentTime: now})
+	if err != nil {
+		t.Fatalf("Parsing blob: %v", err)
+	}
+	testCases := []struct {
+		name              string
+		aaguid            string
+		refuseCompromised bool
+		wantErr           string
+	}{
+		{
+			name:   "Certified",
+			aaguid: certified,
+		},
+		{
+			name:              "Certified refusing compromised",
+			aaguid:            certified,
+			refuseCompromised: true,
+		},
+		{
+			name:   "Revoked",
+			aaguid: revoked,
+		},
+		{
+			name:              "Revoked refusing compromised",
+			aaguid:            revoked,
+			refuseCompromised: true,
+			wantErr:           "REVOKED",
+		},
+		{
+			name:              "Recertified refusing compromised",
+			aaguid:            recertified,
+			refuseCompromised: true,
+			wantErr:           "ATTESTATION_KEY_COMPROMISE",
+		},
+		{
+			name:    "Unknown",
+			aaguid:  unknown,
+			wantErr: "no metadata",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := &RootProvider{
+				Payload:           p,
+				RefuseCompromised: tc.refuseCompromised,
+				Now:               func() time.Time { return now },
+			}
+			pool, err := r.GetRoots(mustParseAAGUID(t, tc.aaguid))
+			if tc.wantErr != "" {
+				if err == nil {
+					t.Fatalf("Expected error getting roots")
+				}
+				if !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("Getting roots returned unexpected error, got=%v, want=%s", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Getting roots: %v", err)
+			}
+			want := x509.NewCertPool()
+			want.AddCert(attRoot)
+			if !pool.Equal(want) {
+				t.Errorf("Unexpected root certificates")
+			}
+		})
+	}
+
+	r := &RootProvider{Payload: p, RefuseCompromised: true}
+	if _, err := r.GetRootsByKeyIdentifier(strings.ToUpper(keyID)); err != nil {
+		t.Errorf("Getting roots by key identifier: %v", err)
+	}
+
+	empty := &RootProvider{}
+	if _, err := empty.GetRoots(mustParseAAGUID(t, certified)); err == nil || !strings.Contains(err.Error(), "no metadata configured") {
+		t.Errorf("Getting roots without metadata returned unexpected error, got=%v", err)
+	}
+	if _, err := empty.GetRootsByKeyIdentifier(keyID); err == nil || !strings.Contains(err.Error(), "no metadata configured") {
+		t.Errorf("Getting roots by key identifier without metadata returned unexpected error, got=%v", err)
+	}
+}
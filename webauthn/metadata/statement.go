@@ -0,0 +1,222 @@
+package metadata
+
+import (
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/go-passkeys/go-passkeys/webauthn"
+)
+
+// MetadataStatement describes the characteristics of an authenticator model.
+//
+// https://fidoalliance.org/specs/mds/fido-metadata-statement-v3.0-ps-20210518.html#metadata-keys
+type MetadataStatement struct {
+	LegalHeader string `json:"legalHeader"`
+	// AAID identifies UAF authenticators.
+	AAID string `json:"aaid"`
+	// AAGUID identifies FIDO2 authenticators.
+	AAGUID webauthn.AAGUID `json:"aaguid"`
+	// AttestationCertificateKeyIdentifiers identifies FIDO U2F authenticators.
+	AttestationCertificateKeyIdentifiers []string `json:"attestationCertificateKeyIdentifiers"`
+
+	// Description is a human readable name of the authenticator model, such as
+	// "YubiKey 5 Series".
+	Description string `json:"description"`
+	// AlternativeDescriptions maps IETF language codes to localized
+	// descriptions.
+	AlternativeDescriptions map[string]string `json:"alternativeDescriptions"`
+	AuthenticatorVersion    uint32            `json:"authenticatorVersion"`
+	// ProtocolFamily is one of "uaf", "u2f", or "fido2".
+	ProtocolFamily string    `json:"protocolFamily"`
+	Schema         int       `json:"schema"`
+	UPV            []Version `json:"upv"`
+
+	// AuthenticationAlgorithms lists supported signing algorithms, such as
+	// "secp256r1_ecdsa_sha256_raw".
+	//
+	// https://fidoalliance.org/specs/common-specs/fido-registry-v2.2-ps-20220523.html#authentication-algorithms
+	AuthenticationAlgorithms []string `json:"authenticationAlgorithms"`
+	// PublicKeyAlgAndEncodings lists public key formats, such as "cose".
+	PublicKeyAlgAndEncodings []string `json:"publicKeyAlgAndEncodings"`
+	// AttestationTypes lists supported attestation types, such as
+	// "basic_full", "attca", or "anonca".
+	AttestationTypes []string `json:"attestationTypes"`
+	// UserVerificationDetails lists combinations of user verification methods
+	// supported by the authenticator. Each inner list must be satisfied in
+	// full.
+	UserVerificationDetails [][]VerificationMethodDescriptor `json:"userVerificationDetails"`
+
+	// KeyProtection describes how keys are stored, such as "hardware" or
+	// "secure_element".
+	KeyProtection                   []string `json:"keyProtection"`
+	IsKeyRestricted                 *bool    `json:"isKeyRestricted"`
+	IsFreshUserVerificationRequired *bool    `json:"isFreshUserVerificationRequired"`
+	MatcherProtection               []string `json:"matcherProtection"`
+	CryptoStrength                  int      `json:"cryptoStrength"`
+	// AttachmentHint describes how the authenticator connects to the client,
+	// such as "internal", "external", or "nfc".
+	AttachmentHint []string `json:"attachmentHint"`
+
+	TCDisplay                   []string                              `json:"tcDisplay"`
+	TCDisplayContentType        string                                `json:"tcDisplayContentType"`
+	TCDisplayPNGCharacteristics []DisplayPNGCharacteristicsDescriptor `json:"tcDisplayPNGCharacteristics"`
+
+	// AttestationRootCertificates holds base64 encoded DER certificates that
+	// attestation certificates of the authenticator model chain to. Use
+	// RootCertificates to parse them.
+	AttestationRootCertificates []string `json:"attestationRootCertificates"`
+	// Icon is a data URL of a PNG icon for the authenticator model.
+	Icon string `json:"icon"`
+
+	SupportedExtensions  []ExtensionDescriptor `json:"supportedExtensions"`
+	AuthenticatorGetInfo *AuthenticatorGetInfo `json:"authenticatorGetInfo"`
+}
+
+// RootCertificates parses the statement's attestation root certificates.
+func (m *MetadataStatement) RootCertificates() ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	for _, s := range m.AttestationRootCertificates {
+		// Some statements include stray whitespace around the encoded value.
+		raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+		if err != nil {
+			return nil, fmt.Errorf("decoding attestation root certificate: %v", err)
+		}
+		// Ignore trailing data after the certificate, which is also present in
+		// some statements.
+		var der asn1.RawValue
+		if _, err := asn1.Unmarshal(raw, &der); err != nil {
+			return nil, fmt.Errorf("parsing attestation root certificate: %v", err)
+		}
+		cert, err := x509.ParseCertificate(der.FullBytes)
+		if err != nil {
+			return nil, fmt.Errorf("parsing attestation root certificate: %v", err)
+		}
+		certs = append(certs, cert)
+	}
+	return certs, nil
+}
+
+// Version is a protocol version supported by the authenticator.
+//
+// https://fidoalliance.org/specs/common-specs/fido-uaf-protocol-v1.2-ps-20201020.html#version-interface
+type Version struct {
+	Major uint16 `json:"major"`
+	Minor uint16 `json:"minor"`
+}
+
+// VerificationMethodDescriptor describes a single user verification method.
+//
+// https://fidoalliance.org/specs/mds/fido-metadata-statement-v3.0-ps-20210518.html#verificationmethoddescriptor-dictionary
+type VerificationMethodDescriptor struct {
+	// UserVerificationMethod is a method such as "presence_internal",
+	// "fingerprint_internal", or "passcode_external".
+	UserVerificationMethod string                       `json:"userVerificationMethod"`
+	CaDesc                 *CodeAccuracyDescriptor      `json:"caDesc"`
+	BaDesc                 *BiometricAccuracyDescriptor `json:"baDesc"`
+	PaDesc                 *PatternAccuracyDescriptor   `json:"paDesc"`
+}
+
+// CodeAccuracyDescriptor describes the accuracy of PIN or passcode based user
+// verification.
+//
+// https://fidoalliance.org/specs/mds/fido-metadata-statement-v3.0-ps-20210518.html#codeaccuracydescriptor-dictionary
+type CodeAccuracyDescriptor struct {
+	Base          int `json:"base"`
+	MinLength     int `json:"minLength"`
+	MaxRetries    int `json:"maxRetries"`
+	BlockSlowdown int `json:"blockSlowdown"`
+}
+
+// BiometricAccuracyDescriptor describes the accuracy of biometric user
+// verification.
+//
+// https://fidoalliance.org/specs/mds/fido-metadata-statement-v3.0-ps-20210518.html#biometricaccuracydescriptor-dictionary
+type BiometricAccuracyDescriptor struct {
+	SelfAttestedFRR float64 `json:"selfAttestedFRR"`
+	SelfAttestedFAR float64 `json:"selfAttestedFAR"`
+	MaxTemplates    int     `json:"maxTemplates"`
+	MaxRetries      int     `json:"maxRetries"`
+	BlockSlowdown   int     `json:"blockSlowdown"`
+}
+
+// PatternAccuracyDescriptor describes the accuracy of pattern based user
+// verification.
+//
+// https://fidoalliance.org/specs/mds/fido-metadata-statement-v3.0-ps-20210518.html#patternaccuracydescriptor-dictionary
+type PatternAccuracyDescriptor struct {
+	MinComplexity uint64 `json:"minComplexity"`
+	MaxRetries    int    `json:"maxRetries"`
+	BlockSlowdown int    `json:"blockSlowdown"`
+}
+
+// DisplayPNGCharacteristicsDescriptor describes a PNG image supported by a
+// transaction confirmation display.
+//
+// https://fidoalliance.org/specs/mds/fido-metadata-statement-v3.0-ps-20210518.html#displaypngcharacteristicsdescriptor-dictionary
+type DisplayPNGCharacteristicsDescriptor struct {
+	Width       uint32 `json:"width"`
+	Height      uint32 `json:"height"`
+	BitDepth    uint8  `json:"bitDepth"`
+	ColorType   uint8  `json:"colorType"`
+	Compression uint8  `json:"compression"`
+	Filter      uint8  `json:"filter"`
+	Interlace   uint8  `json:"interlace"`
+	// PLTE holds the palette entries of the image.
+	PLTE []RGBPaletteEntry `json:"plte"`
+}
+
+// RGBPaletteEntry is a PNG palette entry.
+//
+// https://fidoalliance.org/specs/mds/fido-metadata-statement-v3.0-ps-20210518.html#rgbpaletteentry-dictionary
+type RGBPaletteEntry struct {
+	R uint16 `json:"r"`
+	G uint16 `json:"g"`
+	B uint16 `json:"b"`
+}
+
+// ExtensionDescriptor describes an extension supported by the authenticator.
+//
+// https://fidoalliance.org/specs/mds/fido-metadata-statement-v3.0-ps-20210518.html#extensiondescriptor-dictionary
+type ExtensionDescriptor struct {
+	ID            string `json:"id"`
+	Tag           uint16 `json:"tag"`
+	Data          string `json:"data"`
+	FailIfUnknown bool   `json:"fail_if_unknown"`
+}
+
+// AuthenticatorGetInfo holds the response of the CTAP2 authenticatorGetInfo
+// command for FIDO2 authenticators.
+//
+// https://fidoalliance.org/specs/fido-v2.1-ps-20210615/fido-client-to-authenticator-protocol-v2.1-ps-20210615.html#authenticatorGetInfo
+type AuthenticatorGetInfo struct {
+	// Versions lists supported protocol versions, such as "FIDO_2_0" or
+	// "U2F_V2".
+	Versions   []string `json:"versions"`
+	Extensions []string `json:"extensions"`
+	// AAGUID is the hex encoded AAGUID, without dashes.
+	AAGUID     string          `json:"aaguid"`
+	Options    map[string]bool `json:"options"`
+	MaxMsgSize uint            `json:"maxMsgSize"`
+
+	PINUVAuthProtocols       []uint `json:"pinUvAuthProtocols"`
+	MaxCredentialCountInList uint   `json:"maxCredentialCountInList"`
+	MaxCredentialIDLength    uint   `json:"maxCredentialIdLength"`
+	// Transports lists supported transports, such as "usb" or "nfc".
+	Transports []string `json:"transports"`
+	// Algorithms lists supported credential algorithms.
+	Algorithms      []PublicKeyCredentialParameters `json:"algorithms"`
+	FirmwareVersion uint                            `json:"firmwareVersion"`
+	MinPINLength    uint                            `json:"minPINLength"`
+}
+
+// PublicKeyCredentialParameters is a credential type and algorithm supported by
+// an authenticator.
+//
+// https://www.w3.org/TR/webauthn-3/#dictdef-publickeycredentialparameters
+type PublicKeyCredentialParameters struct {
+	Type string             `json:"type"`
+	Alg  webauthn.Algorithm `json:"alg"`
+}
@@ -0,0 +1,233 @@
+package webauthn
+
+import "slices"
+
+// supportedAlgorithms holds the algorithms accepted by this package, in order
+// of preference.
+var supportedAlgorithms = []Algorithm{
+	ES256,
+	EdDSA,
+	ES384,
+	ES512,
+	RS256,
+	RS384,
+	RS512,
+}
+
+// SupportedAlgorithms returns the signing algorithms that can be verified by
+// this package, in order of preference.
+func SupportedAlgorithms() []Algorithm {
+	return slices.Clone(supportedAlgorithms)
+}
+
+// PublicKeyCredentialType is the only credential type defined by WebAuthn.
+//
+// https://www.w3.org/TR/webauthn-3/#enumdef-publickeycredentialtype
+const PublicKeyCredentialType = "public-key"
+
+// Values of the "residentKey" member of [AuthenticatorSelection].
+//
+// https://www.w3.org/TR/webauthn-3/#enumdef-residentkeyrequirement
+const (
+	ResidentKeyDiscouraged = "discouraged"
+	ResidentKeyPreferred   = "preferred"
+	ResidentKeyRequired    = "required"
+)
+
+// Values of the "userVerification" member of [AuthenticatorSelection] and
+// [RequestOptions], and of [RelyingParty.UserVerification].
+//
+// https://www.w3.org/TR/webauthn-3/#enumdef-userverificationrequirement
+const (
+	UserVerificationRequired    = "required"
+	UserVerificationPreferred   = "preferred"
+	UserVerificationDiscouraged = "discouraged"
+)
+
+// Values of the "attestation" member of [CreationOptions].
+//
+// https://www.w3.org/TR/webauthn-3/#enumdef-attestationconveyancepreference
+const (
+	AttestationNone       = "none"
+	AttestationIndirect   = "indirect"
+	AttestationDirect     = "direct"
+	AttestationEnterprise = "enterprise"
+)
+
+// Values of the "hints" member of [CreationOptions] and [RequestOptions].
+//
+// https://www.w3.org/TR/webauthn-3/#enumdef-publickeycredentialhint
+const (
+	HintSecurityKey  = "security-key"
+	HintClientDevice = "client-device"
+	HintHybrid       = "hybrid"
+)
+
+// RelyingPartyEntity describes the relying party when creating a credential.
+//
+// https://www.w3.org/TR/webauthn-3/#dictdef-publickeycredentialrpentity
+type RelyingPartyEntity struct {
+	ID   string `json:"id,omitempty"`
+	Name string `json:"name"`
+}
+
+// UserEntity describes the user account a credential is created for.
+//
+// https://www.w3.org/TR/webauthn-3/#dictdef-publickeycredentialuserentity
+type UserEntity struct {
+	// ID is the user handle, an opaque identifier of at most 64 bytes. It
+	// shouldn't contain personally identifying information.
+	//
+	// https://www.w3.org/TR/webauthn-3/#user-handle
+	ID Base64URL `json:"id"`
+	// Name is a human readable identifier for the account, such as a username
+	// or email address.
+	Name string `json:"name"`
+	// DisplayName is a human friendly name for the account, such as the
+	// user's full name.
+	DisplayName string `json:"displayName"`
+}
+
+// CredentialParameters identifies a type of credential the relying party
+// accepts.
+//
+// https://www.w3.org/TR/webauthn-3/#dictdef-publickeycredentialparameters
+type CredentialParameters struct {
+	Type string    `json:"type"`
+	Alg  Algorithm `json:"alg"`
+}
+
+// CredentialDescriptor identifies an existing credential, such as to exclude it
+// during registration or allow it during authentication. Relying parties should
+// store the credential ID and transports of each credential at registration to
+// populate this value.
+//
+// https://www.w3.org/TR/webauthn-3/#dictdef-publickeycredentialdescriptorjson
+type CredentialDescriptor struct {
+	Type string    `json:"type"`
+	ID   Base64URL `json:"id"`
+	// Transports holds the values returned by getTransports() when the
+	// credential was registered, such as "internal" or "usb".
+	Transports []string `json:"transports,omitempty"`
+}
+
+// AuthenticatorSelection holds the relying party's requirements for the
+// authenticator creating a credential.
+//
+// https://www.w3.org/TR/webauthn-3/#dictdef-authenticatorselectioncriteria
+type AuthenticatorSelection struct {
+	// AuthenticatorAttachment is "platform" or "cross-platform".
+	AuthenticatorAttachment string `json:"authenticatorAttachment,omitempty"`
+	// ResidentKey is "discouraged", "preferred", or "required".
+	ResidentKey string `json:"residentKey,omitempty"`
+	// RequireResidentKey is retained for backwards compatibility, and should
+	// be set if ResidentKey is "required".
+	RequireResidentKey bool `json:"requireResidentKey,omitempty"`
+	// UserVerification is "required", "preferred", or "discouraged".
+	UserVerification string `json:"userVerification,omitempty"`
+}
+
+// CreationOptions holds the options used to create a credential. It marshals to
+// the PublicKeyCredentialCreationOptionsJSON format, which browsers can consume
+// directly:
+//
+//	const resp = await fetch("/registration-start", { method: "POST" });
+//	const opts = PublicKeyCredential.parseCreationOptionsFromJSON(await resp.json());
+//	const cred = await navigator.credentials.create({ publicKey: opts });
+//
+// https://www.w3.org/TR/webauthn-3/#dictdef-publickeycredentialcreationoptionsjson
+type CreationOptions struct {
+	RP               RelyingPartyEntity     `json:"rp"`
+	User             UserEntity             `json:"user"`
+	Challenge        Base64URL              `json:"challenge"`
+	PubKeyCredParams []CredentialParameters `json:"pubKeyCredParams"`
+	// Timeout is a hint of the time, in milliseconds, the relying party is
+	// willing to wait for the ceremony to complete.
+	Timeout uint32 `json:"timeout,omitempty"`
+	// ExcludeCredentials lists credentials already registered for the user,
+	// preventing the same authenticator from being registered twice.
+	ExcludeCredentials     []CredentialDescriptor  `json:"excludeCredentials,omitempty"`
+	AuthenticatorSelection *AuthenticatorSelection `json:"authenticatorSelection,omitempty"`
+	Hints                  []string                `json:"hints,omitempty"`
+	// Attestation is "none", "indirect", "direct", or "enterprise".
+	Attestation string `json:"attestation,omitempty"`
+	// AttestationFormats lists preferred attestation statement formats, such
+	// as "packed", in order of preference.
+	AttestationFormats []string               `json:"attestationFormats,omitempty"`
+	Extensions         *ClientExtensionInputs `json:"extensions,omitempty"`
+}
+
+// NewCreationOptions returns options to create a credential for a user,
+// populated with the relying party's ID, name, and user verification
+// requirement. The credential parameters default to the algorithms this
+// package supports. Callers can set additional fields, such as
+// ExcludeCredentials, before sending the options to the browser.
+//
+// The challenge should be at least 16 random bytes, and stored by the relying
+// party to pass to [RelyingParty.VerifyRegistration].
+func (rp *RelyingParty) NewCreationOptions(challenge []byte, user UserEntity) *CreationOptions {
+	params := make([]CredentialParameters, 0, len(supportedAlgorithms))
+	for _, alg := range supportedAlgorithms {
+		params = append(params, CredentialParameters{Type: PublicKeyCredentialType, Alg: alg})
+	}
+	opts := &CreationOptions{
+		RP: RelyingPartyEntity{
+			ID:   rp.ID,
+			Name: rp.Name,
+		},
+		User:             user,
+		Challenge:        challenge,
+		PubKeyCredParams: params,
+	}
+	if rp.UserVerification != "" {
+		opts.AuthenticatorSelection = &AuthenticatorSelection{
+			UserVerification: rp.UserVerification,
+		}
+	}
+	return opts
+}
+
+// RequestOptions holds the options used to authenticate with a credential. It
+// marshals to the PublicKeyCredentialRequestOptionsJSON format, which browsers
+// can consume directly:
+//
+//	const resp = await fetch("/login-start", { method: "POST" });
+//	const opts = PublicKeyCredential.parseRequestOptionsFromJSON(await resp.json());
+//	const cred = await navigator.credentials.get({ publicKey: opts });
+//
+// https://www.w3.org/TR/webauthn-3/#dictdef-publickeycredentialrequestoptionsjson
+type RequestOptions struct {
+	Challenge Base64URL `json:"challenge"`
+	// Timeout is a hint of the time, in milliseconds, the relying party is
+	// willing to wait for the ceremony to complete.
+	Timeout uint32 `json:"timeout,omitempty"`
+	RPID    string `json:"rpId,omitempty"`
+	// AllowCredentials lists the credentials that may be used, such as the
+	// credentials of a user who has already been identified. If empty, the
+	// user is prompted to choose from discoverable credentials.
+	AllowCredentials []CredentialDescriptor `json:"allowCredentials,omitempty"`
+	// UserVerification is "required", "preferred", or "discouraged".
+	UserVerification string                 `json:"userVerification,omitempty"`
+	Hints            []string               `json:"hints,omitempty"`
+	Extensions       *ClientExtensionInputs `json:"extensions,omitempty"`
+}
+
+// NewRequestOptions returns options to authenticate with a credential,
+// populated with the relying party's ID and user verification requirement. If
+// the relying party has an AppID, the "appid" extension is requested so legacy
+// U2F credentials can be used. Callers can set additional fields, such as
+// AllowCredentials, before sending the options to the browser.
+//
+// The challenge should be at least 16 random bytes, and stored by the relying
+// party to pass to [RelyingParty.VerifyAssertion].
+func (rp *RelyingParty) NewRequestOptions(challenge []byte) *RequestOptions {
+	opts := &RequestOptions{
+		Challenge:        challenge,
+		RPID:             rp.ID,
+		UserVerification: rp.UserVerification,
+	}
+	if rp.AppID != "" {
+		opts.Extensions = &ClientExtensionInputs{AppID: rp.AppID}
+	}
+	return opts
+}
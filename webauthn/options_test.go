@@ -0,0 +1,137 @@
+package webauthn
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestCreationOptions(t *testing.T) {
+	rp := &RelyingParty{
+		ID:     "example.com",
+		Name:   "Example",
+		Origin: "https://example.com",
+	}
+	opts := rp.NewCreationOptions([]byte("challenge"), UserEntity{
+		ID:          []byte("user"),
+		Name:        "alice@example.com",
+		DisplayName: "Alice",
+	})
+	opts.Timeout = 60000
+	opts.ExcludeCredentials = []CredentialDescriptor{
+		{Type: PublicKeyCredentialType, ID: []byte("credential"), Transports: []string{"internal", "hybrid"}},
+	}
+	opts.AuthenticatorSelection = &AuthenticatorSelection{
+		ResidentKey:        ResidentKeyRequired,
+		RequireResidentKey: true,
+		UserVerification:   "required",
+	}
+	opts.Hints = []string{HintClientDevice}
+	opts.Attestation = AttestationDirect
+	opts.AttestationFormats = []string{FormatPacked}
+	opts.Extensions = &ClientExtensionInputs{CredProps: true}
+
+	got, err := json.Marshal(opts)
+	if err != nil {
+		t.Fatalf("Marshaling options: %v", err)
+	}
+	want := `{
+		"rp": {"id": "example.com", "name": "Example"},
+		"user": {"id": "dXNlcg", "name": "alice@example.com", "displayName": "Alice"},
+		"challenge": "Y2hhbGxlbmdl",
+		"pubKeyCredParams": [
+			{"type": "public-key", "alg": -7},
+			{"type": "public-key", "alg": -8},
+			{"type": "public-key", "alg": -35},
+			{"type": "public-key", "alg": -36},
+			{"type": "public-key", "alg": -257},
+			{"type": "public-key", "alg": -258},
+			{"type": "public-key", "alg": -259}
+		],
+		"timeout": 60000,
+		"excludeCredentials": [
+			{"type": "public-key", "id": "Y3JlZGVudGlhbA", "transports": ["internal", "hybrid"]}
+		],
+		"authenticatorSelection": {
+			"residentKey": "required",
+			"requireResidentKey": true,
+			"userVerification": "required"
+		},
+		"hints": ["client-device"],
+		"attestation": "direct",
+		"attestationFormats": ["packed"],
+		"extensions": {"credProps": true}
+	}`
+	wantCompact := &bytes.Buffer{}
+	if err := json.Compact(wantCompact, []byte(want)); err != nil {
+		t.Fatalf("Compacting expected value: %v", err)
+	}
+	if !bytes.Equal(got, wantCompact.Bytes()) {
+		t.Errorf("Unexpected options\ngot:  %s\nwant: %s", got, wantCompact)
+	}
+}
+
+func TestRequestOptions(t *testing.T) {
+	testCases := []struct {
+		name string
+		rp   *RelyingParty
+		want string
+	}{
+		{
+			name: "Options",
+			rp:   &RelyingParty{ID: "example.com", Origin: "https://example.com"},
+			want: `{
+				"challenge": "Y2hhbGxlbmdl",
+				"timeout": 60000,
+				"rpId": "example.com",
+				"allowCredentials": [
+					{"type": "public-key", "id": "Y3JlZGVudGlhbA", "transports": ["usb"]}
+				],
+				"userVerification": "required",
+				"hints": ["security-key"]
+			}`,
+		},
+		{
+			name: "AppID",
+			rp: &RelyingParty{
+				ID:     "example.com",
+				Origin: "https://example.com",
+				AppID:  "https://example.com/app-id.json",
+			},
+			want: `{
+				"challenge": "Y2hhbGxlbmdl",
+				"timeout": 60000,
+				"rpId": "example.com",
+				"allowCredentials": [
+					{"type": "public-key", "id": "Y3JlZGVudGlhbA", "transports": ["usb"]}
+				],
+				"userVerification": "required",
+				"hints": ["security-key"],
+				"extensions": {"appid": "https://example.com/app-id.json"}
+			}`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			opts := tc.rp.NewRequestOptions([]byte("challenge"))
+			opts.Timeout = 60000
+			opts.AllowCredentials = []CredentialDescriptor{
+				{Type: PublicKeyCredentialType, ID: []byte("credential"), Transports: []string{"usb"}},
+			}
+			opts.UserVerification = "required"
+			opts.Hints = []string{HintSecurityKey}
+
+			got, err := json.Marshal(opts)
+			if err != nil {
+				t.Fatalf("Marshaling options: %v", err)
+			}
+			want := &bytes.Buffer{}
+			if err := json.Compact(want, []byte(tc.want)); err != nil {
+				t.Fatalf("Compacting expected value: %v", err)
+			}
+			if !bytes.Equal(got, want.Bytes()) {
+				t.Errorf("Unexpected options\ngot:  %s\nwant: %s", got, want)
+			}
+		})
+	}
+}
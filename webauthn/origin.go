@@ -0,0 +1,90 @@
+package webauthn
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// androidOriginPrefix is the scheme of origins reported by Android apps using
+// the platform credential APIs.
+//
+// https://developer.android.com/identity/sign-in/credential-manager#verify-origin
+const androidOriginPrefix = "android:apk-key-hash:"
+
+// AndroidOrigin returns the origin reported by an Android app signed with a
+// given certificate, for use in [RelyingParty.Origins]. The fingerprint is the
+// SHA-256 digest of the app's signing certificate, hex encoded with optional
+// colons, as printed by "keytool -list" or the Play Console:
+//
+//	origin, err := webauthn.AndroidOrigin("FA:C6:17:45:DC:09:03:78:6F:B9:ED:E6:2A:96:2B:39:9F:73:48:F0:BB:6F:89:9B:83:32:66:75:91:03:3B:9C")
+//	// origin == "android:apk-key-hash:-sYXRdwJA3hvue3mKpYrOZ9zSPC7b4mbgzJmdZEDO5w"
+//
+// https://developer.android.com/identity/sign-in/credential-manager#verify-origin
+func AndroidOrigin(fingerprint string) (string, error) {
+	digest, err := hex.DecodeString(strings.ReplaceAll(fingerprint, ":", ""))
+	if err != nil {
+		return "", fmt.Errorf("parsing certificate fingerprint: %v", err)
+	}
+	if len(digest) != sha256.Size {
+		return "", fmt.Errorf("certificate fingerprint is not a SHA-256 digest, got %d bytes", len(digest))
+	}
+	return androidOriginPrefix + base64.RawURLEncoding.EncodeToString(digest), nil
+}
+
+// verifyOrigin checks the origin of a ceremony against the relying party's
+// configured origins.
+//
+// https://www.w3.org/TR/webauthn-3/#sctn-validating-origin
+func (rp *RelyingParty) verifyOrigin(origin string) error {
+	if origin == rp.Origin || slices.Contains(rp.Origins, origin) || slices.Contains(rp.RelatedOrigins, origin) {
+		return nil
+	}
+	if rp.AllowSubdomains && rp.subdomainOrigin(origin) {
+		return nil
+	}
+	return fmt.Errorf("invalid client data origin '%s'", origin)
+}
+
+// subdomainOrigin reports whether origin is an HTTPS origin whose host is the
+// relying party ID or a subdomain of it.
+func (rp *RelyingParty) subdomainOrigin(origin string) bool {
+	if rp.ID == "" {
+		return false
+	}
+	u, err := url.Parse(origin)
+	if err != nil || u.Scheme != "https" || u.Opaque != "" || u.User != nil ||
+		u.Path != "" || u.RawQuery != "" || u.Fragment != "" {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	id := strings.ToLower(rp.ID)
+	return host == id || strings.HasSuffix(host, "."+id)
+}
+
+// verifyCrossOrigin checks whether a ceremony performed in a cross-origin
+// iframe is permitted by the relying party's configuration.
+//
+// https://www.w3.org/TR/webauthn-3/#sctn-iframe-guidance
+func (rp *RelyingParty) verifyCrossOrigin(cd *clientData) error {
+	if !cd.CrossOrigin {
+		return nil
+	}
+	if !rp.AllowCrossOrigin {
+		return fmt.Errorf("cross-origin ceremony not allowed")
+	}
+	if len(rp.TopOrigins) == 0 {
+		return nil
+	}
+	if cd.TopOrigin == "" {
+		return fmt.Errorf("cross-origin ceremony didn't provide a top origin")
+	}
+	if !slices.Contains(rp.TopOrigins, cd.TopOrigin) {
+		return fmt.Errorf("invalid client data top origin '%s'", cd.TopOrigin)
+	}
+	return nil
+}
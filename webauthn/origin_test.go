@@ -0,0 +1,259 @@
+package webauthn
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestAndroidOrigin(t *testing.T) {
+	testCases := []struct {
+		name        string
+		fingerprint string
+		want        string
+		wantErr     string
+	}{
+		{
+			name:        "Colons",
+			fingerprint: "FA:C6:17:45:DC:09:03:78:6F:B9:ED:E6:2A:96:2B:39:9F:73:48:F0:BB:6F:89:9B:83:32:66:75:91:03:3B:9C",
+			want:        "android:apk-key-hash:-sYXRdwJA3hvue3mKpYrOZ9zSPC7b4mbgzJmdZEDO5w",
+		},
+		{
+			name:        "No colons",
+			fingerprint: "fac61745dc0903786fb9ede62a962b399f7348f0bb6f899b8332667591033b9c",
+			want:        "android:apk-key-hash:-sYXRdwJA3hvue3mKpYrOZ9zSPC7b4mbgzJmdZEDO5w",
+		},
+		{
+			name:        "SHA-1 fingerprint",
+			fingerprint: "DA:39:A3:EE:5E:6B:4B:0D:32:55:BF:EF:95:60:18:90:AF:D8:07:09",
+			wantErr:     "not a SHA-256 digest",
+		},
+		{
+			name:        "Invalid hex",
+			fingerprint: "not a fingerprint",
+			wantErr:     "parsing certificate fingerprint",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := AndroidOrigin(tc.fingerprint)
+			if tc.wantErr != "" {
+				if err == nil {
+					t.Fatalf("Expected error computing origin")
+				}
+				if !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("Computing origin returned unexpected error, got=%v, want=%s", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Computing origin: %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("Unexpected origin, got=%s, want=%s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestVerifyOrigin(t *testing.T) {
+	const rpID = "example.com"
+	android := "android:apk-key-hash:-sYXRdwJA3hvue3mKpYrOZ9zSPC7b4mbgzJmdZEDO5w"
+	rp := &RelyingParty{
+		ID:      rpID,
+		Origin:  "https://example.com",
+		Origins: []string{"https://www.example.com", android},
+	}
+	subdomains := &RelyingParty{
+		ID:              rpID,
+		Origin:          "https://example.com",
+		AllowSubdomains: true,
+	}
+
+	testCases := []struct {
+		name   string
+		rp     *RelyingParty
+		origin string
+		want   bool
+	}{
+		{name: "Origin", rp: rp, origin: "https://example.com", want: true},
+		{name: "Additional origin", rp: rp, origin: "https://www.example.com", want: true},
+		{name: "Android origin", rp: rp, origin: android, want: true},
+		{name: "Unknown origin", rp: rp, origin: "https://evil.com"},
+		{name: "Subdomain not allowed", rp: rp, origin: "https://app.example.com"},
+		{name: "Subdomain", rp: subdomains, origin: "https://app.example.com", want: true},
+		{name: "Nested subdomain", rp: subdomains, origin: "https://a.b.example.com:8443", want: true},
+		{name: "Subdomain uppercase", rp: subdomains, origin: "https://APP.Example.com", want: true},
+		{name: "Subdomain HTTP", rp: subdomains, origin: "http://app.example.com"},
+		{name: "Subdomain path", rp: subdomains, origin: "https://app.example.com/login"},
+		{name: "Suffix not subdomain", rp: subdomains, origin: "https://badexample.com"},
+		{name: "Subdomain Android", rp: subdomains, origin: android},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.rp.verifyOrigin(tc.origin)
+			if tc.want && err != nil {
+				t.Errorf("Verifying origin %s: %v", tc.origin, err)
+			}
+			if !tc.want && err == nil {
+				t.Errorf("Verifying origin %s succeeded, expected error", tc.origin)
+			}
+		})
+	}
+}
+
+func TestVerifyAssertionOrigins(t *testing.T) {
+	const rpID = "example.com"
+	credKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Generating key: %v", err)
+	}
+	android, err := AndroidOrigin("FA:C6:17:45:DC:09:03:78:6F:B9:ED:E6:2A:96:2B:39:9F:73:48:F0:BB:6F:89:9B:83:32:66:75:91:03:3B:9C")
+	if err != nil {
+		t.Fatalf("Computing Android origin: %v", err)
+	}
+	rp := &RelyingParty{
+		ID:      rpID,
+		Origin:  "https://example.com",
+		Origins: []string{android},
+	}
+
+	challenge := []byte("challenge")
+	for _, origin := range []string{"https://example.com", android} {
+		clientDataJSON := testClientDataJSON("webauthn.get", origin, challenge)
+		authData, sig := testAssertion(t, credKey, rpID, 0x05, nil, clientDataJSON)
+		if _, err := rp.VerifyAssertion(&credKey.PublicKey, ES256, challenge, clientDataJSON, authData, sig); err != nil {
+			t.Errorf("Verifying assertion from %s: %v", origin, err)
+		}
+	}
+
+	clientDataJSON := testClientDataJSON("webauthn.get", "https://www.example.com", challenge)
+	authData, sig := testAssertion(t, credKey, rpID, 0x05, nil, clientDataJSON)
+	_, err = rp.VerifyAssertion(&credKey.PublicKey, ES256, challenge, clientDataJSON, authData, sig)
+	if err == nil || !strings.Contains(err.Error(), "invalid client data origin") {
+		t.Errorf("Verifying assertion from unknown origin, got=%v, want invalid origin error", err)
+	}
+}
+
+func TestVerifyCrossOrigin(t *testing.T) {
+	const (
+		rpID   = "example.com"
+		origin = "https://login.example.com"
+	)
+	credKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Generating key: %v", err)
+	}
+
+	testCases := []struct {
+		name        string
+		rp          *RelyingParty
+		crossOrigin bool
+		topOrigin   string
+		wantErr     string
+	}{
+		{
+			name: "Same origin",
+			rp:   &RelyingParty{ID: rpID, Origin: origin},
+		},
+		{
+			name:        "Cross-origin not allowed",
+			rp:          &RelyingParty{ID: rpID, Origin: origin},
+			crossOrigin: true,
+			topOrigin:   "https://partner.example",
+			wantErr:     "cross-origin ceremony not allowed",
+		},
+		{
+			name:        "Cross-origin allowed",
+			rp:          &RelyingParty{ID: rpID, Origin: origin, AllowCrossOrigin: true},
+			crossOrigin: true,
+			topOrigin:   "https://partner.example",
+		},
+		{
+			name: "Top origin allowed",
+			rp: &RelyingParty{
+				ID:               rpID,
+				Origin:           origin,
+				AllowCrossOrigin: true,
+				TopOrigins:       []string{"https://partner.example"},
+			},
+			crossOrigin: true,
+			topOrigin:   "https://partner.example",
+		},
+		{
+			name: "Top origin not allowed",
+			rp: &RelyingParty{
+				ID:               rpID,
+				Origin:           origin,
+				AllowCrossOrigin: true,
+				TopOrigins:       []string{"https://partner.example"},
+			},
+			crossOrigin: true,
+			topOrigin:   "https://evil.example",
+			wantErr:     "invalid client data top origin",
+		},
+		{
+			name: "Missing top origin",
+			rp: &RelyingParty{
+				ID:               rpID,
+				Origin:           origin,
+				AllowCrossOrigin: true,
+				TopOrigins:       []string{"https://partner.example"},
+			},
+			crossOrigin: true,
+			wantErr:     "didn't provide a top origin",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			clientDataJSON := func(typ string, challenge []byte) []byte {
+				cd := `{"type":"` + typ + `","challenge":"` + base64.RawURLEncoding.EncodeToString(challenge) +
+					`","origin":"` + origin + `","crossOrigin":` + strconv.FormatBool(tc.crossOrigin)
+				if tc.topOrigin != "" {
+					cd += `,"topOrigin":"` + tc.topOrigin + `"`
+				}
+				return []byte(cd + "}")
+			}
+			checkErr := func(t *testing.T, err error) bool {
+				t.Helper()
+				if tc.wantErr != "" {
+					if err == nil {
+						t.Fatalf("Expected error")
+					}
+					if !strings.Contains(err.Error(), tc.wantErr) {
+						t.Fatalf("Unexpected error, got=%v, want=%s", err, tc.wantErr)
+					}
+					return false
+				}
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				return true
+			}
+
+			challenge := []byte("registration")
+			cd := clientDataJSON("webauthn.create", challenge)
+			authData := testAuthData(t, rpID, 0x05, AAGUID{}, []byte("credential"), &credKey.PublicKey, ES256)
+			att, err := tc.rp.VerifyAttestation(challenge, cd, testAttestationObject(FormatNone, cborMap(), authData))
+			if checkErr(t, err) {
+				if att.CrossOrigin != tc.crossOrigin || att.TopOrigin != tc.topOrigin {
+					t.Errorf("Unexpected attestation origin, got crossOrigin=%t topOrigin=%q", att.CrossOrigin, att.TopOrigin)
+				}
+			}
+
+			challenge = []byte("assertion")
+			cd = clientDataJSON("webauthn.get", challenge)
+			authData, sig := testAssertion(t, credKey, rpID, 0x05, nil, cd)
+			a, err := tc.rp.VerifyAssertion(&credKey.PublicKey, ES256, challenge, cd, authData, sig)
+			if checkErr(t, err) {
+				if a.CrossOrigin != tc.crossOrigin || a.TopOrigin != tc.topOrigin {
+					t.Errorf("Unexpected assertion origin, got crossOrigin=%t topOrigin=%q", a.CrossOrigin, a.TopOrigin)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package webauthn_test
+
+import (
+	"crypto/x509"
+	"encoding/base64"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/go-passkeys/go-passkeys/webauthn"
+	"github.com/go-passkeys/go-passkeys/webauthn/metadata"
+)
+
+// TestVerifyAttestationPacked is an external test so it can use the metadata
+// package, which imports webauthn, to fetch attestation roots.
+func TestVerifyAttestationPacked(t *testing.T) {
+	testCases := []struct {
+		name              string
+		rp                *webauthn.RelyingParty
+		challenge         string
+		clientData        string
+		attestationObject string
+	}{
+		{
+			name: "YubiKey 5 Series",
+			rp: &webauthn.RelyingParty{
+				ID:     "localhost",
+				Origin: "http://localhost:8080",
+			},
+			challenge:         "-ium4NdjLD6Acqy9p66NtA",
+			clientData:        `{"type":"webauthn.create","challenge":"-ium4NdjLD6Acqy9p66NtA","origin":"http://localhost:8080","crossOrigin":false}`,
+			attestationObject: "o2NmbXRmcGFja2VkZ2F0dFN0bXSjY2FsZyZjc2lnWEgwRgIhAL7ex0WTU1ZpLSRhoTxNxaYbwYcaNEA/h9eJEp0weJEqAiEA1vMTwi4bkvkE/gzQDO1seRyw0SupYth902MWOpZ0TDpjeDVjgVkC3TCCAtkwggHBoAMCAQICCQCkQGRCP4Vr/DANBgkqhkiG9w0BAQsFADAuMSwwKgYDVQQDEyNZdWJpY28gVTJGIFJvb3QgQ0EgU2VyaWFsIDQ1NzIwMDYzMTAgFw0xNDA4MDEwMDAwMDBaGA8yMDUwMDkwNDAwMDAwMFowbzELMAkGA1UEBhMCU0UxEjAQBgNVBAoMCVl1YmljbyBBQjEiMCAGA1UECwwZQXV0aGVudGljYXRvciBBdHRlc3RhdGlvbjEoMCYGA1UEAwwfWXViaWNvIFUyRiBFRSBTZXJpYWwgMTExMzg2NjQwNDBZMBMGByqGSM49AgEGCCqGSM49AwEHA0IABPkOtta+hbyNLleVf1puWkTqbHzBJz+y42wVbN881zPGfYHty7riyxT4c3fcoXK+bl1/XE7f/2D3I3WT9ILQVYOjgYEwfzATBgorBgEEAYLECg0BBAUEAwUHATAiBgkrBgEEAYLECgIEFTEuMy42LjEuNC4xLjQxNDgyLjEuNzATBgsrBgEEAYLlHAIBAQQEAwIFIDAhBgsrBgEEAYLlHAEBBAQSBBAZCDw9g4NLGLwDjxyasv0bMAwGA1UdEwEB/wQCMAAwDQYJKoZIhvcNAQELBQADggEBAHzCOWZTA+e+ni1+kmfydBAZgdLyWGbYLQxlJtjd00qbh6M41UaYuRm12eKm3uYDgPT1BnVqqGN69k/1+P91O+knuRBfb48El12Up1hfzyON1UKGgBA6IdmghqYbK+X5baMMLGdsZ1nLKEWjVRecjLg79GwHy9HJ25j+Gb7+yNZMJdfgMJvfrecD35Tgmw+3fTCbzpnlW9Sp/LNdkHjdECaicue3MdhtrwaVmNfyVNvU5mqHzQAH2zf4/TsTZKdx2aIDFmqZZAartwD7RskFfQpnN0CWU6uCaBS0ECgDPLLW3q39mfvJ/y2rHPhaSWue85+2lNK+NJPP43ZsNrA7Rw5oYXV0aERhdGFYwkmWDeWIDoxodDQXD2R2YFuP5K65ooYyx5lc87qDHZdjxQAAAAMZCDw9g4NLGLwDjxyasv0bADDC4gNtuVFFZvyU4A2YDTFDSAOHTXQfTVUeXPpK2xTdoFx6LnSx3o2dcheLtBrEj0ylAQIDJiABIVggwuIDbblRRWb8lOANmAK3w9dppoKQXC2rw7yY6c9W/C4iWCBp5XU3NpH55RWYheccEtji/4Yc+zscmwMQN+KrQ/o7/qFrY3JlZFByb3RlY3QD",
+		},
+		{
+			name: "Chrome local",
+			rp: &webauthn.RelyingParty{
+				ID:     "localhost",
+				Origin: "http://localhost:8080",
+			},
+			challenge:         "8XJI5cQqW-VqtSPO7JIpUg",
+			clientData:        `{"type":"webauthn.create","challenge":"8XJI5cQqW-VqtSPO7JIpUg","origin":"http://localhost:8080","crossOrigin":false}`,
+			attestationObject: "o2NmbXRmcGFja2VkZ2F0dFN0bXSiY2FsZyZjc2lnWEcwRQIhAJdhPjKXQAoWBgBDw+tu8q2WpTrXLULwFBgpJGu0SLI7AiA493f+tIVJkf9oeSX24FsSHJqkNKYmph2IAD7wSzTMAGhhdXRoRGF0YVikSZYN5YgOjGh0NBcPZHZgW4/krrmihjLHmVzzuoMdl2NFAAAAAK3OAAI1vMYKZIsLJfHwVQMAIGfNA5n4RSq0gsGzIB6kmazzLLe0goRP+1QG4uixw+zTpQECAyYgASFYIJtUv3C9FxTn1i7xALbGQJjzDkyFECHaHQ5+KYom9eh9IlggCfXDLnVZU9KEKuhqdPInGHcfAlZSCTOeRWSUzrSkkHo=",
+		},
+	}
+
+	rootPEM, err := os.ReadFile("metadata/testdata/globalsign_root_ca_r3.pem")
+	if err != nil {
+		t.Fatalf("loading metadata root: %v", err)
+	}
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(rootPEM) {
+		t.Fatalf("parsing metadata root failed")
+	}
+	blob, err := os.Open("testdata/blob.jwt")
+	if err != nil {
+		t.Fatalf("loading metadata blob: %v", err)
+	}
+	defer blob.Close()
+	md, err := metadata.Parse(blob, &metadata.VerifyOptions{
+		Roots: roots,
+		// Time at which the test BLOB was valid.
+		CurrentTime: time.Date(2024, time.November, 1, 0, 0, 0, 0, time.UTC),
+	})
+	if err != nil {
+		t.Fatalf("parsing metadata blob: %v", err)
+	}
+	opts := &webauthn.PackedOptions{
+		GetRoots:          (&metadata.RootProvider{Payload: md}).GetRoots,
+		AllowSelfAttested: true,
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			challenge, err := base64.RawURLEncoding.DecodeString(tc.challenge)
+			if err != nil {
+				t.Fatalf("Parsing challenge: %v", err)
+			}
+			attestationObject, err := base64.StdEncoding.DecodeString(tc.attestationObject)
+			if err != nil {
+				t.Fatalf("Parsing attestation object: %v", err)
+			}
+			clientDataJSON := []byte(tc.clientData)
+			if _, err := tc.rp.VerifyAttestationPacked(challenge, clientDataJSON, attestationObject, opts); err != nil {
+				t.Errorf("Verifying attestation: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,395 @@
+package webauthn
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+// AuthenticatorStatus is the status of an authenticator model as reported by
+// the FIDO Alliance Metadata Service.
+//
+// https://fidoalliance.org/specs/mds/fido-metadata-service-v3.0-ps-20210518.html#authenticatorstatus-enum
+type AuthenticatorStatus string
+
+// Authenticator statuses defined by the FIDO Alliance Metadata Service.
+const (
+	StatusNotFIDOCertified          AuthenticatorStatus = "NOT_FIDO_CERTIFIED"
+	StatusFIDOCertified             AuthenticatorStatus = "FIDO_CERTIFIED"
+	StatusUserVerificationBypass    AuthenticatorStatus = "USER_VERIFICATION_BYPASS"
+	StatusAttestationKeyCompromise  AuthenticatorStatus = "ATTESTATION_KEY_COMPROMISE"
+	StatusUserKeyRemoteCompromise   AuthenticatorStatus = "USER_KEY_REMOTE_COMPROMISE"
+	StatusUserKeyPhysicalCompromise AuthenticatorStatus = "USER_KEY_PHYSICAL_COMPROMISE"
+	StatusUpdateAvailable           AuthenticatorStatus = "UPDATE_AVAILABLE"
+	StatusRevoked                   AuthenticatorStatus = "REVOKED"
+	StatusSelfAssertionSubmitted    AuthenticatorStatus = "SELF_ASSERTION_SUBMITTED"
+	StatusFIDOCertifiedL1           AuthenticatorStatus = "FIDO_CERTIFIED_L1"
+	StatusFIDOCertifiedL1Plus       AuthenticatorStatus = "FIDO_CERTIFIED_L1plus"
+	StatusFIDOCertifiedL2           AuthenticatorStatus = "FIDO_CERTIFIED_L2"
+	StatusFIDOCertifiedL2Plus       AuthenticatorStatus = "FIDO_CERTIFIED_L2plus"
+	StatusFIDOCertifiedL3           AuthenticatorStatus = "FIDO_CERTIFIED_L3"
+	StatusFIDOCertifiedL3Plus       AuthenticatorStatus = "FIDO_CERTIFIED_L3plus"
+)
+
+// StatusReport records a status change of an authenticator model.
+//
+// https://fidoalliance.org/specs/mds/fido-metadata-service-v3.0-ps-20210518.html#statusreport-dictionary
+type StatusReport struct {
+	Status AuthenticatorStatus
+	// EffectiveDate is the date the status was set. A zero value indicates
+	// the report doesn't carry a date.
+	EffectiveDate time.Time
+}
+
+// FlaggedStatusReport returns the most recent report that is effective at the
+// provided time and has one of the provided statuses, or nil if there is none.
+// Reports dated after now are ignored.
+//
+// Every effective report is considered, not only the latest one. A later
+// report, such as UPDATE_AVAILABLE or a new certification, doesn't clear an
+// earlier ATTESTATION_KEY_COMPROMISE or USER_VERIFICATION_BYPASS, since
+// authenticators already in use remain affected. [AttestationPolicy],
+// [StatusPolicy], and the metadata package's RootProvider all use this rule.
+func FlaggedStatusReport(reports []StatusReport, statuses []AuthenticatorStatus, now time.Time) *StatusReport {
+	var flagged *StatusReport
+	for i, r := range reports {
+		if !r.EffectiveDate.IsZero() && r.EffectiveDate.After(now) {
+			continue
+		}
+		if !slices.Contains(statuses, r.Status) {
+			continue
+		}
+		if flagged == nil || !r.EffectiveDate.Before(flagged.EffectiveDate) {
+			flagged = &reports[i]
+		}
+	}
+	return flagged
+}
+
+// CertificationLevel is a FIDO Authenticator Certification Level.
+//
+// https://fidoalliance.org/certification/authenticator-certification-levels/
+type CertificationLevel int
+
+// Certification levels, ordered from weakest to strongest.
+const (
+	CertificationLevelNone CertificationLevel = iota
+	CertificationLevelL1
+	CertificationLevelL1Plus
+	CertificationLevelL2
+	CertificationLevelL2Plus
+	CertificationLevelL3
+	CertificationLevelL3Plus
+)
+
+var certificationLevelStrings = map[CertificationLevel]string{
+	CertificationLevelNone:   "None",
+	CertificationLevelL1:     "L1",
+	CertificationLevelL1Plus: "L1+",
+	CertificationLevelL2:     "L2",
+	CertificationLevelL2Plus: "L2+",
+	CertificationLevelL3:     "L3",
+	CertificationLevelL3Plus: "L3+",
+}
+
+// String returns a human readable representation of the certification level.
+func (l CertificationLevel) String() string {
+	if s, ok := certificationLevelStrings[l]; ok {
+		return s
+	}
+	return fmt.Sprintf("CertificationLevel(%d)", int(l))
+}
+
+// statusCertificationLevels maps certification statuses to levels. The
+// deprecated "FIDO_CERTIFIED" status is equivalent to L1.
+var statusCertificationLevels = map[AuthenticatorStatus]CertificationLevel{
+	StatusFIDOCertified:       CertificationLevelL1,
+	StatusFIDOCertifiedL1:     CertificationLevelL1,
+	StatusFIDOCertifiedL1Plus: CertificationLevelL1Plus,
+	StatusFIDOCertifiedL2:     CertificationLevelL2,
+	StatusFIDOCertifiedL2Plus: CertificationLevelL2Plus,
+	StatusFIDOCertifiedL3:     CertificationLevelL3,
+	StatusFIDOCertifiedL3Plus: CertificationLevelL3Plus,
+}
+
+// defaultDeniedStatuses are the statuses rejected by an [AttestationPolicy]
+// that doesn't configure DeniedStatuses.
+var defaultDeniedStatuses = []AuthenticatorStatus{
+	StatusUserVerificationBypass,
+	StatusAttestationKeyCompromise,
+	StatusUserKeyRemoteCompromise,
+	StatusUserKeyPhysicalCompromise,
+	StatusRevoked,
+}
+
+// PolicyReason identifies the rule of an [AttestationPolicy] that rejected a
+// registration.
+type PolicyReason int
+
+// Reasons a registration can be rejected by an [AttestationPolicy].
+const (
+	PolicyReasonFormat PolicyReason = iota + 1
+	PolicyReasonAttestationType
+	PolicyReasonAAGUIDNotAllowed
+	PolicyReasonAAGUIDDenied
+	PolicyReasonCertificationLevel
+	PolicyReasonStatus
+)
+
+var policyReasonStrings = map[PolicyReason]string{
+	PolicyReasonFormat:             "Format",
+	PolicyReasonAttestationType:    "AttestationType",
+	PolicyReasonAAGUIDNotAllowed:   "AAGUIDNotAllowed",
+	PolicyReasonAAGUIDDenied:       "AAGUIDDenied",
+	PolicyReasonCertificationLevel: "CertificationLevel",
+	PolicyReasonStatus:             "Status",
+}
+
+// String returns a human readable representation of the reason.
+func (r PolicyReason) String() string {
+	if s, ok := policyReasonStrings[r]; ok {
+		return s
+	}
+	return fmt.Sprintf("PolicyReason(%d)", int(r))
+}
+
+// PolicyError is returned when an [AttestationPolicy] rejects a registration.
+// Use errors.As to inspect the reason.
+type PolicyError struct {
+	// Reason identifies the rule that rejected the registration.
+	Reason PolicyReason
+	// Detail is a human readable description of the rejection.
+	Detail string
+}
+
+func (e *PolicyError) Error() string {
+	return fmt.Sprintf("rejected by attestation policy (%s): %s", e.Reason, e.Detail)
+}
+
+// AttestationPolicy determines which attested credentials a relying party is
+// willing to accept. Empty fields aren't enforced, so the zero value accepts
+// every registration.
+//
+// Note that the AAGUID of a credential is only vouched for by Basic, AttCA,
+// and AnonCA attestation. Policies using AAGUID lists or metadata should
+// generally also restrict Types.
+type AttestationPolicy struct {
+	// Formats lists the attestation statement formats that are accepted, such
+	// as "packed" or "none".
+	Formats []string
+
+	// Types lists the attestation types that are accepted. For example,
+	// excluding AttestationTypeSelf rejects self attested credentials.
+	Types []AttestationType
+
+	// AllowedAAGUIDs, when set, restricts registrations to these authenticator
+	// models.
+	AllowedAAGUIDs []AAGUID
+
+	// DeniedAAGUIDs lists authenticator models that are rejected.
+	DeniedAAGUIDs []AAGUID
+
+	// MinCertificationLevel is the minimum FIDO certification level of the
+	// authenticator, the highest level among its effective status reports.
+	// Requires GetStatusReports.
+	MinCertificationLevel CertificationLevel
+
+	// GetStatusReports returns the status reports for an authenticator model.
+	// For example, from the FIDO Alliance Metadata Service. When unset,
+	// status is not evaluated.
+	//
+	// https://fidoalliance.org/metadata/
+	GetStatusReports func(aaguid AAGUID) ([]StatusReport, error)
+
+	// DeniedStatuses lists statuses that cause an authenticator to be
+	// rejected if any of its effective status reports has one of them. See
+	// [FlaggedStatusReport]. If nil, authenticators reported as revoked,
+	// compromised, or bypassing user verification are rejected.
+	DeniedStatuses []AuthenticatorStatus
+
+	// Now returns the current time, used to ignore status reports that are not
+	// yet effective. If unset, time.Now is used.
+	Now func() time.Time
+}
+
+// Evaluate determines if a registration is permitted by the policy. If the
+// policy rejects the registration, the returned error is a [*PolicyError].
+func (p *AttestationPolicy) Evaluate(reg *Registration) error {
+	if len(p.Formats) > 0 && !slices.Contains(p.Formats, reg.Format) {
+		return &PolicyError{
+			Reason: PolicyReasonFormat,
+			Detail: fmt.Sprintf("attestation format %q not allowed", reg.Format),
+		}
+	}
+	if len(p.Types) > 0 && !slices.Contains(p.Types, reg.Type) {
+		return &PolicyError{
+			Reason: PolicyReasonAttestationType,
+			Detail: fmt.Sprintf("attestation type %s not allowed", reg.Type),
+		}
+	}
+
+	aaguid := reg.Attestation.AAGUID
+	if len(p.AllowedAAGUIDs) > 0 && !slices.Contains(p.AllowedAAGUIDs, aaguid) {
+		return &PolicyError{
+			Reason: PolicyReasonAAGUIDNotAllowed,
+			Detail: fmt.Sprintf("authenticator %s not in allow list", aaguid),
+		}
+	}
+	if slices.Contains(p.DeniedAAGUIDs, aaguid) {
+		return &PolicyError{
+			Reason: PolicyReasonAAGUIDDenied,
+			Detail: fmt.Sprintf("authenticator %s is denied", aaguid),
+		}
+	}
+
+	if p.GetStatusReports == nil {
+		if p.MinCertificationLevel > CertificationLevelNone {
+			return fmt.Errorf("minimum certification level requires status reports to be configured")
+		}
+		return nil
+	}
+	reports, err := p.GetStatusReports(aaguid)
+	if err != nil {
+		return fmt.Errorf("fetching status reports for %s: %v", aaguid, err)
+	}
+
+	now := time.Now()
+	if p.Now != nil {
+		now = p.Now()
+	}
+	denied := p.DeniedStatuses
+	if denied == nil {
+		denied = defaultDeniedStatuses
+	}
+
+	if r := FlaggedStatusReport(reports, denied, now); r != nil {
+		return &PolicyError{
+			Reason: PolicyReasonStatus,
+			Detail: fmt.Sprintf("authenticator %s has status %s", aaguid, r.Status),
+		}
+	}
+
+	level := CertificationLevelNone
+	for _, r := range reports {
+		if !r.EffectiveDate.IsZero() && r.EffectiveDate.After(now) {
+			continue
+		}
+		if l, ok := statusCertificationLevels[r.Status]; ok && l > level {
+			level = l
+		}
+	}
+	if level < p.MinCertificationLevel {
+		return &PolicyError{
+			Reason: PolicyReasonCertificationLevel,
+			Detail: fmt.Sprintf("authenticator %s certification level %s below minimum %s", aaguid, level, p.MinCertificationLevel),
+		}
+	}
+	return nil
+}
+
+// defaultFlaggedStatuses are the statuses reported by a [StatusPolicy] that
+// doesn't configure Statuses.
+var defaultFlaggedStatuses = []AuthenticatorStatus{
+	StatusAttestationKeyCompromise,
+	StatusUserVerificationBypass,
+}
+
+// StatusError is returned when a [StatusPolicy] rejects an authenticator model
+// because of a status reported after the credential was registered. Use
+// errors.As to inspect the report.
+type StatusError struct {
+	AAGUID AAGUID
+	// Report is the status report that caused the rejection.
+	Report StatusReport
+}
+
+func (e *StatusError) Error() string {
+	if e.Report.EffectiveDate.IsZero() {
+		return fmt.Sprintf("authenticator %s has status %s", e.AAGUID, e.Report.Status)
+	}
+	return fmt.Sprintf("authenticator %s has status %s effective %s", e.AAGUID, e.Report.Status, e.Report.EffectiveDate.Format(time.DateOnly))
+}
+
+// StatusPolicy checks the current status of the authenticator model of an
+// existing credential. Unlike [AttestationPolicy], which is evaluated once at
+// registration, a StatusPolicy is intended to be checked on every
+// authentication, so that credentials can be flagged for re-enrollment when
+// their model is later found to be compromised.
+//
+// The AAGUID should be the value stored with the credential at registration,
+// since assertions don't carry one:
+//
+//	a, err := rp.VerifyAssertion(pub, alg, challenge, clientDataJSON, authData, sig)
+//	if err != nil {
+//		// ...
+//	}
+//	report, err := rp.VerifyAuthenticatorStatus(cred.AAGUID)
+//	if err != nil {
+//		// Assertion rejected.
+//	}
+//	if report != nil {
+//		// Assertion allowed, but the user should re-enroll.
+//	}
+type StatusPolicy struct {
+	// GetStatusReports returns the status reports for an authenticator model.
+	// For example, from the FIDO Alliance Metadata Service. Required.
+	//
+	// https://fidoalliance.org/metadata/
+	GetStatusReports func(aaguid AAGUID) ([]StatusReport, error)
+
+	// Statuses lists statuses that cause an authenticator model to be flagged
+	// if any of its effective status reports has one of them. See
+	// [FlaggedStatusReport]. If nil, ATTESTATION_KEY_COMPROMISE and
+	// USER_VERIFICATION_BYPASS are flagged.
+	Statuses []AuthenticatorStatus
+
+	// Reject causes flagged authenticators to return a [*StatusError] rather
+	// than only reporting the status.
+	Reject bool
+
+	// Now returns the current time, used to ignore status reports that are not
+	// yet effective. If unset, time.Now is used.
+	Now func() time.Time
+}
+
+// Check returns the most recent effective status report of the authenticator
+// model that matches one of the policy's statuses, or nil if there is none. If
+// the policy rejects flagged authenticators, the report is returned as a
+// [*StatusError] instead.
+func (p *StatusPolicy) Check(aaguid AAGUID) (*StatusReport, error) {
+	if p.GetStatusReports == nil {
+		return nil, fmt.Errorf("status policy doesn't configure status reports")
+	}
+	reports, err := p.GetStatusReports(aaguid)
+	if err != nil {
+		return nil, fmt.Errorf("fetching status reports for %s: %v", aaguid, err)
+	}
+
+	now := time.Now()
+	if p.Now != nil {
+		now = p.Now()
+	}
+	statuses := p.Statuses
+	if statuses == nil {
+		statuses = defaultFlaggedStatuses
+	}
+
+	flagged := FlaggedStatusReport(reports, statuses, now)
+	if flagged == nil {
+		return nil, nil
+	}
+	if p.Reject {
+		return nil, &StatusError{AAGUID: aaguid, Report: *flagged}
+	}
+	return flagged, nil
+}
+
+// VerifyAuthenticatorStatus checks the status of a credential's authenticator
+// model using the relying party's StatusPolicy. It returns nil if no policy is
+// configured. See [StatusPolicy.Check].
+func (rp *RelyingParty) VerifyAuthenticatorStatus(aaguid AAGUID) (*StatusReport, error) {
+	if rp.StatusPolicy == nil {
+		return nil, nil
+	}
+	return rp.StatusPolicy.Check(aaguid)
+}
@@ -0,0 +1,406 @@
+package webauthn
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestAttestationPolicy(t *testing.T) {
+	aaguid := mustParseAAGUID("ee882879-721c-4913-9775-3dfcce97072a")
+	other := mustParseAAGUID("08987058-cadc-4b81-b6e1-30de50dcbe96")
+	now := time.Date(2024, time.June, 1, 0, 0, 0, 0, time.UTC)
+
+	statusReports := func(reports ...StatusReport) func(AAGUID) ([]StatusReport, error) {
+		return func(got AAGUID) ([]StatusReport, error) {
+			if got != aaguid {
+				t.Errorf("GetStatusReports called with unexpected aaguid, got=%s, want=%s", got, aaguid)
+			}
+			return reports, nil
+		}
+	}
+
+	testCases := []struct {
+		name       string
+		policy     AttestationPolicy
+		format     string
+		typ        AttestationType
+		wantReason PolicyReason
+		wantErr    bool
+	}{
+		{
+			name:   "Zero value",
+			format: FormatNone,
+			typ:    AttestationTypeNone,
+		},
+		{
+			name:   "Allowed format",
+			policy: AttestationPolicy{Formats: []string{FormatPacked, FormatTPM}},
+			format: FormatPacked,
+			typ:    AttestationTypeBasic,
+		},
+		{
+			name:       "Format not allowed",
+			policy:     AttestationPolicy{Formats: []string{FormatPacked}},
+			format:     FormatNone,
+			typ:        AttestationTypeNone,
+			wantReason: PolicyReasonFormat,
+		},
+		{
+			name:       "Self attestation rejected",
+			policy:     AttestationPolicy{Types: []AttestationType{AttestationTypeBasic, AttestationTypeAttCA}},
+			format:     FormatPacked,
+			typ:        AttestationTypeSelf,
+			wantReason: PolicyReasonAttestationType,
+		},
+		{
+			name:   "AAGUID allowed",
+			policy: AttestationPolicy{AllowedAAGUIDs: []AAGUID{other, aaguid}},
+			format: FormatPacked,
+			typ:    AttestationTypeBasic,
+		},
+		{
+			name:       "AAGUID not allowed",
+			policy:     AttestationPolicy{AllowedAAGUIDs: []AAGUID{other}},
+			format:     FormatPacked,
+			typ:        AttestationTypeBasic,
+			wantReason: PolicyReasonAAGUIDNotAllowed,
+		},
+		{
+			name:       "AAGUID denied",
+			policy:     AttestationPolicy{DeniedAAGUIDs: []AAGUID{aaguid}},
+			format:     FormatPacked,
+			typ:        AttestationTypeBasic,
+			wantReason: PolicyReasonAAGUIDDenied,
+		},
+		{
+			name: "Certification level met",
+			policy: AttestationPolicy{
+				MinCertificationLevel: CertificationLevelL2,
+				GetStatusReports: statusReports(
+					StatusReport{Status: StatusFIDOCertifiedL1},
+					StatusReport{Status: StatusFIDOCertifiedL2},
+				),
+			},
+			format: FormatPacked,
+			typ:    AttestationTypeBasic,
+		},
+		{
+			name: "Certification level not met",
+			policy: AttestationPolicy{
+				MinCertificationLevel: CertificationLevelL2,
+				GetStatusReports:      statusReports(StatusReport{Status: StatusFIDOCertified}),
+			},
+			format:     FormatPacked,
+			typ:        AttestationTypeBasic,
+			wantReason: PolicyReasonCertificationLevel,
+		},
+		{
+			name:    "Certification level without status reports",
+			policy:  AttestationPolicy{MinCertificationLevel: CertificationLevelL1},
+			format:  FormatPacked,
+			typ:     AttestationTypeBasic,
+			wantErr: true,
+		},
+		{
+			name: "Revoked",
+			policy: AttestationPolicy{
+				GetStatusReports: statusReports(
+					StatusReport{Status: StatusFIDOCertifiedL1},
+					StatusReport{Status: StatusRevoked},
+				),
+			},
+			format:     FormatPacked,
+			typ:        AttestationTypeBasic,
+			wantReason: PolicyReasonStatus,
+		},
+		{
+			name: "Key compromise then update available",
+			policy: AttestationPolicy{
+				GetStatusReports: statusReports(
+					StatusReport{Status: StatusFIDOCertifiedL2},
+					StatusReport{Status: StatusAttestationKeyCompromise},
+					StatusReport{Status: StatusUpdateAvailable},
+				),
+			},
+			format:     FormatPacked,
+			typ:        AttestationTypeBasic,
+			wantReason: PolicyReasonStatus,
+		},
+		{
+			name: "Revocation not yet effective",
+			policy: AttestationPolicy{
+				GetStatusReports: statusReports(
+					StatusReport{Status: StatusRevoked, EffectiveDate: now.Add(24 * time.Hour)},
+				),
+				Now: func() time.Time { return now },
+			},
+			format: FormatPacked,
+			typ:    AttestationTypeBasic,
+		},
+		{
+			name: "Custom denied statuses",
+			policy: AttestationPolicy{
+				GetStatusReports: statusReports(StatusReport{Status: StatusUpdateAvailable}),
+				DeniedStatuses:   []AuthenticatorStatus{StatusUpdateAvailable},
+			},
+			format:     FormatPacked,
+			typ:        AttestationTypeBasic,
+			wantReason: PolicyReasonStatus,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			reg := &Registration{
+				Format:      tc.format,
+				Type:        tc.typ,
+				Attestation: &Attestation{AAGUID: aaguid},
+			}
+			err := tc.policy.Evaluate(reg)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("Expected error evaluating policy")
+				}
+				return
+			}
+			if tc.wantReason == 0 {
+				if err != nil {
+					t.Fatalf("Evaluating policy: %v", err)
+				}
+				return
+			}
+			var perr *PolicyError
+			if !errors.As(err, &perr) {
+				t.Fatalf("Expected policy error, got=%v", err)
+			}
+			if perr.Reason != tc.wantReason {
+				t.Errorf("Unexpected policy reason, got=%s, want=%s", perr.Reason, tc.wantReason)
+			}
+		})
+	}
+}
+
+func TestVerifyRegistrationPolicy(t *testing.T) {
+	const (
+		rpID   = "localhost"
+		origin = "http://localhost:8080"
+	)
+	credKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Generating key: %v", err)
+	}
+	challenge := []byte("policy-test-challenge")
+	clientDataJSON := testClientDataJSON("webauthn.create", origin, challenge)
+	authData := testAuthData(t, rpID, 0x45, AAGUID{}, []byte("credential"), &credKey.PublicKey, ES256)
+	attestationObject := testAttestationObject(FormatNone, cborMap(), authData)
+
+	rp := &RelyingParty{ID: rpID, Origin: origin}
+	if _, err := rp.VerifyRegistration(challenge, clientDataJSON, attestationObject); err != nil {
+		t.Fatalf("Verifying registration without policy: %v", err)
+	}
+
+	rp.AttestationPolicy = &AttestationPolicy{
+		Types: []AttestationType{AttestationTypeBasic, AttestationTypeAttCA},
+	}
+	_, err = rp.VerifyRegistration(challenge, clientDataJSON, attestationObject)
+	var perr *PolicyError
+	if !errors.As(err, &perr) {
+		t.Fatalf("Expected policy error verifying registration, got=%v", err)
+	}
+	if perr.Reason != PolicyReasonAttestationType {
+		t.Errorf("Unexpected policy reason, got=%s, want=%s", perr.Reason, PolicyReasonAttestationType)
+	}
+}
+
+func TestStatusPolicy(t *testing.T) {
+	aaguid := mustParseAAGUID("ee882879-721c-4913-9775-3dfcce97072a")
+	now := time.Date(2024, time.June, 1, 0, 0, 0, 0, time.UTC)
+	before := now.Add(-30 * 24 * time.Hour)
+	after := now.Add(30 * 24 * time.Hour)
+
+	testCases := []struct {
+		name       string
+		reports    []StatusReport
+		statuses   []AuthenticatorStatus
+		reject     bool
+		wantStatus AuthenticatorStatus
+		// Expected effective date of the returned report, if set.
+		wantDate time.Time
+		wantErr  bool
+	}{
+		{
+			name: "Certified",
+			reports: []StatusReport{
+				{Status: StatusFIDOCertifiedL1, EffectiveDate: before},
+			},
+		},
+		{
+			name: "Key compromise",
+			reports: []StatusReport{
+				{Status: StatusFIDOCertifiedL1, EffectiveDate: before.Add(-time.Hour)},
+				{Status: StatusAttestationKeyCompromise, EffectiveDate: before},
+			},
+			wantStatus: StatusAttestationKeyCompromise,
+		},
+		{
+			name: "Most recent report",
+			reports: []StatusReport{
+				{Status: StatusAttestationKeyCompromise, EffectiveDate: before},
+				{Status: StatusUserVerificationBypass, EffectiveDate: before.Add(-time.Hour)},
+			},
+			wantStatus: StatusAttestationKeyCompromise,
+		},
+		{
+			name: "Recertified after key compromise",
+			reports: []StatusReport{
+				{Status: StatusAttestationKeyCompromise, EffectiveDate: before.Add(-time.Hour)},
+				{Status: StatusFIDOCertifiedL1, EffectiveDate: before},
+			},
+			reject:  true,
+			wantErr: true,
+		},
+		{
+			name: "Update available after key compromise",
+			reports: []StatusReport{
+				{Status: StatusAttestationKeyCompromise, EffectiveDate: before.Add(-time.Hour)},
+				{Status: StatusUpdateAvailable, EffectiveDate: before},
+			},
+			wantStatus: StatusAttestationKeyCompromise,
+			wantDate:   before.Add(-time.Hour),
+		},
+		{
+			name: "Key compromise rejected",
+			reports: []StatusReport{
+				{Status: StatusAttestationKeyCompromise, EffectiveDate: before},
+			},
+			reject:  true,
+			wantErr: true,
+		},
+		{
+			name: "Future report ignored",
+			reports: []StatusReport{
+				{Status: StatusUserVerificationBypass, EffectiveDate: after},
+			},
+			reject: true,
+		},
+		{
+			name: "Custom statuses",
+			reports: []StatusReport{
+				{Status: StatusAttestationKeyCompromise, EffectiveDate: before},
+				{Status: StatusRevoked, EffectiveDate: before},
+			},
+			statuses:   []AuthenticatorStatus{StatusRevoked},
+			wantStatus: StatusRevoked,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rp := &RelyingParty{
+				StatusPolicy: &StatusPolicy{
+					GetStatusReports: func(got AAGUID) ([]StatusReport, error) {
+						if got != aaguid {
+							t.Errorf("GetStatusReports called with unexpected aaguid, got=%s, want=%s", got, aaguid)
+						}
+						return tc.reports, nil
+					},
+					Statuses: tc.statuses,
+					Reject:   tc.reject,
+					Now:      func() time.Time { return now },
+				},
+			}
+			got, err := rp.VerifyAuthenticatorStatus(aaguid)
+			if tc.wantErr {
+				var serr *StatusError
+				if !errors.As(err, &serr) {
+					t.Fatalf("Expected status error, got=%v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Verifying authenticator status: %v", err)
+			}
+			if tc.wantStatus == "" {
+				if got != nil {
+					t.Errorf("Expected no status report, got=%s", got.Status)
+				}
+				return
+			}
+			if got == nil {
+				t.Fatalf("Expected status report %s, got none", tc.wantStatus)
+			}
+			if got.Status != tc.wantStatus {
+				t.Errorf("Unexpected status, got=%s, want=%s", got.Status, tc.wantStatus)
+			}
+			if !tc.wantDate.IsZero() && !got.EffectiveDate.Equal(tc.wantDate) {
+				t.Errorf("Unexpected effective date, got=%s, want=%s", got.EffectiveDate, tc.wantDate)
+			}
+		})
+	}
+}
+
+func TestFlaggedStatusReport(t *testing.T) {
+	now := time.Date(2024, time.June, 1, 0, 0, 0, 0, time.UTC)
+	before := now.Add(-30 * 24 * time.Hour)
+	after := now.Add(30 * 24 * time.Hour)
+	statuses := []AuthenticatorStatus{StatusRevoked, StatusAttestationKeyCompromise}
+
+	testCases := []struct {
+		name    string
+		reports []StatusReport
+		want    AuthenticatorStatus
+	}{
+		{
+			name: "No reports",
+		},
+		{
+			name: "No flagged reports",
+			reports: []StatusReport{
+				{Status: StatusFIDOCertifiedL1, EffectiveDate: before},
+			},
+		},
+		{
+			name: "Flagged then recertified",
+			reports: []StatusReport{
+				{Status: StatusAttestationKeyCompromise, EffectiveDate: before.Add(-time.Hour)},
+				{Status: StatusFIDOCertifiedL1, EffectiveDate: before},
+			},
+			want: StatusAttestationKeyCompromise,
+		},
+		{
+			name: "Most recent flagged report",
+			reports: []StatusReport{
+				{Status: StatusRevoked, EffectiveDate: before},
+				{Status: StatusAttestationKeyCompromise, EffectiveDate: before.Add(-time.Hour)},
+			},
+			want: StatusRevoked,
+		},
+		{
+			name: "Future report ignored",
+			reports: []StatusReport{
+				{Status: StatusFIDOCertifiedL1, EffectiveDate: before},
+				{Status: StatusRevoked, EffectiveDate: after},
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := FlaggedStatusReport(tc.reports, statuses, now)
+			if tc.want == "" {
+				if got != nil {
+					t.Errorf("Expected no status report, got=%s", got.Status)
+				}
+				return
+			}
+			if got == nil {
+				t.Fatalf("Expected status report %s, got none", tc.want)
+			}
+			if got.Status != tc.want {
+				t.Errorf("Unexpected status report, got=%s, want=%s", got.Status, tc.want)
+			}
+		})
+	}
+}
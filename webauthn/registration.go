@@ -0,0 +1,305 @@
+package webauthn
+
+import (
+	"crypto/x509"
+	"fmt"
+
+	"github.com/go-passkeys/go-passkeys/webauthn/internal/cbor"
+)
+
+// AttestationType describes the trust model used by an attestation statement.
+//
+// https://www.w3.org/TR/webauthn-3/#sctn-attestation-types
+type AttestationType int
+
+// Attestation types defined by the WebAuthn specification.
+const (
+	// The authenticator didn't provide attestation.
+	AttestationTypeNone AttestationType = iota
+	// The attestation was signed by the credential private key itself.
+	AttestationTypeSelf
+	// The attestation was signed by a key shared by a batch of authenticators.
+	AttestationTypeBasic
+	// The attestation was signed by a per-device key certified by an
+	// Attestation CA, such as a TPM's attestation identity key.
+	AttestationTypeAttCA
+	// The attestation was signed by an anonymization CA, which issues a
+	// per-credential attestation certificate.
+	AttestationTypeAnonCA
+)
+
+var attestationTypeStrings = map[AttestationType]string{
+	AttestationTypeNone:   "None",
+	AttestationTypeSelf:   "Self",
+	AttestationTypeBasic:  "Basic",
+	AttestationTypeAttCA:  "AttCA",
+	AttestationTypeAnonCA: "AnonCA",
+}
+
+// String returns a human readable representation of the attestation type.
+func (t AttestationType) String() string {
+	if s, ok := attestationTypeStrings[t]; ok {
+		return s
+	}
+	return fmt.Sprintf("AttestationType(%d)", int(t))
+}
+
+// AttestationStatement holds the components of an attestation object passed to
+// an [AttestationVerifier]. Client data has already been validated against the
+// relying party, and the authenticator data parsed, by the time a verifier is
+// called.
+type AttestationStatement struct {
+	// RPID is the relying party identifier the credential was created for.
+	RPID string
+	// Format is the attestation statement format identifier, such as "packed".
+	Format string
+	// Statement is the raw CBOR encoded attestation statement ("attStmt").
+	Statement []byte
+	// AuthData is the raw authenticator data ("authData").
+	AuthData []byte
+	// ClientDataJSON is the raw client data, whose hash is signed by most
+	// attestation statement formats.
+	ClientDataJSON []byte
+
+	// AttestationData holds the parsed authenticator data.
+	AttestationData *Attestation
+}
+
+// AttestationResult is returned by an [AttestationVerifier] after successfully
+// validating an attestation statement.
+type AttestationResult struct {
+	// Type is the attestation type conveyed by the statement.
+	Type AttestationType
+	// TrustPath holds the attestation certificate chain, leaf first. This is
+	// empty for None and Self attestation.
+	TrustPath []*x509.Certificate
+	// Details holds the format specific result, such as a [*Packed] or [*TPM]
+	// for the formats implemented by this package. It may be nil.
+	Details any
+}
+
+// AttestationVerifier validates attestation statements of a specific format.
+// Implementations can be registered with [RelyingParty] to support additional
+// or custom formats.
+//
+// The option types of this package, such as [PackedOptions] and [TPMOptions],
+// implement this interface.
+//
+// https://www.w3.org/TR/webauthn-3/#sctn-attestation-formats
+type AttestationVerifier interface {
+	VerifyAttestationStatement(stmt *AttestationStatement) (*AttestationResult, error)
+}
+
+// Registration holds the result of a validated credential creation.
+type Registration struct {
+	// Format is the attestation statement format used by the authenticator.
+	Format string
+	// Type is the attestation type conveyed by the statement.
+	Type AttestationType
+	// TrustPath holds the attestation certificate chain, leaf first.
+	TrustPath []*x509.Certificate
+	// Details holds the format specific result returned by the verifier. See
+	// [AttestationResult].
+	Details any
+
+	// Parsed and validated authenticator data.
+	Attestation *Attestation
+}
+
+// defaultAttestationVerifiers are used for formats that don't have a verifier
+// registered with the relying party.
+var defaultAttestationVerifiers = map[string]AttestationVerifier{
+	FormatNone:   noneVerifier{},
+	FormatPacked: &PackedOptions{AllowSelfAttested: true},
+}
+
+// VerifyRegistration validates a credential creation attempt, dispatching on
+// the attestation statement format to the relying party's
+// AttestationVerifiers. If the relying party has an AttestationPolicy, it's
+// evaluated against the result and a [*PolicyError] is returned on rejection.
+//
+// Without any configuration, "none" and self attested "packed" statements
+// are accepted. Register verifiers with root certificates to accept other
+// formats:
+//
+//	rp.AttestationVerifiers = map[string]webauthn.AttestationVerifier{
+//		webauthn.FormatPacked: &webauthn.PackedOptions{GetRoots: getRoots},
+//		webauthn.FormatTPM:    &webauthn.TPMOptions{GetRoots: getRoots},
+//	}
+//
+// https://www.w3.org/TR/webauthn-3/#sctn-registering-a-new-credential
+func (rp *RelyingParty) VerifyRegistration(challenge, clientDataJSON, attestationObject []byte) (*Registration, error) {
+	cd, err := rp.verifyClientData(clientDataJSON, "webauthn.create", challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	attObj, err := parseAttestationObject(attestationObject)
+	if err != nil {
+		return nil, fmt.Errorf("parsing attestation object: %v", err)
+	}
+	ad, err := parseAuthData(attObj.authData, rp.ID)
+	if err != nil {
+		return nil, fmt.Errorf("parsing authenticator data: %v", err)
+	}
+	if err := rp.verifyAttestationData(cd, ad); err != nil {
+		return nil, err
+	}
+
+	v, ok := rp.AttestationVerifiers[attObj.format]
+	if !ok {
+		v, ok = defaultAttestationVerifiers[attObj.format]
+	}
+	if !ok || v == nil {
+		return nil, fmt.Errorf("unsupported attestation format: %s", attObj.format)
+	}
+	res, err := v.VerifyAttestationStatement(&AttestationStatement{
+		RPID:            rp.ID,
+		Format:          attObj.format,
+		Statement:       attObj.attestationStatement,
+		AuthData:        attObj.authData,
+		ClientDataJSON:  clientDataJSON,
+		AttestationData: ad,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("verifying %s attestation statement: %v", attObj.format, err)
+	}
+	reg := &Registration{
+		Format:      attObj.format,
+		Type:        res.Type,
+		TrustPath:   res.TrustPath,
+		Details:     res.Details,
+		Attestation: ad,
+	}
+	if rp.AttestationPolicy != nil {
+		if err := rp.AttestationPolicy.Evaluate(reg); err != nil {
+			return nil, err
+		}
+	}
+	return reg, nil
+}
+
+// attestationData returns the parsed authenticator data of the statement,
+// parsing it only if the caller didn't provide it.
+func (s *AttestationStatement) attestationData() (*Attestation, error) {
+	if s.AttestationData != nil {
+		return s.AttestationData, nil
+	}
+	ad, err := parseAuthData(s.AuthData, s.RPID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid auth data: %v", err)
+	}
+	return ad, nil
+}
+
+// noneVerifier accepts attestation objects that don't convey attestation. The
+// attestation statement must be an empty map.
+//
+// https://www.w3.org/TR/webauthn-3/#sctn-none-attestation
+type noneVerifier struct{}
+
+func (noneVerifier) VerifyAttestationStatement(stmt *AttestationStatement) (*AttestationResult, error) {
+	n := 0
+	d := cbor.NewDecoder(stmt.Statement)
+	ok := d.Map(func(kv *cbor.Decoder) bool {
+		n++
+		return kv.Skip() && kv.Skip()
+	}) && d.Done()
+	if !ok {
+		return nil, fmt.Errorf("attestation statement was not valid cbor")
+	}
+	if n != 0 {
+		return nil, fmt.Errorf("attestation statement for format %q must be empty", FormatNone)
+	}
+	return &AttestationResult{Type: AttestationTypeNone}, nil
+}
+
+// VerifyAttestationStatement implements [AttestationVerifier] for "packed"
+// attestation statements. The result's Details holds a [*Packed].
+func (o *PackedOptions) VerifyAttestationStatement(stmt *AttestationStatement) (*AttestationResult, error) {
+	ad, err := stmt.attestationData()
+	if err != nil {
+		return nil, err
+	}
+	p, chain, err := verifyPacked(ad, stmt.AuthData, stmt.Statement, stmt.ClientDataJSON, o)
+	if err != nil {
+		return nil, err
+	}
+	if p.SelfAttested {
+		return &AttestationResult{Type: AttestationTypeSelf, Details: p}, nil
+	}
+	return &AttestationResult{Type: AttestationTypeBasic, TrustPath: chain, Details: p}, nil
+}
+
+// VerifyAttestationStatement implements [AttestationVerifier] for "tpm"
+// attestation statements. The result's Details holds a [*TPM].
+func (o *TPMOptions) VerifyAttestationStatement(stmt *AttestationStatement) (*AttestationResult, error) {
+	ad, err := stmt.attestationData()
+	if err != nil {
+		return nil, err
+	}
+	t, chain, err := verifyTPM(ad, stmt.AuthData, stmt.Statement, stmt.ClientDataJSON, o)
+	if err != nil {
+		return nil, err
+	}
+	return &AttestationResult{Type: AttestationTypeAttCA, TrustPath: chain, Details: t}, nil
+}
+
+// VerifyAttestationStatement implements [AttestationVerifier] for
+// "android-key" attestation statements. The result's Details holds an
+// [*AndroidKey].
+func (o *AndroidKeyOptions) VerifyAttestationStatement(stmt *AttestationStatement) (*AttestationResult, error) {
+	ad, err := stmt.attestationData()
+	if err != nil {
+		return nil, err
+	}
+	k, chain, err := verifyAndroidKey(ad, stmt.AuthData, stmt.Statement, stmt.ClientDataJSON, o)
+	if err != nil {
+		return nil, err
+	}
+	return &AttestationResult{Type: AttestationTypeBasic, TrustPath: chain, Details: k}, nil
+}
+
+// VerifyAttestationStatement implements [AttestationVerifier] for "apple"
+// attestation statements. The result's Details holds an [*Apple].
+func (o *AppleOptions) VerifyAttestationStatement(stmt *AttestationStatement) (*AttestationResult, error) {
+	ad, err := stmt.attestationData()
+	if err != nil {
+		return nil, err
+	}
+	a, chain, err := verifyApple(ad, stmt.AuthData, stmt.Statement, stmt.ClientDataJSON, o)
+	if err != nil {
+		return nil, err
+	}
+	return &AttestationResult{Type: AttestationTypeAnonCA, TrustPath: chain, Details: a}, nil
+}
+
+// VerifyAttestationStatement implements [AttestationVerifier] for "fido-u2f"
+// attestation statements. The result's Details holds a [*FIDOU2F].
+func (o *FIDOU2FOptions) VerifyAttestationStatement(stmt *AttestationStatement) (*AttestationResult, error) {
+	ad, err := stmt.attestationData()
+	if err != nil {
+		return nil, err
+	}
+	u, chain, err := verifyFIDOU2F(ad, stmt.AuthData, stmt.Statement, stmt.ClientDataJSON, o)
+	if err != nil {
+		return nil, err
+	}
+	return &AttestationResult{Type: AttestationTypeBasic, TrustPath: chain, Details: u}, nil
+}
+
+// VerifyAttestationStatement implements [AttestationVerifier] for
+// "android-safetynet" attestation statements. The result's Details holds a
+// [*SafetyNet], and the trust path is the certificate chain of the SafetyNet
+// response.
+func (o *SafetyNetOptions) VerifyAttestationStatement(stmt *AttestationStatement) (*AttestationResult, error) {
+	ad, err := stmt.attestationData()
+	if err != nil {
+		return nil, err
+	}
+	sn, chain, err := verifySafetyNet(ad, stmt.AuthData, stmt.Statement, stmt.ClientDataJSON, o)
+	if err != nil {
+		return nil, err
+	}
+	return &AttestationResult{Type: AttestationTypeBasic, TrustPath: chain, Details: sn}, nil
+}
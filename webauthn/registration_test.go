@@ -0,0 +1,221 @@
+package webauthn
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+type testVerifier struct {
+	err error
+}
+
+func (v *testVerifier) VerifyAttestationStatement(stmt *AttestationStatement) (*AttestationResult, error) {
+	if v.err != nil {
+		return nil, v.err
+	}
+	return &AttestationResult{Type: AttestationTypeBasic}, nil
+}
+
+func TestVerifyRegistration(t *testing.T) {
+	const (
+		rpID   = "localhost"
+		origin = "http://localhost:8080"
+	)
+
+	ca := newTestCA(t)
+	credKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Generating key: %v", err)
+	}
+	attKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Generating key: %v", err)
+	}
+
+	challenge := []byte("registration-test-challenge")
+	clientDataJSON := testClientDataJSON("webauthn.create", origin, challenge)
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	authData := testAuthData(t, rpID, 0x45, AAGUID{}, []byte("credential"), &credKey.PublicKey, ES256)
+	signedData := append(append([]byte{}, authData...), clientDataHash[:]...)
+
+	selfStmt := cborMap(
+		cborString("alg"), cborInt(int64(ES256)),
+		cborString("sig"), cborBytes(signES256(t, credKey, signedData)),
+	)
+	attCert := ca.issue(t, &x509.Certificate{
+		Subject: pkix.Name{
+			CommonName:         "Test Authenticator",
+			Organization:       []string{"Test Vendor"},
+			OrganizationalUnit: []string{"Authenticator Attestation"},
+			Country:            []string{"US"},
+		},
+		BasicConstraintsValid: true,
+		ExtraExtensions:       []pkix.Extension{aaguidExtension(t, AAGUID{})},
+	}, attKey.Public())
+	fullStmt := cborMap(
+		cborString("alg"), cborInt(int64(ES256)),
+		cborString("sig"), cborBytes(signES256(t, attKey, signedData)),
+		cborString("x5c"), cborCertificates(attCert),
+	)
+
+	nonce := sha256.Sum256(signedData)
+	ext, err := asn1.Marshal(struct {
+		Nonce []byte `asn1:"tag:1,explicit"`
+	}{nonce[:]})
+	if err != nil {
+		t.Fatalf("Encoding nonce extension: %v", err)
+	}
+	credCert := ca.issue(t, &x509.Certificate{
+		Subject: pkix.Name{CommonName: "credential"},
+		ExtraExtensions: []pkix.Extension{
+			{Id: idAppleAnonymousAttestation, Value: ext},
+		},
+	}, credKey.Public())
+	appleStmt := cborMap(cborString("x5c"), cborCertificates(credCert))
+
+	testCases := []struct {
+		name          string
+		format        string
+		attStmt       []byte
+		verifiers     map[string]AttestationVerifier
+		wantType      AttestationType
+		wantTrustPath int
+		wantDetails   any
+		wantErr       string
+	}{
+		{
+			name:     "None",
+			format:   FormatNone,
+			attStmt:  cborMap(),
+			wantType: AttestationTypeNone,
+		},
+		{
+			name:    "None with statement",
+			format:  FormatNone,
+			attStmt: selfStmt,
+			wantErr: "must be empty",
+		},
+		{
+			name:        "Packed self attestation",
+			format:      FormatPacked,
+			attStmt:     selfStmt,
+			wantType:    AttestationTypeSelf,
+			wantDetails: &Packed{},
+		},
+		{
+			name:    "Packed full attestation without roots",
+			format:  FormatPacked,
+			attStmt: fullStmt,
+			wantErr: "verifying packed attestation statement",
+		},
+		{
+			name:    "Packed full attestation",
+			format:  FormatPacked,
+			attStmt: fullStmt,
+			verifiers: map[string]AttestationVerifier{
+				FormatPacked: &PackedOptions{
+					GetRoots: func(AAGUID) (*x509.CertPool, error) {
+						return ca.pool(), nil
+					},
+				},
+			},
+			wantType:      AttestationTypeBasic,
+			wantTrustPath: 1,
+			wantDetails:   &Packed{},
+		},
+		{
+			name:    "Apple",
+			format:  FormatApple,
+			attStmt: appleStmt,
+			verifiers: map[string]AttestationVerifier{
+				FormatApple: &AppleOptions{Roots: ca.pool()},
+			},
+			wantType:      AttestationTypeAnonCA,
+			wantTrustPath: 1,
+			wantDetails:   &Apple{},
+		},
+		{
+			name:    "Unregistered format",
+			format:  FormatApple,
+			attStmt: appleStmt,
+			wantErr: "unsupported attestation format",
+		},
+		{
+			name:    "Custom format",
+			format:  "custom",
+			attStmt: cborMap(),
+			verifiers: map[string]AttestationVerifier{
+				"custom": &testVerifier{},
+			},
+			wantType: AttestationTypeBasic,
+		},
+		{
+			name:    "Custom format error",
+			format:  "custom",
+			attStmt: cborMap(),
+			verifiers: map[string]AttestationVerifier{
+				"custom": &testVerifier{err: fmt.Errorf("bad statement")},
+			},
+			wantErr: "bad statement",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			attestationObject := testAttestationObject(tc.format, tc.attStmt, authData)
+			rp := &RelyingParty{ID: rpID, Origin: origin, AttestationVerifiers: tc.verifiers}
+			got, err := rp.VerifyRegistration(challenge, clientDataJSON, attestationObject)
+			if tc.wantErr != "" {
+				if err == nil {
+					t.Fatalf("Expected error verifying registration")
+				}
+				if !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("Verifying registration returned unexpected error, got=%v, want=%s", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Verifying registration: %v", err)
+			}
+			if got.Format != tc.format {
+				t.Errorf("Unexpected format, got=%s, want=%s", got.Format, tc.format)
+			}
+			if got.Type != tc.wantType {
+				t.Errorf("Unexpected attestation type, got=%s, want=%s", got.Type, tc.wantType)
+			}
+			if len(got.TrustPath) != tc.wantTrustPath {
+				t.Errorf("Unexpected trust path length, got=%d, want=%d", len(got.TrustPath), tc.wantTrustPath)
+			}
+			if !credKey.PublicKey.Equal(got.Attestation.PublicKey) {
+				t.Errorf("Registration returned unexpected public key")
+			}
+			if gotType, wantType := fmt.Sprintf("%T", got.Details), fmt.Sprintf("%T", tc.wantDetails); gotType != wantType {
+				t.Errorf("Unexpected details type, got=%s, want=%s", gotType, wantType)
+			}
+			switch d := got.Details.(type) {
+			case *Packed:
+				if d.AttestationData != got.Attestation {
+					t.Errorf("Packed details don't share the registration's authenticator data")
+				}
+				if len(got.TrustPath) > 0 && d.AttestationCertificate != got.TrustPath[0] {
+					t.Errorf("Trust path doesn't start with the verified attestation certificate")
+				}
+			case *Apple:
+				if d.AttestationData != got.Attestation {
+					t.Errorf("Apple details don't share the registration's authenticator data")
+				}
+				if d.AttestationCertificate != got.TrustPath[0] {
+					t.Errorf("Trust path doesn't start with the verified attestation certificate")
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package webauthn
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/go-passkeys/go-passkeys/webauthn/internal/publicsuffix"
+)
+
+// RelatedOriginsPath is the path browsers fetch, relative to the RP ID, to
+// discover the related origins permitted to use it.
+//
+// https://www.w3.org/TR/webauthn-3/#sctn-related-origins
+const RelatedOriginsPath = "/.well-known/webauthn"
+
+// maxRelatedOriginLabels is the number of distinct labels browsers are
+// required to process in a related origins document. Origins beyond this limit
+// may be ignored.
+//
+// https://www.w3.org/TR/webauthn-3/#sctn-validating-relation-origin
+const maxRelatedOriginLabels = 5
+
+// relatedOriginLabel returns the label of an origin's registrable domain,
+// excluding the public suffix. For example "example" for both
+// "https://login.example.com" and "https://example.co.uk".
+func relatedOriginLabel(origin string) (string, error) {
+	u, err := url.Parse(origin)
+	if err != nil {
+		return "", fmt.Errorf("parsing related origin %s: %v", origin, err)
+	}
+	if u.Scheme != "https" || u.Host == "" || u.Path != "" || u.RawQuery != "" || u.Fragment != "" {
+		return "", fmt.Errorf("related origin %s is not an HTTPS origin", origin)
+	}
+	host := strings.ToLower(u.Hostname())
+	if net.ParseIP(host) != nil {
+		return "", fmt.Errorf("related origin %s doesn't have a domain", origin)
+	}
+	domain, err := publicsuffix.EffectiveTLDPlusOne(host)
+	if err != nil {
+		return "", fmt.Errorf("related origin %s doesn't have a registrable domain: %v", origin, err)
+	}
+	label, _, _ := strings.Cut(domain, ".")
+	return label, nil
+}
+
+// RelatedOriginsDocument returns the JSON document listing the relying party's
+// RelatedOrigins, to be served at "https://<RP ID>/.well-known/webauthn".
+//
+// Browsers only process origins with up to 5 distinct labels, where a label is
+// the registrable domain excluding its public suffix. "https://example.com" and
+// "https://example.co.uk" share the label "example". An error is returned if
+// the origins exceed this limit, or can't be processed by browsers.
+//
+// https://www.w3.org/TR/webauthn-3/#sctn-related-origins
+func (rp *RelyingParty) RelatedOriginsDocument() ([]byte, error) {
+	if len(rp.RelatedOrigins) == 0 {
+		return nil, fmt.Errorf("no related origins configured")
+	}
+	labels := map[string]bool{}
+	for _, origin := range rp.RelatedOrigins {
+		label, err := relatedOriginLabel(origin)
+		if err != nil {
+			return nil, err
+		}
+		labels[label] = true
+	}
+	if len(labels) > maxRelatedOriginLabels {
+		return nil, fmt.Errorf("related origins use %d labels, browsers only process %d", len(labels), maxRelatedOriginLabels)
+	}
+	return json.Marshal(struct {
+		Origins []string `json:"origins"`
+	}{rp.RelatedOrigins})
+}
+
+// RelatedOriginsHandler returns a handler serving [RelyingParty.RelatedOriginsDocument].
+// The document is generated when the handler is created. The handler should be
+// registered on the RP ID's host:
+//
+//	h, err := rp.RelatedOriginsHandler()
+//	if err != nil {
+//		// Handle error.
+//	}
+//	mux.Handle("GET "+webauthn.RelatedOriginsPath, h)
+//
+// https://www.w3.org/TR/webauthn-3/#sctn-related-origins
+func (rp *RelyingParty) RelatedOriginsHandler() (http.Handler, error) {
+	doc, err := rp.RelatedOriginsDocument()
+	if err != nil {
+		return nil, err
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(doc)
+	}), nil
+}
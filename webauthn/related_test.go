@@ -0,0 +1,170 @@
+package webauthn
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRelatedOriginsDocument(t *testing.T) {
+	testCases := []struct {
+		name    string
+		origins []string
+		want    string
+		wantErr string
+	}{
+		{
+			name: "Same label",
+			origins: []string{
+				"https://example.co.uk",
+				"https://example.de",
+				"https://login.example.fr",
+			},
+			want: `{"origins":["https://example.co.uk","https://example.de","https://login.example.fr"]}`,
+		},
+		{
+			name: "Five labels",
+			origins: []string{
+				"https://a.com",
+				"https://b.com",
+				"https://c.co.uk",
+				"https://d.de",
+				"https://e.github.io",
+				"https://a.de",
+			},
+			want: `{"origins":["https://a.com","https://b.com","https://c.co.uk","https://d.de","https://e.github.io","https://a.de"]}`,
+		},
+		{
+			name: "Too many labels",
+			origins: []string{
+				"https://a.com",
+				"https://b.com",
+				"https://c.com",
+				"https://d.com",
+				"https://e.com",
+				"https://f.com",
+			},
+			wantErr: "use 6 labels",
+		},
+		{
+			name:    "Public suffix",
+			origins: []string{"https://co.uk"},
+			wantErr: "registrable domain",
+		},
+		{
+			name:    "IP address",
+			origins: []string{"https://127.0.0.1"},
+			wantErr: "doesn't have a domain",
+		},
+		{
+			name:    "HTTP",
+			origins: []string{"http://example.de"},
+			wantErr: "not an HTTPS origin",
+		},
+		{
+			name:    "Path",
+			origins: []string{"https://example.de/login"},
+			wantErr: "not an HTTPS origin",
+		},
+		{
+			name:    "Empty",
+			wantErr: "no related origins",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rp := &RelyingParty{
+				ID:             "example.com",
+				Origin:         "https://example.com",
+				RelatedOrigins: tc.origins,
+			}
+			got, err := rp.RelatedOriginsDocument()
+			if tc.wantErr != "" {
+				if err == nil {
+					t.Fatalf("Expected error generating document")
+				}
+				if !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("Generating document returned unexpected error, got=%v, want=%s", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Generating document: %v", err)
+			}
+			if string(got) != tc.want {
+				t.Errorf("Unexpected document\ngot:  %s\nwant: %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestRelatedOriginsHandler(t *testing.T) {
+	rp := &RelyingParty{
+		ID:             "example.com",
+		Origin:         "https://example.com",
+		RelatedOrigins: []string{"https://example.co.uk"},
+	}
+	h, err := rp.RelatedOriginsHandler()
+	if err != nil {
+		t.Fatalf("Creating handler: %v", err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle("GET "+RelatedOriginsPath, h)
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/.well-known/webauthn")
+	if err != nil {
+		t.Fatalf("Fetching document: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Reading response: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Unexpected status code: %s: %s", resp.Status, body)
+	}
+	if got, want := resp.Header.Get("Content-Type"), "application/json"; got != want {
+		t.Errorf("Unexpected content type, got=%s, want=%s", got, want)
+	}
+	if got, want := string(body), `{"origins":["https://example.co.uk"]}`; got != want {
+		t.Errorf("Unexpected document, got=%s, want=%s", got, want)
+	}
+
+	rp.RelatedOrigins = []string{"https://com"}
+	if _, err := rp.RelatedOriginsHandler(); err == nil {
+		t.Errorf("Expected error creating handler with invalid origins")
+	}
+}
+
+func TestVerifyAssertionRelatedOrigin(t *testing.T) {
+	const rpID = "example.com"
+	credKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Generating key: %v", err)
+	}
+	rp := &RelyingParty{
+		ID:             rpID,
+		Origin:         "https://example.com",
+		RelatedOrigins: []string{"https://example.co.uk", "https://example.de"},
+	}
+
+	challenge := []byte("challenge")
+	clientDataJSON := testClientDataJSON("webauthn.get", "https://example.de", challenge)
+	authData, sig := testAssertion(t, credKey, rpID, 0x05, nil, clientDataJSON)
+	if _, err := rp.VerifyAssertion(&credKey.PublicKey, ES256, challenge, clientDataJSON, authData, sig); err != nil {
+		t.Errorf("Verifying assertion from related origin: %v", err)
+	}
+
+	clientDataJSON = testClientDataJSON("webauthn.get", "https://example.fr", challenge)
+	authData, sig = testAssertion(t, credKey, rpID, 0x05, nil, clientDataJSON)
+	if _, err := rp.VerifyAssertion(&credKey.PublicKey, ES256, challenge, clientDataJSON, authData, sig); err == nil {
+		t.Errorf("Verifying assertion from unrelated origin succeeded, expected error")
+	}
+}
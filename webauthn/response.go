@@ -0,0 +1,135 @@
+package webauthn
+
+import (
+	"bytes"
+	"crypto"
+	"encoding/base64"
+	"fmt"
+)
+
+// RegistrationResponse holds the result of navigator.credentials.create(),
+// serialized by PublicKeyCredential.toJSON():
+//
+//	const cred = await navigator.credentials.create({ publicKey: opts });
+//	await fetch("/registration-finish", {
+//		method: "POST",
+//		body: JSON.stringify(cred.toJSON()),
+//	});
+//
+// https://www.w3.org/TR/webauthn-3/#dictdef-registrationresponsejson
+type RegistrationResponse struct {
+	// ID is the base64url encoded credential ID.
+	ID    string    `json:"id"`
+	RawID Base64URL `json:"rawId"`
+	// Type is always "public-key".
+	Type                    string                           `json:"type"`
+	Response                AuthenticatorAttestationResponse `json:"response"`
+	AuthenticatorAttachment string                           `json:"authenticatorAttachment,omitempty"`
+	ClientExtensionResults  *ClientExtensionResults          `json:"clientExtensionResults,omitempty"`
+}
+
+// AuthenticatorAttestationResponse holds the authenticator's response to a
+// credential creation.
+//
+// https://www.w3.org/TR/webauthn-3/#dictdef-authenticatorattestationresponsejson
+type AuthenticatorAttestationResponse struct {
+	ClientDataJSON    Base64URL `json:"clientDataJSON"`
+	AttestationObject Base64URL `json:"attestationObject"`
+	// Transports holds the values of getTransports(), such as "internal" or
+	// "usb", and should be stored to populate [CredentialDescriptor].
+	Transports []string `json:"transports,omitempty"`
+
+	// The following fields are conveniences duplicating values in the
+	// attestation object, and are not used for verification.
+	AuthenticatorData  Base64URL `json:"authenticatorData,omitempty"`
+	PublicKey          Base64URL `json:"publicKey,omitempty"`
+	PublicKeyAlgorithm Algorithm `json:"publicKeyAlgorithm,omitempty"`
+}
+
+// AuthenticationResponse holds the result of navigator.credentials.get(),
+// serialized by PublicKeyCredential.toJSON():
+//
+//	const cred = await navigator.credentials.get({ publicKey: opts });
+//	await fetch("/login-finish", {
+//		method: "POST",
+//		body: JSON.stringify(cred.toJSON()),
+//	});
+//
+// https://www.w3.org/TR/webauthn-3/#dictdef-authenticationresponsejson
+type AuthenticationResponse struct {
+	// ID is the base64url encoded credential ID.
+	ID    string    `json:"id"`
+	RawID Base64URL `json:"rawId"`
+	// Type is always "public-key".
+	Type                    string                         `json:"type"`
+	Response                AuthenticatorAssertionResponse `json:"response"`
+	AuthenticatorAttachment string                         `json:"authenticatorAttachment,omitempty"`
+	ClientExtensionResults  *ClientExtensionResults        `json:"clientExtensionResults,omitempty"`
+}
+
+// AuthenticatorAssertionResponse holds the authenticator's response to an
+// authentication request.
+//
+// https://www.w3.org/TR/webauthn-3/#dictdef-authenticatorassertionresponsejson
+type AuthenticatorAssertionResponse struct {
+	ClientDataJSON    Base64URL `json:"clientDataJSON"`
+	AuthenticatorData Base64URL `json:"authenticatorData"`
+	Signature         Base64URL `json:"signature"`
+	// UserHandle is the user ID the credential was created for. This is
+	// always set for discoverable credentials.
+	UserHandle Base64URL `json:"userHandle,omitempty"`
+}
+
+// verifyCredentialID checks the type and identifiers of a serialized
+// credential.
+func verifyCredentialID(typ, id string, rawID []byte) error {
+	if typ != PublicKeyCredentialType {
+		return fmt.Errorf("invalid credential type, expected '%s', got '%s'", PublicKeyCredentialType, typ)
+	}
+	if len(rawID) == 0 {
+		return fmt.Errorf("no credential ID provided")
+	}
+	if id != base64.RawURLEncoding.EncodeToString(rawID) {
+		return fmt.Errorf("credential id doesn't match raw id")
+	}
+	return nil
+}
+
+// VerifyRegistrationResponse validates a credential creation serialized by
+// PublicKeyCredential.toJSON(). The credential attestation is verified by
+// [RelyingParty.VerifyRegistration], and the credential ID of the response
+// must match the attested credential.
+func (rp *RelyingParty) VerifyRegistrationResponse(challenge []byte, resp *RegistrationResponse) (*Registration, error) {
+	if err := verifyCredentialID(resp.Type, resp.ID, resp.RawID); err != nil {
+		return nil, err
+	}
+	reg, err := rp.VerifyRegistration(challenge, resp.Response.ClientDataJSON, resp.Response.AttestationObject)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(reg.Attestation.CredentialID, resp.RawID) {
+		return nil, fmt.Errorf("credential id doesn't match attested credential")
+	}
+	return reg, nil
+}
+
+// VerifyAuthenticationResponse validates an assertion serialized by
+// PublicKeyCredential.toJSON(). The public key and algorithm should be those of
+// the credential identified by the response's RawID, and the challenge the
+// value passed to the frontend to sign. See [RelyingParty.VerifyAssertion].
+//
+// If the client extension results report that the "appid" extension was used,
+// the assertion may be scoped to either the RP ID or the relying party's AppID,
+// and [Assertion.AppID] reports which one matched. Otherwise, it must be scoped
+// to the RP ID.
+func (rp *RelyingParty) VerifyAuthenticationResponse(pub crypto.PublicKey, alg Algorithm, challenge []byte, resp *AuthenticationResponse) (*Assertion, error) {
+	if err := verifyCredentialID(resp.Type, resp.ID, resp.RawID); err != nil {
+		return nil, err
+	}
+	appID := false
+	if ext := resp.ClientExtensionResults; ext != nil && ext.AppID != nil {
+		appID = *ext.AppID
+	}
+	r := resp.Response
+	return rp.verifyAssertion(pub, alg, challenge, r.ClientDataJSON, r.AuthenticatorData, r.Signature, appID)
+}
@@ -0,0 +1,188 @@
+package webauthn
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestVerifyRegistrationResponse(t *testing.T) {
+	const (
+		rpID   = "localhost"
+		origin = "http://localhost:8080"
+	)
+	credKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Generating key: %v", err)
+	}
+	credID := []byte("credential")
+	challenge := []byte("registration")
+	clientDataJSON := testClientDataJSON("webauthn.create", origin, challenge)
+	authData := testAuthData(t, rpID, 0x05, AAGUID{}, credID, &credKey.PublicKey, ES256)
+	attestationObject := testAttestationObject(FormatNone, cborMap(), authData)
+
+	b64 := base64.RawURLEncoding.EncodeToString
+	testCases := []struct {
+		name    string
+		id      string
+		rawID   string
+		typ     string
+		wantErr string
+	}{
+		{
+			name:  "Valid",
+			id:    b64(credID),
+			rawID: b64(credID),
+			typ:   "public-key",
+		},
+		{
+			name:    "Invalid type",
+			id:      b64(credID),
+			rawID:   b64(credID),
+			typ:     "password",
+			wantErr: "invalid credential type",
+		},
+		{
+			name:    "ID mismatch",
+			id:      b64([]byte("other")),
+			rawID:   b64(credID),
+			typ:     "public-key",
+			wantErr: "doesn't match raw id",
+		},
+		{
+			name:    "Attested credential mismatch",
+			id:      b64([]byte("other")),
+			rawID:   b64([]byte("other")),
+			typ:     "public-key",
+			wantErr: "doesn't match attested credential",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			body := `{
+				"id": "` + tc.id + `",
+				"rawId": "` + tc.rawID + `",
+				"type": "` + tc.typ + `",
+				"response": {
+					"clientDataJSON": "` + b64(clientDataJSON) + `",
+					"attestationObject": "` + b64(attestationObject) + `",
+					"transports": ["internal", "hybrid"],
+					"publicKeyAlgorithm": -7
+				},
+				"authenticatorAttachment": "platform",
+				"clientExtensionResults": {"credProps": {"rk": true}}
+			}`
+			var resp RegistrationResponse
+			if err := json.Unmarshal([]byte(body), &resp); err != nil {
+				t.Fatalf("Parsing response: %v", err)
+			}
+			rp := &RelyingParty{ID: rpID, Origin: origin}
+			reg, err := rp.VerifyRegistrationResponse(challenge, &resp)
+			if tc.wantErr != "" {
+				if err == nil {
+					t.Fatalf("Expected error verifying response")
+				}
+				if !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("Verifying response returned unexpected error, got=%v, want=%s", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Verifying response: %v", err)
+			}
+			if reg.Format != FormatNone {
+				t.Errorf("Unexpected format, got=%s, want=%s", reg.Format, FormatNone)
+			}
+			if len(resp.Response.Transports) != 2 {
+				t.Errorf("Unexpected transports: %v", resp.Response.Transports)
+			}
+			if resp.ClientExtensionResults == nil || resp.ClientExtensionResults.CredProps == nil {
+				t.Errorf("Expected credProps client extension result")
+			}
+		})
+	}
+}
+
+func TestVerifyAuthenticationResponse(t *testing.T) {
+	const (
+		rpID   = "localhost"
+		origin = "http://localhost:8080"
+	)
+	credKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Generating key: %v", err)
+	}
+	credID := []byte("credential")
+	challenge := []byte("authentication")
+	clientDataJSON := testClientDataJSON("webauthn.get", origin, challenge)
+	authData, sig := testAssertion(t, credKey, rpID, 0x05, nil, clientDataJSON)
+
+	b64 := base64.RawURLEncoding.EncodeToString
+	testCases := []struct {
+		name    string
+		id      string
+		typ     string
+		wantErr string
+	}{
+		{
+			name: "Valid",
+			id:   b64(credID),
+			typ:  "public-key",
+		},
+		{
+			name:    "Padded ID",
+			id:      base64.URLEncoding.EncodeToString(credID),
+			typ:     "public-key",
+			wantErr: "doesn't match raw id",
+		},
+		{
+			name:    "Missing type",
+			id:      b64(credID),
+			wantErr: "invalid credential type",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			body := `{
+				"id": "` + tc.id + `",
+				"rawId": "` + b64(credID) + `",
+				"type": "` + tc.typ + `",
+				"response": {
+					"clientDataJSON": "` + b64(clientDataJSON) + `",
+					"authenticatorData": "` + b64(authData) + `",
+					"signature": "` + b64(sig) + `",
+					"userHandle": "` + b64([]byte("user")) + `"
+				},
+				"clientExtensionResults": {}
+			}`
+			var resp AuthenticationResponse
+			if err := json.Unmarshal([]byte(body), &resp); err != nil {
+				t.Fatalf("Parsing response: %v", err)
+			}
+			rp := &RelyingParty{ID: rpID, Origin: origin}
+			a, err := rp.VerifyAuthenticationResponse(&credKey.PublicKey, ES256, challenge, &resp)
+			if tc.wantErr != "" {
+				if err == nil {
+					t.Fatalf("Expected error verifying response")
+				}
+				if !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("Verifying response returned unexpected error, got=%v, want=%s", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Verifying response: %v", err)
+			}
+			if a.Counter != 1 {
+				t.Errorf("Unexpected counter, got=%d, want=1", a.Counter)
+			}
+			if string(resp.Response.UserHandle) != "user" {
+				t.Errorf("Unexpected user handle, got=%q", resp.Response.UserHandle)
+			}
+		})
+	}
+}
@@ -0,0 +1,235 @@
+package webauthn
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/go-passkeys/go-passkeys/webauthn/internal/cbor"
+	"github.com/go-passkeys/go-passkeys/webauthn/internal/jws"
+)
+
+// safetyNetHostname is the hostname of the certificate used to sign SafetyNet
+// attestation responses.
+const safetyNetHostname = "attest.android.com"
+
+// SafetyNetOptions allows configuration for validating android-safetynet
+// attestation statements.
+//
+// https://www.w3.org/TR/webauthn-3/#sctn-android-safetynet-attestation
+type SafetyNetOptions struct {
+	// Roots used to validate the certificate that signed the SafetyNet
+	// response. This is generally the GlobalSign root certificate used by
+	// Google services.
+	Roots *x509.CertPool
+
+	// MaxTimestampSkew is the maximum difference between the response's
+	// timestamp and the current time. Defaults to one minute.
+	MaxTimestampSkew time.Duration
+
+	// Now returns the current time, used to validate the response timestamp and
+	// certificate chain. Defaults to time.Now.
+	Now func() time.Time
+}
+
+// SafetyNet holds a parsed android-safetynet attestation format.
+//
+// https://www.w3.org/TR/webauthn-3/#sctn-android-safetynet-attestation
+// https://developer.android.com/privacy-and-security/safetynet/attestation
+type SafetyNet struct {
+	// Parsed and validated authenticator data.
+	AttestationData *Attestation
+
+	// AttestationCertificate is the certificate used to sign the SafetyNet
+	// response, and chains up to a root certificate within the configuration.
+	AttestationCertificate *x509.Certificate
+
+	// Version of Google Play Services responsible for providing the SafetyNet
+	// API.
+	Version string
+
+	// Values reported by the SafetyNet response.
+	Timestamp                  time.Time
+	CTSProfileMatch            bool
+	BasicIntegrity             bool
+	APKPackageName             string
+	APKCertificateDigestSHA256 []string
+	EvaluationType             string
+}
+
+// VerifyAttestationSafetyNet is similar to VerifyAttestation, but additionally
+// performs validation of "android-safetynet" attestation statements.
+//
+// https://www.w3.org/TR/webauthn-3/#sctn-android-safetynet-attestation
+func (rp *RelyingParty) VerifyAttestationSafetyNet(challenge, clientDataJSON, attestationObject []byte, opts *SafetyNetOptions) (*SafetyNet, error) {
+	cd, err := rp.verifyClientData(clientDataJSON, "webauthn.create", challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	attObj, err := parseAttestationObject(attestationObject)
+	if err != nil {
+		return nil, fmt.Errorf("parsing attestation object: %v", err)
+	}
+
+	data, err := attObj.VerifySafetyNet(rp.ID, clientDataJSON, opts)
+	if err != nil {
+		return nil, fmt.Errorf("parsing authenticator data: %v", err)
+	}
+	if err := rp.verifyAttestationData(cd, data.AttestationData); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// VerifySafetyNet validates an attestation object and client JSON data against
+// an android-safetynet attestation statement.
+//
+// https://www.w3.org/TR/webauthn-3/#sctn-android-safetynet-attestation
+func (o *attestationObject) VerifySafetyNet(rpid string, clientDataJSON []byte, opts *SafetyNetOptions) (*SafetyNet, error) {
+	ad, err := parseAuthData(o.authData, rpid)
+	if err != nil {
+		return nil, fmt.Errorf("invalid auth data: %v", err)
+	}
+	sn, _, err := verifySafetyNet(ad, o.authData, o.attestationStatement, clientDataJSON, opts)
+	return sn, err
+}
+
+// verifySafetyNet validates an android-safetynet attestation statement against
+// already parsed authenticator data, returning the verified certificate chain
+// of the SafetyNet response, leaf first.
+func verifySafetyNet(ad *Attestation, authData, attStmt, clientDataJSON []byte, opts *SafetyNetOptions) (*SafetyNet, []*x509.Certificate, error) {
+	if opts == nil || opts.Roots == nil {
+		return nil, nil, fmt.Errorf("no root certificates provided")
+	}
+	now := time.Now
+	if opts.Now != nil {
+		now = opts.Now
+	}
+	maxSkew := time.Minute
+	if opts.MaxTimestampSkew != 0 {
+		maxSkew = opts.MaxTimestampSkew
+	}
+
+	ver, response, err := parseSafetyNet(attStmt)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid attestation statement: %v", err)
+	}
+
+	// "Verify that response is a valid SafetyNet response of version ver by
+	// following the steps indicated by the SafetyNet online documentation."
+	j, err := jws.Parse(response)
+	if err != nil {
+		return nil, nil, fmt.Errorf("parsing safetynet response: %v", err)
+	}
+	if len(j.Header.X5C) == 0 {
+		return nil, nil, fmt.Errorf("safetynet response contains no certificates")
+	}
+	x5c, err := parseCertificates(j.Header.X5C)
+	if err != nil {
+		return nil, nil, err
+	}
+	leaf := x5c[0]
+	if err := j.Verify(leaf.PublicKey); err != nil {
+		return nil, nil, fmt.Errorf("verifying safetynet response: %v", err)
+	}
+
+	// "Verify that the SafetyNet response actually came from the SafetyNet
+	// service by following the steps in the SafetyNet online documentation."
+	if err := leaf.VerifyHostname(safetyNetHostname); err != nil {
+		return nil, nil, fmt.Errorf("safetynet response not signed by %s: %v", safetyNetHostname, err)
+	}
+	v := x509.VerifyOptions{
+		Roots:       opts.Roots,
+		CurrentTime: now(),
+		KeyUsages:   []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	}
+	if len(x5c) > 1 {
+		v.Intermediates = x509.NewCertPool()
+		for _, cert := range x5c[1:] {
+			v.Intermediates.AddCert(cert)
+		}
+	}
+	if _, err := leaf.Verify(v); err != nil {
+		return nil, nil, fmt.Errorf("failed to verify safetynet certificate: %v", err)
+	}
+
+	var payload struct {
+		Nonce                      string   `json:"nonce"`
+		TimestampMs                int64    `json:"timestampMs"`
+		APKPackageName             string   `json:"apkPackageName"`
+		APKCertificateDigestSHA256 []string `json:"apkCertificateDigestSha256"`
+		CTSProfileMatch            bool     `json:"ctsProfileMatch"`
+		BasicIntegrity             bool     `json:"basicIntegrity"`
+		EvaluationType             string   `json:"evaluationType"`
+	}
+	if err := json.Unmarshal(j.Payload, &payload); err != nil {
+		return nil, nil, fmt.Errorf("parsing safetynet payload: %v", err)
+	}
+
+	// "Verify that the nonce attribute in the payload of response is identical
+	// to the Base64 encoding of the SHA-256 hash of the concatenation of
+	// authenticatorData and clientDataHash."
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	h := sha256.New()
+	h.Write(authData)
+	h.Write(clientDataHash[:])
+	if payload.Nonce != base64.StdEncoding.EncodeToString(h.Sum(nil)) {
+		return nil, nil, fmt.Errorf("safetynet nonce doesn't match attested data")
+	}
+	if !payload.CTSProfileMatch {
+		return nil, nil, fmt.Errorf("safetynet response reports device failed compatibility test suite profile")
+	}
+
+	ts := time.UnixMilli(payload.TimestampMs)
+	if skew := now().Sub(ts).Abs(); skew > maxSkew {
+		return nil, nil, fmt.Errorf("safetynet response timestamp %s outside of allowed skew %s", ts.UTC().Format(time.RFC3339), maxSkew)
+	}
+
+	return &SafetyNet{
+		AttestationData:            ad,
+		AttestationCertificate:     leaf,
+		Version:                    ver,
+		Timestamp:                  ts,
+		CTSProfileMatch:            payload.CTSProfileMatch,
+		BasicIntegrity:             payload.BasicIntegrity,
+		APKPackageName:             payload.APKPackageName,
+		APKCertificateDigestSHA256: payload.APKCertificateDigestSHA256,
+		EvaluationType:             payload.EvaluationType,
+	}, x5c, nil
+}
+
+// parseSafetyNet returns the version and JWS response of an android-safetynet
+// attestation statement.
+//
+// https://www.w3.org/TR/webauthn-3/#sctn-android-safetynet-attestation
+func parseSafetyNet(b []byte) (ver string, response []byte, err error) {
+	d := cbor.NewDecoder(b)
+	ok := d.Map(func(kv *cbor.Decoder) bool {
+		var key string
+		if !kv.String(&key) {
+			return false
+		}
+		switch key {
+		case "ver":
+			return kv.String(&ver)
+		case "response":
+			return kv.Bytes(&response)
+		default:
+			return kv.Skip()
+		}
+	}) && d.Done()
+	if !ok {
+		return "", nil, fmt.Errorf("attestation statement was not valid cbor")
+	}
+	if ver == "" {
+		return "", nil, fmt.Errorf("attestation statement didn't specify a version")
+	}
+	if len(response) == 0 {
+		return "", nil, fmt.Errorf("attestation statement didn't contain a response")
+	}
+	return ver, response, nil
+}
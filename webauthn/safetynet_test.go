@@ -0,0 +1,157 @@
+package webauthn
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testSafetyNetResponse signs a SafetyNet JWS response using ES256.
+func testSafetyNetResponse(t *testing.T, key *ecdsa.PrivateKey, cert *x509.Certificate, payload any) []byte {
+	t.Helper()
+	header, err := json.Marshal(map[string]any{
+		"alg": "ES256",
+		"x5c": [][]byte{cert.Raw},
+	})
+	if err != nil {
+		t.Fatalf("Encoding header: %v", err)
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		t.Fatalf("Encoding payload: %v", err)
+	}
+	input := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(body)
+	h := sha256.Sum256([]byte(input))
+	r, s, err := ecdsa.Sign(rand.Reader, key, h[:])
+	if err != nil {
+		t.Fatalf("Signing response: %v", err)
+	}
+	sig := append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	return []byte(input + "." + base64.RawURLEncoding.EncodeToString(sig))
+}
+
+func TestVerifyAttestationSafetyNet(t *testing.T) {
+	const (
+		rpID   = "localhost"
+		origin = "http://localhost:8080"
+	)
+	now := time.Now()
+
+	testCases := []struct {
+		name      string
+		hostname  string
+		timestamp time.Time
+		cts       bool
+		badNonce  bool
+		wantErr   string
+	}{
+		{
+			name:      "Valid",
+			hostname:  safetyNetHostname,
+			timestamp: now.Add(-10 * time.Second),
+			cts:       true,
+		},
+		{
+			name:      "Wrong hostname",
+			hostname:  "attest.example.com",
+			timestamp: now,
+			cts:       true,
+			wantErr:   "not signed by attest.android.com",
+		},
+		{
+			name:      "Stale timestamp",
+			hostname:  safetyNetHostname,
+			timestamp: now.Add(-time.Hour),
+			cts:       true,
+			wantErr:   "outside of allowed skew",
+		},
+		{
+			name:      "Failed CTS profile",
+			hostname:  safetyNetHostname,
+			timestamp: now,
+			cts:       false,
+			wantErr:   "compatibility test suite",
+		},
+		{
+			name:      "Bad nonce",
+			hostname:  safetyNetHostname,
+			timestamp: now,
+			cts:       true,
+			badNonce:  true,
+			wantErr:   "nonce doesn't match",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ca := newTestCA(t)
+			signingKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+			if err != nil {
+				t.Fatalf("Generating key: %v", err)
+			}
+			signingCert := ca.issue(t, &x509.Certificate{
+				Subject:  pkix.Name{CommonName: tc.hostname},
+				DNSNames: []string{tc.hostname},
+			}, signingKey.Public())
+
+			credKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+			if err != nil {
+				t.Fatalf("Generating key: %v", err)
+			}
+			challenge := []byte("safetynet-test-challenge")
+			clientDataJSON := testClientDataJSON("webauthn.create", origin, challenge)
+			clientDataHash := sha256.Sum256(clientDataJSON)
+			authData := testAuthData(t, rpID, 0x05, AAGUID{}, []byte("credential"), &credKey.PublicKey, ES256)
+
+			nonce := sha256.Sum256(append(append([]byte{}, authData...), clientDataHash[:]...))
+			if tc.badNonce {
+				nonce = sha256.Sum256([]byte("bad"))
+			}
+			response := testSafetyNetResponse(t, signingKey, signingCert, map[string]any{
+				"nonce":           base64.StdEncoding.EncodeToString(nonce[:]),
+				"timestampMs":     tc.timestamp.UnixMilli(),
+				"apkPackageName":  "com.google.android.gms",
+				"ctsProfileMatch": tc.cts,
+				"basicIntegrity":  true,
+			})
+			attStmt := cborMap(
+				cborString("ver"), cborString("200616037"),
+				cborString("response"), cborBytes(response),
+			)
+			attestationObject := testAttestationObject(FormatAndroidSafetyNet, attStmt, authData)
+
+			rp := &RelyingParty{ID: rpID, Origin: origin}
+			opts := &SafetyNetOptions{
+				Roots: ca.pool(),
+				Now:   func() time.Time { return now },
+			}
+			got, err := rp.VerifyAttestationSafetyNet(challenge, clientDataJSON, attestationObject, opts)
+			if tc.wantErr != "" {
+				if err == nil {
+					t.Fatalf("Expected error verifying attestation")
+				}
+				if !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("Verifying attestation returned unexpected error, got=%v, want=%s", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Verifying attestation: %v", err)
+			}
+			if got.APKPackageName != "com.google.android.gms" {
+				t.Errorf("Unexpected package name: %s", got.APKPackageName)
+			}
+			if got.Timestamp.UnixMilli() != tc.timestamp.UnixMilli() {
+				t.Errorf("Unexpected timestamp, got=%s, want=%s", got.Timestamp, tc.timestamp)
+			}
+		})
+	}
+}
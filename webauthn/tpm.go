@@ -0,0 +1,575 @@
+package webauthn
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	_ "crypto/sha1" // Used by TPM name algorithms.
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"slices"
+
+	"github.com/go-passkeys/go-passkeys/webauthn/internal/cbor"
+)
+
+var (
+	idSubjectAltName   = asn1.ObjectIdentifier{2, 5, 29, 17}
+	idTCGKPAIKCert     = asn1.ObjectIdentifier{2, 23, 133, 8, 3}
+	idTCGAtTPMManufact = asn1.ObjectIdentifier{2, 23, 133, 2, 1}
+	idTCGAtTPMModel    = asn1.ObjectIdentifier{2, 23, 133, 2, 2}
+	idTCGAtTPMVersion  = asn1.ObjectIdentifier{2, 23, 133, 2, 3}
+)
+
+// TPM algorithm and structure constants.
+//
+// https://trustedcomputinggroup.org/wp-content/uploads/TCG-_Algorithm_Registry_r1p32_pub.pdf
+// https://trustedcomputinggroup.org/wp-content/uploads/TPM-Rev-2.0-Part-2-Structures-01.38.pdf
+const (
+	tpmAlgRSA    = 0x0001
+	tpmAlgSHA1   = 0x0004
+	tpmAlgSHA256 = 0x000B
+	tpmAlgSHA384 = 0x000C
+	tpmAlgSHA512 = 0x000D
+	tpmAlgNull   = 0x0010
+	tpmAlgECC    = 0x0023
+
+	tpmECCNISTP256 = 0x0003
+	tpmECCNISTP384 = 0x0004
+	tpmECCNISTP521 = 0x0005
+
+	tpmGeneratedValue   = 0xff544347
+	tpmSTAttestCertify  = 0x8017
+	tpmRSADefaultPubExp = 65537
+)
+
+// TPMOptions allows configuration for validating TPM attestation statements.
+//
+// https://www.w3.org/TR/webauthn-3/#sctn-tpm-attestation
+type TPMOptions struct {
+	// GetRoots returns the root certificates for a given AAGUID. For example, by
+	// parsing the FIDO Alliance Metadata Service, which includes the TPM
+	// manufacturer roots used by Windows Hello.
+	//
+	// https://fidoalliance.org/metadata/
+	GetRoots func(aaguid AAGUID) (*x509.CertPool, error)
+}
+
+// TPM holds a parsed TPM attestation format. This format is used by
+// authenticators backed by a Trusted Platform Module, such as Windows Hello.
+//
+// https://www.w3.org/TR/webauthn-3/#sctn-tpm-attestation
+type TPM struct {
+	// Parsed and validated authenticator data.
+	AttestationData *Attestation
+
+	// AttestationCertificate is the attestation identity key (AIK) certificate
+	// that signed the attestation, and chains up to a root certificate within
+	// the configuration.
+	//
+	// https://www.w3.org/TR/webauthn-3/#sctn-tpm-cert-requirements
+	AttestationCertificate *x509.Certificate
+
+	// Manufacturer, model, and firmware version of the TPM as reported by the
+	// attestation certificate's subject alternative name. For example, the
+	// manufacturer "id:4E544300" or the version "id:00070002".
+	//
+	// https://trustedcomputinggroup.org/wp-content/uploads/TCG-EK-Credential-Profile-V-2.5-R2_published.pdf
+	Manufacturer string
+	Model        string
+	Version      string
+}
+
+// VerifyAttestationTPM is similar to VerifyAttestation, but additionally
+// performs validation of "tpm" attestation statements.
+//
+// TPM attestations are generally produced by platform authenticators, such as
+// Windows Hello, that store credentials within the device's TPM. Statements
+// signed using RS1 (SHA-1) are rejected.
+//
+// https://www.w3.org/TR/webauthn-3/#sctn-tpm-attestation
+func (rp *RelyingParty) VerifyAttestationTPM(challenge, clientDataJSON, attestationObject []byte, opts *TPMOptions) (*TPM, error) {
+	cd, err := rp.verifyClientData(clientDataJSON, "webauthn.create", challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	attObj, err := parseAttestationObject(attestationObject)
+	if err != nil {
+		return nil, fmt.Errorf("parsing attestation object: %v", err)
+	}
+
+	data, err := attObj.VerifyTPM(rp.ID, clientDataJSON, opts)
+	if err != nil {
+		return nil, fmt.Errorf("parsing authenticator data: %v", err)
+	}
+	if err := rp.verifyAttestationData(cd, data.AttestationData); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// VerifyTPM validates an attestation object and client JSON data against a TPM
+// attestation statement.
+//
+// https://www.w3.org/TR/webauthn-3/#sctn-tpm-attestation
+func (o *attestationObject) VerifyTPM(rpid string, clientDataJSON []byte, opts *TPMOptions) (*TPM, error) {
+	ad, err := parseAuthData(o.authData, rpid)
+	if err != nil {
+		return nil, fmt.Errorf("invalid auth data: %v", err)
+	}
+	t, _, err := verifyTPM(ad, o.authData, o.attestationStatement, clientDataJSON, opts)
+	return t, err
+}
+
+// verifyTPM validates a TPM attestation statement against already parsed
+// authenticator data, returning the verified certificate chain, leaf first.
+func verifyTPM(ad *Attestation, authData, attStmt, clientDataJSON []byte, opts *TPMOptions) (*TPM, []*x509.Certificate, error) {
+	if opts == nil || opts.GetRoots == nil {
+		return nil, nil, fmt.Errorf("no root certificates provided")
+	}
+
+	s, err := parseTPMStatement(attStmt)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid attestation statement: %v", err)
+	}
+
+	// "Verify that the public key specified by the parameters and unique fields
+	// of pubArea is identical to the credentialPublicKey in the
+	// attestedCredentialData in authenticatorData."
+	pubArea, err := parseTPMPublic(s.pubArea)
+	if err != nil {
+		return nil, nil, fmt.Errorf("parsing pubArea: %v", err)
+	}
+	credPub, ok := ad.PublicKey.(interface{ Equal(crypto.PublicKey) bool })
+	if !ok || !credPub.Equal(pubArea.pub) {
+		return nil, nil, fmt.Errorf("pubArea key doesn't match credential public key")
+	}
+
+	certInfo, err := parseTPMAttest(s.certInfo)
+	if err != nil {
+		return nil, nil, fmt.Errorf("parsing certInfo: %v", err)
+	}
+
+	// "Verify that extraData is set to the hash of attToBeSigned using the hash
+	// algorithm employed in "alg"."
+	alg := Algorithm(s.alg)
+	h, ok := alg.hash()
+	if !ok {
+		return nil, nil, fmt.Errorf("unsupported attestation algorithm: %s", alg)
+	}
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	hh := h.New()
+	hh.Write(authData)
+	hh.Write(clientDataHash[:])
+	if !bytes.Equal(hh.Sum(nil), certInfo.extraData) {
+		return nil, nil, fmt.Errorf("certInfo extraData doesn't match hash of attested data")
+	}
+
+	// "Verify that attested contains a TPMS_CERTIFY_INFO structure as specified
+	// in [TPMv2-Part2] section 10.12.3, whose name field contains a valid Name
+	// for pubArea, as computed using the algorithm in the nameAlg field of
+	// pubArea using the procedure specified in [TPMv2-Part1] section 16."
+	nameHash, ok := tpmHash(pubArea.nameAlg)
+	if !ok {
+		return nil, nil, fmt.Errorf("unsupported pubArea name algorithm: 0x%04x", pubArea.nameAlg)
+	}
+	nh := nameHash.New()
+	nh.Write(s.pubArea)
+	name := binary.BigEndian.AppendUint16(nil, pubArea.nameAlg)
+	name = nh.Sum(name)
+	if !bytes.Equal(name, certInfo.name) {
+		return nil, nil, fmt.Errorf("certInfo name doesn't match pubArea")
+	}
+
+	// "Verify the sig is a valid signature over certInfo using the attestation
+	// public key in aikCert with the algorithm specified in alg."
+	if len(s.x5c) == 0 {
+		return nil, nil, fmt.Errorf("attestation statement contains no certificates")
+	}
+	x5c, err := parseCertificates(s.x5c)
+	if err != nil {
+		return nil, nil, err
+	}
+	aikCert := x5c[0]
+	if err := verifySignature(aikCert.PublicKey, alg, s.certInfo, s.sig); err != nil {
+		return nil, nil, fmt.Errorf("verifying with attestation certificate: %v", err)
+	}
+
+	// "Verify that aikCert meets the requirements in § 8.3.1 TPM Attestation
+	// Statement Certificate Requirements."
+	//
+	// https://www.w3.org/TR/webauthn-3/#sctn-tpm-cert-requirements
+	if aikCert.Version != 3 {
+		return nil, nil, fmt.Errorf("attestation certificate uses version %d, must be version 3", aikCert.Version)
+	}
+	if len(aikCert.Subject.Names) != 0 {
+		return nil, nil, fmt.Errorf("attestation certificate subject must be empty: %s", aikCert.Subject)
+	}
+	manufacturer, model, version, err := tpmSubjectAltName(aikCert)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !slices.ContainsFunc(aikCert.UnknownExtKeyUsage, idTCGKPAIKCert.Equal) {
+		return nil, nil, fmt.Errorf("attestation certificate extended key usage doesn't contain tcg-kp-AIKCertificate")
+	}
+	if aikCert.IsCA {
+		return nil, nil, fmt.Errorf("attestation certificate basic constraints CA value must be set to false")
+	}
+
+	// "If aikCert contains an extension with OID 1.3.6.1.4.1.45724.1.1.4
+	// (id-fido-gen-ce-aaguid) verify that the value of this extension matches
+	// the aaguid in authenticatorData."
+	aaguid, ok, err := certificateAAGUID(aikCert)
+	if err != nil {
+		return nil, nil, err
+	}
+	if ok && aaguid != ad.AAGUID {
+		return nil, nil, fmt.Errorf("authenticator data aaguid (%s) doesn't match tpm certificate aaguid (%s)", ad.AAGUID, aaguid)
+	}
+
+	roots, err := opts.GetRoots(ad.AAGUID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// The subject alternative name is marked critical and only contains a
+	// directory name, which Go's x509 package doesn't process. Since it's been
+	// validated above, mark it as handled before verifying the chain.
+	leaf := *aikCert
+	leaf.UnhandledCriticalExtensions = slices.DeleteFunc(slices.Clone(leaf.UnhandledCriticalExtensions), idSubjectAltName.Equal)
+
+	v := x509.VerifyOptions{
+		Roots:     roots,
+		KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	}
+	if len(x5c) > 1 {
+		v.Intermediates = x509.NewCertPool()
+		for _, cert := range x5c[1:] {
+			v.Intermediates.AddCert(cert)
+		}
+	}
+	if _, err := leaf.Verify(v); err != nil {
+		return nil, nil, fmt.Errorf("failed to verify attestation certificate for provider %s: %v", ad.AAGUID, err)
+	}
+	return &TPM{
+		AttestationData:        ad,
+		AttestationCertificate: aikCert,
+		Manufacturer:           manufacturer,
+		Model:                  model,
+		Version:                version,
+	}, x5c, nil
+}
+
+// tpmSubjectAltName parses the TPM manufacturer, model, and version from the
+// subject alternative name of an attestation identity key certificate.
+//
+// https://www.w3.org/TR/webauthn-3/#sctn-tpm-cert-requirements
+func tpmSubjectAltName(cert *x509.Certificate) (manufacturer, model, version string, err error) {
+	var san []byte
+	for _, ext := range cert.Extensions {
+		if ext.Id.Equal(idSubjectAltName) {
+			san = ext.Value
+			break
+		}
+	}
+	if len(san) == 0 {
+		return "", "", "", fmt.Errorf("attestation certificate has no subject alternative name")
+	}
+
+	var names []asn1.RawValue
+	if rest, err := asn1.Unmarshal(san, &names); err != nil || len(rest) != 0 {
+		return "", "", "", fmt.Errorf("parsing attestation certificate subject alternative name")
+	}
+	for _, name := range names {
+		// directoryName [4] Name
+		if name.Class != asn1.ClassContextSpecific || name.Tag != 4 {
+			continue
+		}
+		var rdns pkix.RDNSequence
+		if rest, err := asn1.Unmarshal(name.Bytes, &rdns); err != nil || len(rest) != 0 {
+			return "", "", "", fmt.Errorf("parsing attestation certificate directory name")
+		}
+		for _, rdn := range rdns {
+			for _, atv := range rdn {
+				val, ok := atv.Value.(string)
+				if !ok {
+					continue
+				}
+				switch {
+				case atv.Type.Equal(idTCGAtTPMManufact):
+					manufacturer = val
+				case atv.Type.Equal(idTCGAtTPMModel):
+					model = val
+				case atv.Type.Equal(idTCGAtTPMVersion):
+					version = val
+				}
+			}
+		}
+	}
+	if manufacturer == "" || model == "" || version == "" {
+		return "", "", "", fmt.Errorf("attestation certificate subject alternative name must contain tpm manufacturer, model, and version")
+	}
+	return manufacturer, model, version, nil
+}
+
+func tpmHash(alg uint16) (crypto.Hash, bool) {
+	switch alg {
+	case tpmAlgSHA1:
+		return crypto.SHA1, true
+	case tpmAlgSHA256:
+		return crypto.SHA256, true
+	case tpmAlgSHA384:
+		return crypto.SHA384, true
+	case tpmAlgSHA512:
+		return crypto.SHA512, true
+	default:
+		return 0, false
+	}
+}
+
+type tpmStatement struct {
+	ver      string
+	alg      int64
+	x5c      [][]byte
+	sig      []byte
+	certInfo []byte
+	pubArea  []byte
+}
+
+// https://www.w3.org/TR/webauthn-3/#sctn-tpm-attestation
+func parseTPMStatement(b []byte) (*tpmStatement, error) {
+	d := cbor.NewDecoder(b)
+	s := &tpmStatement{}
+	ok := d.Map(func(kv *cbor.Decoder) bool {
+		var key string
+		if !kv.String(&key) {
+			return false
+		}
+		switch key {
+		case "ver":
+			return kv.String(&s.ver)
+		case "alg":
+			return kv.Int(&s.alg)
+		case "sig":
+			return kv.Bytes(&s.sig)
+		case "certInfo":
+			return kv.Bytes(&s.certInfo)
+		case "pubArea":
+			return kv.Bytes(&s.pubArea)
+		case "x5c":
+			return kv.Array(func(d *cbor.Decoder) bool {
+				var cert []byte
+				if !d.Bytes(&cert) {
+					return false
+				}
+				s.x5c = append(s.x5c, cert)
+				return true
+			})
+		default:
+			return kv.Skip()
+		}
+	}) && d.Done()
+	if !ok {
+		return nil, fmt.Errorf("attestation statement was not valid cbor")
+	}
+	if s.ver != "2.0" {
+		return nil, fmt.Errorf("unsupported tpm version: '%s'", s.ver)
+	}
+	if s.alg == 0 {
+		return nil, fmt.Errorf("attestation statement didn't specify an algorithm")
+	}
+	if len(s.sig) == 0 {
+		return nil, fmt.Errorf("attestation statement didn't contain a signature")
+	}
+	if len(s.certInfo) == 0 {
+		return nil, fmt.Errorf("attestation statement didn't contain certInfo")
+	}
+	if len(s.pubArea) == 0 {
+		return nil, fmt.Errorf("attestation statement didn't contain pubArea")
+	}
+	return s, nil
+}
+
+// tpmBuffer reads big endian encoded TPM structures.
+type tpmBuffer []byte
+
+func (b *tpmBuffer) uint16(n *uint16) bool {
+	if len(*b) < 2 {
+		return false
+	}
+	*n = binary.BigEndian.Uint16(*b)
+	*b = (*b)[2:]
+	return true
+}
+
+func (b *tpmBuffer) uint32(n *uint32) bool {
+	if len(*b) < 4 {
+		return false
+	}
+	*n = binary.BigEndian.Uint32(*b)
+	*b = (*b)[4:]
+	return true
+}
+
+func (b *tpmBuffer) skip(n int) bool {
+	if len(*b) < n {
+		return false
+	}
+	*b = (*b)[n:]
+	return true
+}
+
+// sized reads a TPM2B structure, a buffer prefixed by a 2 byte size.
+func (b *tpmBuffer) sized(out *[]byte) bool {
+	var n uint16
+	if !b.uint16(&n) || len(*b) < int(n) {
+		return false
+	}
+	*out = (*b)[:n]
+	*b = (*b)[n:]
+	return true
+}
+
+// algorithmScheme skips an algorithm identifier and, if the algorithm isn't
+// TPM_ALG_NULL, the given number of bytes of algorithm details.
+func (b *tpmBuffer) algorithmScheme(details int) bool {
+	var alg uint16
+	if !b.uint16(&alg) {
+		return false
+	}
+	if alg == tpmAlgNull {
+		return true
+	}
+	return b.skip(details)
+}
+
+type tpmPublic struct {
+	nameAlg uint16
+	pub     crypto.PublicKey
+}
+
+// parseTPMPublic parses a TPMT_PUBLIC structure.
+//
+// https://trustedcomputinggroup.org/wp-content/uploads/TPM-Rev-2.0-Part-2-Structures-01.38.pdf#page=138
+func parseTPMPublic(b []byte) (*tpmPublic, error) {
+	buf := tpmBuffer(b)
+	var (
+		typ        uint16
+		nameAlg    uint16
+		attributes uint32
+		authPolicy []byte
+	)
+	if !buf.uint16(&typ) ||
+		!buf.uint16(&nameAlg) ||
+		!buf.uint32(&attributes) ||
+		!buf.sized(&authPolicy) {
+		return nil, fmt.Errorf("malformed public area")
+	}
+
+	p := &tpmPublic{nameAlg: nameAlg}
+	switch typ {
+	case tpmAlgRSA:
+		// TPMS_RSA_PARMS
+		var (
+			keyBits  uint16
+			exponent uint32
+			n        []byte
+		)
+		if !buf.algorithmScheme(4) || // symmetric
+			!buf.algorithmScheme(2) || // scheme
+			!buf.uint16(&keyBits) ||
+			!buf.uint32(&exponent) ||
+			!buf.sized(&n) {
+			return nil, fmt.Errorf("malformed rsa parameters")
+		}
+		e := int(exponent)
+		if e == 0 {
+			e = tpmRSADefaultPubExp
+		}
+		p.pub = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: e}
+	case tpmAlgECC:
+		// TPMS_ECC_PARMS
+		var (
+			curveID uint16
+			x, y    []byte
+		)
+		if !buf.algorithmScheme(4) || // symmetric
+			!buf.algorithmScheme(2) || // scheme
+			!buf.uint16(&curveID) ||
+			!buf.algorithmScheme(2) || // kdf
+			!buf.sized(&x) ||
+			!buf.sized(&y) {
+			return nil, fmt.Errorf("malformed ecc parameters")
+		}
+		var curve elliptic.Curve
+		switch curveID {
+		case tpmECCNISTP256:
+			curve = elliptic.P256()
+		case tpmECCNISTP384:
+			curve = elliptic.P384()
+		case tpmECCNISTP521:
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported ecc curve: 0x%04x", curveID)
+		}
+		p.pub = &ecdsa.PublicKey{
+			Curve: curve,
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}
+	default:
+		return nil, fmt.Errorf("unsupported public area type: 0x%04x", typ)
+	}
+	if len(buf) != 0 {
+		return nil, fmt.Errorf("trailing data after public area")
+	}
+	return p, nil
+}
+
+type tpmAttest struct {
+	extraData []byte
+	name      []byte
+}
+
+// parseTPMAttest parses a TPMS_ATTEST structure, ensuring it holds a
+// TPMS_CERTIFY_INFO value.
+//
+// https://trustedcomputinggroup.org/wp-content/uploads/TPM-Rev-2.0-Part-2-Structures-01.38.pdf#page=125
+func parseTPMAttest(b []byte) (*tpmAttest, error) {
+	buf := tpmBuffer(b)
+	var (
+		magic           uint32
+		typ             uint16
+		qualifiedSigner []byte
+		a               tpmAttest
+		qualifiedName   []byte
+	)
+	if !buf.uint32(&magic) || !buf.uint16(&typ) {
+		return nil, fmt.Errorf("malformed attestation header")
+	}
+	if magic != tpmGeneratedValue {
+		return nil, fmt.Errorf("invalid magic value: 0x%08x", magic)
+	}
+	if typ != tpmSTAttestCertify {
+		return nil, fmt.Errorf("invalid attestation type: 0x%04x", typ)
+	}
+	if !buf.sized(&qualifiedSigner) ||
+		!buf.sized(&a.extraData) ||
+		!buf.skip(8+4+4+1) || // clockInfo
+		!buf.skip(8) || // firmwareVersion
+		!buf.sized(&a.name) ||
+		!buf.sized(&qualifiedName) {
+		return nil, fmt.Errorf("malformed attestation")
+	}
+	if len(buf) != 0 {
+		return nil, fmt.Errorf("trailing data after attestation")
+	}
+	return &a, nil
+}
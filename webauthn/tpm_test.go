@@ -0,0 +1,285 @@
+package webauthn
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/binary"
+	"strings"
+	"testing"
+)
+
+// tpm2b encodes a TPM2B sized buffer.
+func tpm2b(b []byte) []byte {
+	return append(binary.BigEndian.AppendUint16(nil, uint16(len(b))), b...)
+}
+
+func testTPMPubArea(pub *ecdsa.PublicKey) []byte {
+	b := binary.BigEndian.AppendUint16(nil, tpmAlgECC)
+	b = binary.BigEndian.AppendUint16(b, tpmAlgSHA256)
+	b = binary.BigEndian.AppendUint32(b, 0x00060472) // objectAttributes
+	b = append(b, tpm2b(nil)...)                     // authPolicy
+	b = binary.BigEndian.AppendUint16(b, tpmAlgNull) // symmetric
+	b = binary.BigEndian.AppendUint16(b, tpmAlgNull) // scheme
+	b = binary.BigEndian.AppendUint16(b, tpmECCNISTP256)
+	b = binary.BigEndian.AppendUint16(b, tpmAlgNull) // kdf
+	b = append(b, tpm2b(pub.X.FillBytes(make([]byte, 32)))...)
+	b = append(b, tpm2b(pub.Y.FillBytes(make([]byte, 32)))...)
+	return b
+}
+
+func testTPMRSAPubArea(pub *rsa.PublicKey) []byte {
+	b := binary.BigEndian.AppendUint16(nil, tpmAlgRSA)
+	b = binary.BigEndian.AppendUint16(b, tpmAlgSHA256)
+	b = binary.BigEndian.AppendUint32(b, 0x00060472) // objectAttributes
+	b = append(b, tpm2b(nil)...)                     // authPolicy
+	b = binary.BigEndian.AppendUint16(b, tpmAlgNull) // symmetric
+	b = binary.BigEndian.AppendUint16(b, tpmAlgNull) // scheme
+	b = binary.BigEndian.AppendUint16(b, uint16(pub.N.BitLen()))
+	b = binary.BigEndian.AppendUint32(b, 0) // exponent, zero for the default
+	b = append(b, tpm2b(pub.N.Bytes())...)
+	return b
+}
+
+// testTPMName returns the TPM Name of a public area using SHA-256.
+func testTPMName(pubArea []byte) []byte {
+	h := sha256.Sum256(pubArea)
+	return append([]byte{0x00, 0x0B}, h[:]...)
+}
+
+func testTPMCertInfo(name, extraData []byte) []byte {
+	b := binary.BigEndian.AppendUint32(nil, tpmGeneratedValue)
+	b = binary.BigEndian.AppendUint16(b, tpmSTAttestCertify)
+	b = append(b, tpm2b([]byte("signer"))...)
+	b = append(b, tpm2b(extraData)...)
+	b = append(b, make([]byte, 17)...) // clockInfo
+	b = append(b, make([]byte, 8)...)  // firmwareVersion
+	b = append(b, tpm2b(name)...)
+	b = append(b, tpm2b([]byte("qualified"))...)
+	return b
+}
+
+func testTPMSubjectAltName(t *testing.T) pkix.Extension {
+	t.Helper()
+	name, err := asn1.Marshal(pkix.RDNSequence{{
+		{Type: idTCGAtTPMManufact, Value: "id:54455354"},
+		{Type: idTCGAtTPMModel, Value: "TEST"},
+		{Type: idTCGAtTPMVersion, Value: "id:00010002"},
+	}})
+	if err != nil {
+		t.Fatalf("Encoding directory name: %v", err)
+	}
+	val, err := asn1.Marshal([]asn1.RawValue{{
+		Class:      asn1.ClassContextSpecific,
+		Tag:        4,
+		IsCompound: true,
+		Bytes:      name,
+	}})
+	if err != nil {
+		t.Fatalf("Encoding subject alternative name: %v", err)
+	}
+	return pkix.Extension{Id: idSubjectAltName, Critical: true, Value: val}
+}
+
+func TestVerifyAttestationTPM(t *testing.T) {
+	const (
+		rpID   = "localhost"
+		origin = "http://localhost:8080"
+	)
+	aaguid := mustParseAAGUID("08987058-cadc-4b81-b6e1-30de50dcbe96")
+
+	testCases := []struct {
+		name string
+		// Use an RSA credential key instead of an ECDSA one.
+		rsa bool
+		// Algorithm reported by the attestation statement. Defaults to ES256.
+		alg Algorithm
+		// Optional modifications to the attestation before it's signed.
+		modifyCert      func(tmpl *x509.Certificate)
+		modifyPubArea   func(pubArea []byte) []byte
+		modifyExtraData func(extraData []byte) []byte
+		modifyName      func(name []byte) []byte
+		wantErr         string
+	}{
+		{
+			name: "Valid",
+		},
+		{
+			name: "RSA pubArea",
+			rsa:  true,
+		},
+		{
+			name:    "RS1 signature",
+			alg:     algRS1,
+			wantErr: "unsupported attestation algorithm",
+		},
+		{
+			name: "Subject set",
+			modifyCert: func(tmpl *x509.Certificate) {
+				tmpl.Subject = pkix.Name{CommonName: "AIK"}
+			},
+			wantErr: "subject must be empty",
+		},
+		{
+			name: "Missing AIK extended key usage",
+			modifyCert: func(tmpl *x509.Certificate) {
+				tmpl.UnknownExtKeyUsage = nil
+			},
+			wantErr: "tcg-kp-AIKCertificate",
+		},
+		{
+			name: "Missing subject alternative name",
+			modifyCert: func(tmpl *x509.Certificate) {
+				tmpl.ExtraExtensions = nil
+			},
+			wantErr: "subject alternative name",
+		},
+		{
+			name: "CA certificate",
+			modifyCert: func(tmpl *x509.Certificate) {
+				tmpl.IsCA = true
+			},
+			wantErr: "basic constraints CA",
+		},
+		{
+			name: "Mismatched certificate aaguid",
+			modifyCert: func(tmpl *x509.Certificate) {
+				tmpl.ExtraExtensions = append(tmpl.ExtraExtensions,
+					aaguidExtension(t, mustParseAAGUID("ee882879-721c-4913-9775-3dfcce97072a")))
+			},
+			wantErr: "doesn't match tpm certificate aaguid",
+		},
+		{
+			name: "Mismatched extraData",
+			modifyExtraData: func(extraData []byte) []byte {
+				h := sha256.Sum256([]byte("other"))
+				return h[:]
+			},
+			wantErr: "certInfo extraData doesn't match",
+		},
+		{
+			name: "Mismatched name",
+			modifyName: func(name []byte) []byte {
+				h := sha256.Sum256([]byte("other"))
+				return append([]byte{0x00, 0x0B}, h[:]...)
+			},
+			wantErr: "certInfo name doesn't match",
+		},
+		{
+			name: "Mismatched pubArea",
+			modifyPubArea: func(pubArea []byte) []byte {
+				other, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+				if err != nil {
+					t.Fatalf("Generating key: %v", err)
+				}
+				return testTPMPubArea(&other.PublicKey)
+			},
+			wantErr: "pubArea key doesn't match",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ca := newTestCA(t)
+			aikKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+			if err != nil {
+				t.Fatalf("Generating key: %v", err)
+			}
+			var (
+				credPub interface{ Equal(crypto.PublicKey) bool }
+				credAlg Algorithm
+				pubArea []byte
+			)
+			if tc.rsa {
+				credKey, err := rsa.GenerateKey(rand.Reader, 2048)
+				if err != nil {
+					t.Fatalf("Generating key: %v", err)
+				}
+				credPub, credAlg, pubArea = &credKey.PublicKey, RS256, testTPMRSAPubArea(&credKey.PublicKey)
+			} else {
+				credKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+				if err != nil {
+					t.Fatalf("Generating key: %v", err)
+				}
+				credPub, credAlg, pubArea = &credKey.PublicKey, ES256, testTPMPubArea(&credKey.PublicKey)
+			}
+
+			tmpl := &x509.Certificate{
+				ExtraExtensions:    []pkix.Extension{testTPMSubjectAltName(t)},
+				UnknownExtKeyUsage: []asn1.ObjectIdentifier{idTCGKPAIKCert},
+			}
+			if tc.modifyCert != nil {
+				tc.modifyCert(tmpl)
+			}
+			aikCert := ca.issue(t, tmpl, aikKey.Public())
+
+			challenge := []byte("tpm-test-challenge")
+			clientDataJSON := testClientDataJSON("webauthn.create", origin, challenge)
+			clientDataHash := sha256.Sum256(clientDataJSON)
+			authData := testAuthData(t, rpID, 0x05, aaguid, []byte("credential"), credPub, credAlg)
+			digest := sha256.Sum256(append(append([]byte{}, authData...), clientDataHash[:]...))
+			extraData := digest[:]
+			if tc.modifyExtraData != nil {
+				extraData = tc.modifyExtraData(extraData)
+			}
+
+			if tc.modifyPubArea != nil {
+				pubArea = tc.modifyPubArea(pubArea)
+			}
+			name := testTPMName(pubArea)
+			if tc.modifyName != nil {
+				name = tc.modifyName(name)
+			}
+			certInfo := testTPMCertInfo(name, extraData)
+
+			alg := tc.alg
+			if alg == 0 {
+				alg = ES256
+			}
+			attStmt := cborMap(
+				cborString("ver"), cborString("2.0"),
+				cborString("alg"), cborInt(int64(alg)),
+				cborString("x5c"), cborCertificates(aikCert),
+				cborString("sig"), cborBytes(signES256(t, aikKey, certInfo)),
+				cborString("certInfo"), cborBytes(certInfo),
+				cborString("pubArea"), cborBytes(pubArea),
+			)
+			attestationObject := testAttestationObject(FormatTPM, attStmt, authData)
+
+			rp := &RelyingParty{ID: rpID, Origin: origin}
+			opts := &TPMOptions{
+				GetRoots: func(got AAGUID) (*x509.CertPool, error) {
+					if got != aaguid {
+						t.Errorf("GetRoots called with unexpected aaguid, got=%s, want=%s", got, aaguid)
+					}
+					return ca.pool(), nil
+				},
+			}
+			got, err := rp.VerifyAttestationTPM(challenge, clientDataJSON, attestationObject, opts)
+			if tc.wantErr != "" {
+				if err == nil {
+					t.Fatalf("Expected error verifying attestation")
+				}
+				if !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("Verifying attestation returned unexpected error, got=%v, want=%s", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Verifying attestation: %v", err)
+			}
+			if got.Manufacturer != "id:54455354" || got.Model != "TEST" || got.Version != "id:00010002" {
+				t.Errorf("Unexpected TPM information, got manufacturer=%s, model=%s, version=%s", got.Manufacturer, got.Model, got.Version)
+			}
+			if !credPub.Equal(got.AttestationData.PublicKey) {
+				t.Errorf("Attestation returned unexpected public key")
+			}
+		})
+	}
+}
@@ -0,0 +1,209 @@
+package webauthn
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/go-passkeys/go-passkeys/webauthn/internal/cbor"
+)
+
+// FIDOU2FOptions allows configuration for validating fido-u2f attestation
+// statements.
+//
+// https://www.w3.org/TR/webauthn-3/#sctn-fido-u2f-attestation
+type FIDOU2FOptions struct {
+	// GetRoots returns the root certificates for an attestation certificate.
+	// Because U2F authenticators don't report an AAGUID, certificates are
+	// identified by their key identifier: the hex encoded SHA-1 hash of the
+	// certificate's subject public key, as used by the "attestationCertificateKeyIdentifiers"
+	// field of the FIDO Alliance Metadata Service.
+	//
+	// https://fidoalliance.org/specs/mds/fido-metadata-statement-v3.0-ps-20210518.html#dom-metadatastatement-attestationcertificatekeyidentifiers
+	GetRoots func(keyID string) (*x509.CertPool, error)
+}
+
+// FIDOU2F holds a parsed fido-u2f attestation format. This format is used by
+// legacy security keys that only implement the FIDO U2F protocol.
+//
+// https://www.w3.org/TR/webauthn-3/#sctn-fido-u2f-attestation
+type FIDOU2F struct {
+	// Parsed and validated authenticator data.
+	AttestationData *Attestation
+
+	// AttestationCertificate is the certificate used to sign the attestation,
+	// and chains up to a root certificate within the configuration.
+	AttestationCertificate *x509.Certificate
+
+	// AttestationCertificateKeyID is the hex encoded SHA-1 hash of the
+	// attestation certificate's public key, used to identify the authenticator
+	// model in the FIDO Alliance Metadata Service.
+	AttestationCertificateKeyID string
+}
+
+// VerifyAttestationFIDOU2F is similar to VerifyAttestation, but additionally
+// performs validation of "fido-u2f" attestation statements.
+//
+// https://www.w3.org/TR/webauthn-3/#sctn-fido-u2f-attestation
+func (rp *RelyingParty) VerifyAttestationFIDOU2F(challenge, clientDataJSON, attestationObject []byte, opts *FIDOU2FOptions) (*FIDOU2F, error) {
+	cd, err := rp.verifyClientData(clientDataJSON, "webauthn.create", challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	attObj, err := parseAttestationObject(attestationObject)
+	if err != nil {
+		return nil, fmt.Errorf("parsing attestation object: %v", err)
+	}
+
+	data, err := attObj.VerifyFIDOU2F(rp.ID, clientDataJSON, opts)
+	if err != nil {
+		return nil, fmt.Errorf("parsing authenticator data: %v", err)
+	}
+	if err := rp.verifyAttestationData(cd, data.AttestationData); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// VerifyFIDOU2F validates an attestation object and client JSON data against a
+// fido-u2f attestation statement.
+//
+// https://www.w3.org/TR/webauthn-3/#sctn-fido-u2f-attestation
+func (o *attestationObject) VerifyFIDOU2F(rpid string, clientDataJSON []byte, opts *FIDOU2FOptions) (*FIDOU2F, error) {
+	ad, err := parseAuthData(o.authData, rpid)
+	if err != nil {
+		return nil, fmt.Errorf("invalid auth data: %v", err)
+	}
+	u, _, err := verifyFIDOU2F(ad, o.authData, o.attestationStatement, clientDataJSON, opts)
+	return u, err
+}
+
+// verifyFIDOU2F validates a fido-u2f attestation statement against already
+// parsed authenticator data, returning the verified certificate.
+func verifyFIDOU2F(ad *Attestation, authData, attStmt, clientDataJSON []byte, opts *FIDOU2FOptions) (*FIDOU2F, []*x509.Certificate, error) {
+	if opts == nil || opts.GetRoots == nil {
+		return nil, nil, fmt.Errorf("no root certificates provided")
+	}
+
+	sig, rawX5C, err := parseFIDOU2F(attStmt)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid attestation statement: %v", err)
+	}
+
+	// "Check that x5c has exactly one element and let attCert be that element.
+	// Let certificate public key be the public key conveyed by attCert. If
+	// certificate public key is not an Elliptic Curve (EC) public key over the
+	// P-256 curve, terminate this algorithm and return an appropriate error."
+	if len(rawX5C) != 1 {
+		return nil, nil, fmt.Errorf("attestation statement must contain exactly one certificate, got %d", len(rawX5C))
+	}
+	attCert, err := x509.ParseCertificate(rawX5C[0])
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid certificate: %v", err)
+	}
+	certPub, ok := attCert.PublicKey.(*ecdsa.PublicKey)
+	if !ok || certPub.Curve != elliptic.P256() {
+		return nil, nil, fmt.Errorf("attestation certificate must use a P-256 key")
+	}
+
+	// "Convert the COSE_KEY formatted credentialPublicKey to Raw ANSI X9.62
+	// public key format."
+	credPub, ok := ad.PublicKey.(*ecdsa.PublicKey)
+	if ad.Algorithm != ES256 || !ok || credPub.Curve != elliptic.P256() {
+		return nil, nil, fmt.Errorf("credential public key must be an ES256 P-256 key, got %s %T", ad.Algorithm, ad.PublicKey)
+	}
+	publicKeyU2F := []byte{0x04}
+	publicKeyU2F = append(publicKeyU2F, credPub.X.FillBytes(make([]byte, 32))...)
+	publicKeyU2F = append(publicKeyU2F, credPub.Y.FillBytes(make([]byte, 32))...)
+
+	// "Let verificationData be the concatenation of (0x00 || rpIdHash ||
+	// clientDataHash || credentialId || publicKeyU2F)."
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	data := []byte{0x00}
+	data = append(data, authData[:32]...)
+	data = append(data, clientDataHash[:]...)
+	data = append(data, ad.CredentialID...)
+	data = append(data, publicKeyU2F...)
+	if err := verifySignature(certPub, ES256, data, sig); err != nil {
+		return nil, nil, fmt.Errorf("verifying with attestation certificate: %v", err)
+	}
+
+	keyID, err := certificateKeyID(attCert)
+	if err != nil {
+		return nil, nil, err
+	}
+	roots, err := opts.GetRoots(keyID)
+	if err != nil {
+		return nil, nil, err
+	}
+	v := x509.VerifyOptions{
+		Roots:     roots,
+		KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	}
+	if _, err := attCert.Verify(v); err != nil {
+		return nil, nil, fmt.Errorf("failed to verify attestation certificate %s: %v", keyID, err)
+	}
+	return &FIDOU2F{
+		AttestationData:             ad,
+		AttestationCertificate:      attCert,
+		AttestationCertificateKeyID: keyID,
+	}, []*x509.Certificate{attCert}, nil
+}
+
+// certificateKeyID returns the hex encoded SHA-1 hash of the certificate's
+// subject public key.
+//
+// https://fidoalliance.org/specs/mds/fido-metadata-statement-v3.0-ps-20210518.html#dom-metadatastatement-attestationcertificatekeyidentifiers
+func certificateKeyID(cert *x509.Certificate) (string, error) {
+	var spki struct {
+		Algorithm asn1.RawValue
+		PublicKey asn1.BitString
+	}
+	if _, err := asn1.Unmarshal(cert.RawSubjectPublicKeyInfo, &spki); err != nil {
+		return "", fmt.Errorf("parsing certificate public key: %v", err)
+	}
+	h := sha1.Sum(spki.PublicKey.Bytes)
+	return hex.EncodeToString(h[:]), nil
+}
+
+// parseFIDOU2F returns the signature and certificate chain of a fido-u2f
+// attestation statement.
+//
+// https://www.w3.org/TR/webauthn-3/#sctn-fido-u2f-attestation
+func parseFIDOU2F(b []byte) (sig []byte, x5c [][]byte, err error) {
+	d := cbor.NewDecoder(b)
+	ok := d.Map(func(kv *cbor.Decoder) bool {
+		var key string
+		if !kv.String(&key) {
+			return false
+		}
+		switch key {
+		case "sig":
+			return kv.Bytes(&sig)
+		case "x5c":
+			return kv.Array(func(d *cbor.Decoder) bool {
+				var cert []byte
+				if !d.Bytes(&cert) {
+					return false
+				}
+				x5c = append(x5c, cert)
+				return true
+			})
+		default:
+			return kv.Skip()
+		}
+	}) && d.Done()
+	if !ok {
+		return nil, nil, fmt.Errorf("attestation statement was not valid cbor")
+	}
+	if len(sig) == 0 {
+		return nil, nil, fmt.Errorf("attestation statement didn't contain a signature")
+	}
+	return sig, x5c, nil
+}
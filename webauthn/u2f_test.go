@@ -0,0 +1,131 @@
+package webauthn
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"strings"
+	"testing"
+)
+
+func TestVerifyAttestationFIDOU2F(t *testing.T) {
+	const (
+		rpID   = "localhost"
+		origin = "http://localhost:8080"
+	)
+
+	testCases := []struct {
+		name    string
+		credKey func(t *testing.T) any
+		wantErr string
+	}{
+		{
+			name: "Valid",
+		},
+		{
+			name: "P-384 credential key",
+			credKey: func(t *testing.T) any {
+				k, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+				if err != nil {
+					t.Fatalf("Generating key: %v", err)
+				}
+				return &k.PublicKey
+			},
+			wantErr: "ES256 P-256",
+		},
+		{
+			name: "RSA credential key",
+			credKey: func(t *testing.T) any {
+				k, err := rsa.GenerateKey(rand.Reader, 2048)
+				if err != nil {
+					t.Fatalf("Generating key: %v", err)
+				}
+				return &k.PublicKey
+			},
+			wantErr: "ES256 P-256",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ca := newTestCA(t)
+			attKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+			if err != nil {
+				t.Fatalf("Generating key: %v", err)
+			}
+			attCert := ca.issue(t, &x509.Certificate{
+				Subject: pkix.Name{CommonName: "U2F Attestation"},
+			}, attKey.Public())
+
+			credKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+			if err != nil {
+				t.Fatalf("Generating key: %v", err)
+			}
+			var (
+				pub any = &credKey.PublicKey
+				alg     = ES256
+			)
+			if tc.credKey != nil {
+				pub = tc.credKey(t)
+				if _, ok := pub.(*rsa.PublicKey); ok {
+					alg = RS256
+				}
+			}
+
+			challenge := []byte("u2f-test-challenge")
+			clientDataJSON := testClientDataJSON("webauthn.create", origin, challenge)
+			clientDataHash := sha256.Sum256(clientDataJSON)
+			credID := []byte("u2f-credential")
+			authData := testAuthData(t, rpID, 0x01, AAGUID{}, credID, pub, alg)
+
+			rpIDHash := sha256.Sum256([]byte(rpID))
+			data := []byte{0x00}
+			data = append(data, rpIDHash[:]...)
+			data = append(data, clientDataHash[:]...)
+			data = append(data, credID...)
+			data = append(data, 0x04)
+			data = append(data, credKey.X.FillBytes(make([]byte, 32))...)
+			data = append(data, credKey.Y.FillBytes(make([]byte, 32))...)
+
+			attStmt := cborMap(
+				cborString("sig"), cborBytes(signES256(t, attKey, data)),
+				cborString("x5c"), cborCertificates(attCert),
+			)
+			attestationObject := testAttestationObject(FormatFIDOU2F, attStmt, authData)
+
+			wantKeyID, err := certificateKeyID(attCert)
+			if err != nil {
+				t.Fatalf("Computing key ID: %v", err)
+			}
+			rp := &RelyingParty{ID: rpID, Origin: origin}
+			opts := &FIDOU2FOptions{
+				GetRoots: func(keyID string) (*x509.CertPool, error) {
+					if keyID != wantKeyID {
+						t.Errorf("GetRoots called with unexpected key ID, got=%s, want=%s", keyID, wantKeyID)
+					}
+					return ca.pool(), nil
+				},
+			}
+			got, err := rp.VerifyAttestationFIDOU2F(challenge, clientDataJSON, attestationObject, opts)
+			if tc.wantErr != "" {
+				if err == nil {
+					t.Fatalf("Expected error verifying attestation")
+				}
+				if !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("Verifying attestation returned unexpected error, got=%v, want=%s", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Verifying attestation: %v", err)
+			}
+			if got.AttestationCertificateKeyID != wantKeyID {
+				t.Errorf("Unexpected key ID, got=%s, want=%s", got.AttestationCertificateKeyID, wantKeyID)
+			}
+		})
+	}
+}
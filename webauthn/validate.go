@@ -0,0 +1,114 @@
+package webauthn
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/go-passkeys/go-passkeys/webauthn/internal/publicsuffix"
+)
+
+// Validate checks the relying party's configuration, returning an error
+// describing origins or an ID that browsers would reject. It's intended to be
+// called at startup:
+//
+//	rp := &webauthn.RelyingParty{
+//		ID:     "example.com",
+//		Origin: "https://login.example.com",
+//	}
+//	if err := rp.Validate(); err != nil {
+//		log.Fatalf("Invalid relying party configuration: %v", err)
+//	}
+//
+// The ID must be a domain that isn't a public suffix, such as "com" or
+// "github.io". Origins must use HTTPS, except for "localhost", and their host
+// must be the ID or a subdomain of it. Android app origins are accepted in
+// Origins, and RelatedOrigins are checked by
+// [RelyingParty.RelatedOriginsDocument].
+//
+// https://www.w3.org/TR/webauthn-3/#rp-id
+// https://html.spec.whatwg.org/multipage/browsers.html#is-a-registrable-domain-suffix-of-or-is-equal-to
+func (rp *RelyingParty) Validate() error {
+	if err := validateRPID(rp.ID); err != nil {
+		return err
+	}
+	if rp.Origin == "" {
+		return fmt.Errorf("no origin configured")
+	}
+	if err := rp.validateOrigin(rp.Origin); err != nil {
+		return err
+	}
+	for _, origin := range rp.Origins {
+		if strings.HasPrefix(origin, androidOriginPrefix) {
+			continue
+		}
+		if err := rp.validateOrigin(origin); err != nil {
+			return err
+		}
+	}
+	if len(rp.RelatedOrigins) > 0 {
+		if _, err := rp.RelatedOriginsDocument(); err != nil {
+			return err
+		}
+	}
+	if len(rp.TopOrigins) > 0 && !rp.AllowCrossOrigin {
+		return fmt.Errorf("top origins configured without allowing cross-origin ceremonies")
+	}
+	switch rp.UserVerification {
+	case "", UserVerificationRequired, UserVerificationPreferred, UserVerificationDiscouraged:
+	default:
+		return fmt.Errorf("invalid user verification requirement: %s", rp.UserVerification)
+	}
+	return nil
+}
+
+// validateRPID checks that an RP ID is a lowercase domain that isn't a public
+// suffix.
+func validateRPID(id string) error {
+	if id == "" {
+		return fmt.Errorf("no relying party ID configured")
+	}
+	if id != strings.ToLower(id) {
+		return fmt.Errorf("relying party ID %s must be lowercase", id)
+	}
+	if strings.ContainsAny(id, ":/") || net.ParseIP(id) != nil {
+		return fmt.Errorf("relying party ID %s must be a domain, not an address or URL", id)
+	}
+	if id == "localhost" {
+		return nil
+	}
+	if _, err := publicsuffix.EffectiveTLDPlusOne(id); err != nil {
+		return fmt.Errorf("invalid relying party ID %s: %v", id, err)
+	}
+	return nil
+}
+
+// validateOrigin checks that an origin is a secure context whose host is the
+// RP ID or a subdomain of it. Hosts are compared case-insensitively.
+func (rp *RelyingParty) validateOrigin(origin string) error {
+	u, err := url.Parse(origin)
+	if err != nil {
+		return fmt.Errorf("parsing origin %s: %v", origin, err)
+	}
+	if u.Host == "" || u.Opaque != "" || u.User != nil || u.Path != "" || u.RawQuery != "" || u.Fragment != "" {
+		return fmt.Errorf("origin %s must only contain a scheme, host, and optional port", origin)
+	}
+	host := strings.ToLower(u.Hostname())
+	id := strings.ToLower(rp.ID)
+	switch u.Scheme {
+	case "https":
+	case "http":
+		// Browsers treat localhost as a secure context, allowing WebAuthn to
+		// be used without TLS during development.
+		if host != "localhost" && !strings.HasSuffix(host, ".localhost") {
+			return fmt.Errorf("origin %s must use https, http is only allowed for localhost", origin)
+		}
+	default:
+		return fmt.Errorf("origin %s must use https", origin)
+	}
+	if host != id && !strings.HasSuffix(host, "."+id) {
+		return fmt.Errorf("relying party ID %s is not a registrable suffix of origin %s", rp.ID, origin)
+	}
+	return nil
+}
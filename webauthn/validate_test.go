@@ -0,0 +1,155 @@
+package webauthn
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	testCases := []struct {
+		name    string
+		rp      *RelyingParty
+		wantErr string
+	}{
+		{
+			name: "Valid",
+			rp:   &RelyingParty{ID: "example.com", Origin: "https://example.com"},
+		},
+		{
+			name: "Subdomain origin",
+			rp:   &RelyingParty{ID: "example.com", Origin: "https://login.example.com:8443"},
+		},
+		{
+			name: "Mixed case origin",
+			rp:   &RelyingParty{ID: "example.com", Origin: "https://Login.Example.com"},
+		},
+		{
+			name: "Localhost",
+			rp:   &RelyingParty{ID: "localhost", Origin: "http://localhost:8080"},
+		},
+		{
+			name: "Private suffix subdomain",
+			rp:   &RelyingParty{ID: "foo.github.io", Origin: "https://foo.github.io"},
+		},
+		{
+			name: "Additional origins",
+			rp: &RelyingParty{
+				ID:     "example.com",
+				Origin: "https://example.com",
+				Origins: []string{
+					"https://www.example.com",
+					"android:apk-key-hash:-sYXRdwJA3hvue3mKpYrOZ9zSPC7b4mbgzJmdZEDO5w",
+				},
+				RelatedOrigins: []string{"https://example.co.uk"},
+			},
+		},
+		{
+			name:    "Missing ID",
+			rp:      &RelyingParty{Origin: "https://example.com"},
+			wantErr: "no relying party ID",
+		},
+		{
+			name:    "Missing origin",
+			rp:      &RelyingParty{ID: "example.com"},
+			wantErr: "no origin",
+		},
+		{
+			name:    "Public suffix",
+			rp:      &RelyingParty{ID: "co.uk", Origin: "https://example.co.uk"},
+			wantErr: "public suffix",
+		},
+		{
+			name:    "Private public suffix",
+			rp:      &RelyingParty{ID: "github.io", Origin: "https://foo.github.io"},
+			wantErr: "public suffix",
+		},
+		{
+			name:    "IP address",
+			rp:      &RelyingParty{ID: "127.0.0.1", Origin: "http://127.0.0.1:8080"},
+			wantErr: "must be a domain",
+		},
+		{
+			name:    "ID with port",
+			rp:      &RelyingParty{ID: "localhost:8080", Origin: "http://localhost:8080"},
+			wantErr: "must be a domain",
+		},
+		{
+			name:    "Uppercase ID",
+			rp:      &RelyingParty{ID: "Example.com", Origin: "https://example.com"},
+			wantErr: "lowercase",
+		},
+		{
+			name:    "HTTP origin",
+			rp:      &RelyingParty{ID: "example.com", Origin: "http://example.com"},
+			wantErr: "http is only allowed for localhost",
+		},
+		{
+			name:    "Origin with path",
+			rp:      &RelyingParty{ID: "example.com", Origin: "https://example.com/login"},
+			wantErr: "only contain a scheme",
+		},
+		{
+			name:    "Origin outside ID",
+			rp:      &RelyingParty{ID: "login.example.com", Origin: "https://example.com"},
+			wantErr: "not a registrable suffix",
+		},
+		{
+			name:    "Origin suffix without dot",
+			rp:      &RelyingParty{ID: "example.com", Origin: "https://badexample.com"},
+			wantErr: "not a registrable suffix",
+		},
+		{
+			name: "Additional origin outside ID",
+			rp: &RelyingParty{
+				ID:      "example.com",
+				Origin:  "https://example.com",
+				Origins: []string{"https://example.co.uk"},
+			},
+			wantErr: "not a registrable suffix",
+		},
+		{
+			name: "Invalid related origin",
+			rp: &RelyingParty{
+				ID:             "example.com",
+				Origin:         "https://example.com",
+				RelatedOrigins: []string{"http://example.co.uk"},
+			},
+			wantErr: "not an HTTPS origin",
+		},
+		{
+			name: "Top origins without cross-origin",
+			rp: &RelyingParty{
+				ID:         "example.com",
+				Origin:     "https://example.com",
+				TopOrigins: []string{"https://partner.example"},
+			},
+			wantErr: "without allowing cross-origin",
+		},
+		{
+			name: "Invalid user verification",
+			rp: &RelyingParty{
+				ID:               "example.com",
+				Origin:           "https://example.com",
+				UserVerification: "always",
+			},
+			wantErr: "invalid user verification requirement",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.rp.Validate()
+			if tc.wantErr != "" {
+				if err == nil {
+					t.Fatalf("Expected error validating relying party")
+				}
+				if !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("Validating relying party returned unexpected error, got=%v, want=%s", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Validating relying party: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package webauthn
+
+import "fmt"
+
+// UserPresenceError is returned when authenticator data doesn't report that
+// the user was present, such as by touching a security key, and the relying
+// party requires it. See [RelyingParty.UserPresenceOptional].
+//
+// https://www.w3.org/TR/webauthn-3/#concept-user-present
+type UserPresenceError struct {
+	// Flags reported by the authenticator.
+	Flags Flags
+}
+
+// Error implements the error interface.
+func (e *UserPresenceError) Error() string {
+	return fmt.Sprintf("user presence required but not reported by authenticator, flags: %s", e.Flags)
+}
+
+// UserVerificationError is returned when authenticator data doesn't report
+// that the user was verified, such as with a PIN or biometric, and the relying
+// party's [RelyingParty.UserVerification] is "required".
+//
+// https://www.w3.org/TR/webauthn-3/#concept-user-verified
+type UserVerificationError struct {
+	// Flags reported by the authenticator.
+	Flags Flags
+}
+
+// Error implements the error interface.
+func (e *UserVerificationError) Error() string {
+	return fmt.Sprintf("user verification required but not reported by authenticator, flags: %s", e.Flags)
+}
+
+// verifyFlags enforces the relying party's user presence and verification
+// requirements.
+//
+// https://www.w3.org/TR/webauthn-3/#sctn-registering-a-new-credential
+// https://www.w3.org/TR/webauthn-3/#sctn-verifying-assertion
+func (rp *RelyingParty) verifyFlags(flags Flags) error {
+	switch rp.UserVerification {
+	case "", UserVerificationPreferred, UserVerificationDiscouraged:
+	case UserVerificationRequired:
+		if !flags.UserVerified() {
+			return &UserVerificationError{Flags: flags}
+		}
+	default:
+		return fmt.Errorf("invalid user verification requirement: %s", rp.UserVerification)
+	}
+	if !rp.UserPresenceOptional && !flags.UserPresent() {
+		return &UserPresenceError{Flags: flags}
+	}
+	return nil
+}
+
+// verifyAttestationData records the client data of a credential creation on the
+// parsed attestation, and enforces the relying party's requirements on its
+// flags.
+func (rp *RelyingParty) verifyAttestationData(cd *clientData, att *Attestation) error {
+	if err := rp.verifyFlags(att.Flags); err != nil {
+		return err
+	}
+	att.CrossOrigin = cd.CrossOrigin
+	att.TopOrigin = cd.TopOrigin
+	return nil
+}
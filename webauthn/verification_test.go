@@ -0,0 +1,145 @@
+package webauthn
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestVerifyUserVerification(t *testing.T) {
+	const (
+		rpID   = "localhost"
+		origin = "http://localhost:8080"
+
+		up = Flags(1 << 0)
+		uv = Flags(1 << 2)
+	)
+	credKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Generating key: %v", err)
+	}
+
+	testCases := []struct {
+		name             string
+		userVerification string
+		presenceOptional bool
+		flags            Flags
+		wantPresenceErr  bool
+		wantVerifyErr    bool
+		wantErr          string
+	}{
+		{
+			name:  "Default",
+			flags: up,
+		},
+		{
+			name:             "Required",
+			userVerification: UserVerificationRequired,
+			flags:            up | uv,
+		},
+		{
+			name:             "Required not verified",
+			userVerification: UserVerificationRequired,
+			flags:            up,
+			wantVerifyErr:    true,
+		},
+		{
+			name:             "Preferred not verified",
+			userVerification: UserVerificationPreferred,
+			flags:            up,
+		},
+		{
+			name:             "Discouraged verified",
+			userVerification: UserVerificationDiscouraged,
+			flags:            up | uv,
+		},
+		{
+			name:            "User not present",
+			flags:           uv,
+			wantPresenceErr: true,
+		},
+		{
+			name:             "User presence optional",
+			presenceOptional: true,
+		},
+		{
+			name:             "Invalid requirement",
+			userVerification: "always",
+			flags:            up | uv,
+			wantErr:          "invalid user verification requirement",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rp := &RelyingParty{
+				ID:                   rpID,
+				Origin:               origin,
+				UserVerification:     tc.userVerification,
+				UserPresenceOptional: tc.presenceOptional,
+			}
+			checkErr := func(t *testing.T, err error) {
+				t.Helper()
+				var presenceErr *UserPresenceError
+				var verifyErr *UserVerificationError
+				switch {
+				case tc.wantPresenceErr:
+					if !errors.As(err, &presenceErr) {
+						t.Fatalf("Expected user presence error, got=%v", err)
+					}
+					if presenceErr.Flags.UserPresent() {
+						t.Errorf("Error reported user present flag: %s", presenceErr.Flags)
+					}
+				case tc.wantVerifyErr:
+					if !errors.As(err, &verifyErr) {
+						t.Fatalf("Expected user verification error, got=%v", err)
+					}
+					if verifyErr.Flags.UserVerified() {
+						t.Errorf("Error reported user verified flag: %s", verifyErr.Flags)
+					}
+				case tc.wantErr != "":
+					if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+						t.Fatalf("Unexpected error, got=%v, want=%s", err, tc.wantErr)
+					}
+				default:
+					if err != nil {
+						t.Fatalf("Unexpected error: %v", err)
+					}
+				}
+			}
+
+			challenge := []byte("registration")
+			clientDataJSON := testClientDataJSON("webauthn.create", origin, challenge)
+			authData := testAuthData(t, rpID, tc.flags, AAGUID{}, []byte("credential"), &credKey.PublicKey, ES256)
+			attestationObject := testAttestationObject(FormatNone, cborMap(), authData)
+			_, err := rp.VerifyAttestation(challenge, clientDataJSON, attestationObject)
+			checkErr(t, err)
+			_, err = rp.VerifyRegistration(challenge, clientDataJSON, attestationObject)
+			checkErr(t, err)
+
+			challenge = []byte("assertion")
+			clientDataJSON = testClientDataJSON("webauthn.get", origin, challenge)
+			authData, sig := testAssertion(t, credKey, rpID, tc.flags, nil, clientDataJSON)
+			_, err = rp.VerifyAssertion(&credKey.PublicKey, ES256, challenge, clientDataJSON, authData, sig)
+			checkErr(t, err)
+		})
+	}
+}
+
+func TestUserVerificationOptions(t *testing.T) {
+	rp := &RelyingParty{
+		ID:               "example.com",
+		Origin:           "https://example.com",
+		UserVerification: UserVerificationRequired,
+	}
+	creation := rp.NewCreationOptions([]byte("challenge"), UserEntity{ID: []byte("user")})
+	if creation.AuthenticatorSelection == nil || creation.AuthenticatorSelection.UserVerification != UserVerificationRequired {
+		t.Errorf("Creation options don't require user verification: %+v", creation.AuthenticatorSelection)
+	}
+	request := rp.NewRequestOptions([]byte("challenge"))
+	if request.UserVerification != UserVerificationRequired {
+		t.Errorf("Request options don't require user verification, got=%s", request.UserVerification)
+	}
+}
@@ -41,14 +41,22 @@ const (
 	RS512 Algorithm = -259
 )
 
+// algRS1 is RSASSA-PKCS1-v1_5 using SHA-1, which some TPMs use to sign
+// attestation statements. It's recognized so it can be reported by name, but
+// isn't supported since SHA-1 is no longer collision resistant.
+//
+// https://www.w3.org/TR/webauthn-3/#sctn-alg-identifier
+const algRS1 Algorithm = -65535
+
 var algStrings = map[Algorithm]string{
-	ES256: "ES256",
-	ES384: "ES384",
-	ES512: "ES512",
-	EdDSA: "EdDSA",
-	RS256: "RS256",
-	RS384: "RS384",
-	RS512: "RS512",
+	ES256:  "ES256",
+	ES384:  "ES384",
+	ES512:  "ES512",
+	EdDSA:  "EdDSA",
+	RS256:  "RS256",
+	RS384:  "RS384",
+	RS512:  "RS512",
+	algRS1: "RS1",
 }
 
 // Algorithm returns a human readable representation of the algorithm.
@@ -59,11 +67,32 @@ func (a Algorithm) String() string {
 	return fmt.Sprintf("Algorithm(0x%x)", int(a))
 }
 
+// hash returns the hash function used by the signing algorithm. RS1 isn't
+// supported and always returns false.
+func (a Algorithm) hash() (crypto.Hash, bool) {
+	switch a {
+	case ES256, RS256:
+		return crypto.SHA256, true
+	case ES384, RS384:
+		return crypto.SHA384, true
+	case ES512, RS512:
+		return crypto.SHA512, true
+	default:
+		return 0, false
+	}
+}
+
 // Attestation formats recognized by this package.
 const (
 	// Indicates that the authenticator didn't provide attestation.
-	FormatNone   = "none"
-	FormatPacked = "packed"
+	FormatNone             = "none"
+	FormatPacked           = "packed"
+	FormatTPM              = "tpm"
+	FormatAndroidKey       = "android-key"
+	FormatApple            = "apple"
+	FormatFIDOU2F          = "fido-u2f"
+	FormatAndroidSafetyNet = "android-safetynet"
+	FormatCompound         = "compound"
 )
 
 type attestationObject struct {
@@ -105,29 +134,137 @@ type RelyingParty struct {
 	// https://www.w3.org/TR/webauthn-3/#relying-party-identifier
 	ID string
 
+	// Name is a human readable name of the relying party, such as
+	// "Example Corp", passed to the browser in creation options.
+	//
+	// https://www.w3.org/TR/webauthn-3/#dom-publickeycredentialentity-name
+	Name string
+
 	// Origin is the base URL used by the browser when registering or challenging
 	// a credential. For example "https://login.example.com:8080"
 	Origin string
+
+	// Origins lists additional origins accepted alongside Origin, such as
+	// "https://www.example.com" or Android app origins returned by
+	// [AndroidOrigin]. iOS apps report the HTTPS origin of their associated
+	// domain, and don't need an entry of their own.
+	//
+	// https://www.w3.org/TR/webauthn-3/#sctn-validating-origin
+	Origins []string
+
+	// AllowSubdomains accepts any HTTPS origin whose host is the relying party
+	// ID or a subdomain of it, such as "https://app.example.com" for the ID
+	// "example.com". This should only be set if all subdomains are trusted.
+	AllowSubdomains bool
+
+	// RelatedOrigins lists origins with a different registrable domain than the
+	// relying party ID that are allowed to use it, such as
+	// "https://example.co.uk" for the ID "example.com". These origins are
+	// accepted during verification, and must also be published by serving
+	// [RelyingParty.RelatedOriginsDocument] from the RP ID.
+	//
+	// https://www.w3.org/TR/webauthn-3/#sctn-related-origins
+	RelatedOrigins []string
+
+	// AllowCrossOrigin accepts ceremonies performed in an iframe whose origin
+	// differs from the top-level page, such as a login form embedded in a
+	// partner site. By default, client data reporting "crossOrigin" is
+	// rejected.
+	//
+	// https://www.w3.org/TR/webauthn-3/#sctn-iframe-guidance
+	AllowCrossOrigin bool
+
+	// TopOrigins, when set, restricts cross-origin ceremonies to iframes
+	// embedded in the listed top-level origins, such as
+	// "https://partner.example". Ceremonies whose client data doesn't report
+	// a top origin are rejected.
+	//
+	// https://www.w3.org/TR/webauthn-3/#dom-collectedclientdata-toporigin
+	TopOrigins []string
+
+	// UserVerification is "required", "preferred", or "discouraged", and
+	// should match the value passed to the browser in options. When
+	// "required", attestations and assertions without the user verified flag
+	// are rejected with a [*UserVerificationError]. The default, "preferred",
+	// doesn't enforce user verification; callers can check
+	// [Flags.UserVerified] instead.
+	//
+	// https://www.w3.org/TR/webauthn-3/#enumdef-userverificationrequirement
+	UserVerification string
+
+	// UserPresenceOptional accepts attestations and assertions without the
+	// user present flag, such as credentials created through conditional
+	// mediation. By default, these are rejected with a [*UserPresenceError].
+	//
+	// https://www.w3.org/TR/webauthn-3/#concept-user-present
+	UserPresenceOptional bool
+
+	// AppID is the FIDO AppID used to register legacy U2F credentials, such as
+	// "https://example.com/app-id.json". When set, assertions scoped to the
+	// AppID are accepted by [RelyingParty.VerifyAuthenticationResponse] if the
+	// client reports using the "appid" extension, allowing U2F credentials to
+	// be used after migrating to WebAuthn. The same value should be passed to
+	// the client as the "appid" extension input.
+	//
+	// https://www.w3.org/TR/webauthn-3/#sctn-appid-extension
+	AppID string
+
+	// AttestationVerifiers maps attestation statement formats to the verifiers
+	// used by [RelyingParty.VerifyRegistration]. Formats not present fall back
+	// to built-in verifiers for "none" and self attested "packed" statements.
+	AttestationVerifiers map[string]AttestationVerifier
+
+	// AttestationPolicy, when set, is evaluated by
+	// [RelyingParty.VerifyRegistration] after the attestation statement has
+	// been verified.
+	AttestationPolicy *AttestationPolicy
+
+	// StatusPolicy, when set, is used by
+	// [RelyingParty.VerifyAuthenticatorStatus] to flag or reject credentials
+	// whose authenticator model was reported as compromised after
+	// registration.
+	StatusPolicy *StatusPolicy
+
+	// AAGUIDResolver is used by [RelyingParty.ResolveAAGUID] to describe
+	// authenticator models. If nil, only the compiled-in tables are used. See
+	// [DefaultAAGUIDResolver].
+	AAGUIDResolver AAGUIDResolver
 }
 
-// VerifyAttestation validates a credential creation attempt. attestationObject
-// and clientDataJSON arguments coorespond directly to the credential response
-// fields returned during creation. Challenge is the value passed to the creation
-// call used to prevent replay attacks.
-func (rp *RelyingParty) VerifyAttestation(challenge, clientDataJSON, attestationObject []byte) (*Attestation, error) {
+// verifyClientData parses clientDataJSON and validates the ceremony type,
+// origin, and challenge against the relying party's configuration.
+//
+// https://www.w3.org/TR/webauthn-3/#sctn-registering-a-new-credential
+// https://www.w3.org/TR/webauthn-3/#sctn-verifying-assertion
+func (rp *RelyingParty) verifyClientData(clientDataJSON []byte, typ string, challenge []byte) (*clientData, error) {
 	var clientData clientData
 	if err := json.Unmarshal(clientDataJSON, &clientData); err != nil {
 		return nil, fmt.Errorf("parsing client data: %v", err)
 	}
-	if clientData.Type != "webauthn.create" {
-		return nil, fmt.Errorf("invalid client data type, expected 'webauthn.create', got '%s'", clientData.Type)
+	if clientData.Type != typ {
+		return nil, fmt.Errorf("invalid client data type, expected '%s', got '%s'", typ, clientData.Type)
 	}
-	if clientData.Origin != rp.Origin {
-		return nil, fmt.Errorf("invalid client data origin, expected '%s', got '%s'", rp.Origin, clientData.Origin)
+	if err := rp.verifyOrigin(clientData.Origin); err != nil {
+		return nil, err
+	}
+	if err := rp.verifyCrossOrigin(&clientData); err != nil {
+		return nil, err
 	}
 	if !clientData.Challenge.Equal(challenge) {
 		return nil, fmt.Errorf("invalid client data challenge")
 	}
+	return &clientData, nil
+}
+
+// VerifyAttestation validates a credential creation attempt. attestationObject
+// and clientDataJSON arguments coorespond directly to the credential response
+// fields returned during creation. Challenge is the value passed to the creation
+// call used to prevent replay attacks.
+func (rp *RelyingParty) VerifyAttestation(challenge, clientDataJSON, attestationObject []byte) (*Attestation, error) {
+	cd, err := rp.verifyClientData(clientDataJSON, "webauthn.create", challenge)
+	if err != nil {
+		return nil, err
+	}
 
 	attObj, err := parseAttestationObject(attestationObject)
 	if err != nil {
@@ -138,6 +275,9 @@ func (rp *RelyingParty) VerifyAttestation(challenge, clientDataJSON, attestation
 	if err != nil {
 		return nil, fmt.Errorf("parsing authenticator data: %v", err)
 	}
+	if err := rp.verifyAttestationData(cd, data); err != nil {
+		return nil, err
+	}
 	return data, nil
 }
 
@@ -152,18 +292,9 @@ func (rp *RelyingParty) VerifyAttestation(challenge, clientDataJSON, attestation
 //
 // https://www.w3.org/TR/webauthn-3/#sctn-packed-attestation
 func (rp *RelyingParty) VerifyAttestationPacked(challenge, clientDataJSON, attestationObject []byte, opts *PackedOptions) (*Packed, error) {
-	var clientData clientData
-	if err := json.Unmarshal(clientDataJSON, &clientData); err != nil {
-		return nil, fmt.Errorf("parsing client data: %v", err)
-	}
-	if clientData.Type != "webauthn.create" {
-		return nil, fmt.Errorf("invalid client data type, expected 'webauthn.create', got '%s'", clientData.Type)
-	}
-	if clientData.Origin != rp.Origin {
-		return nil, fmt.Errorf("invalid client data origin, expected '%s', got '%s'", rp.Origin, clientData.Origin)
-	}
-	if !clientData.Challenge.Equal(challenge) {
-		return nil, fmt.Errorf("invalid client data challenge")
+	cd, err := rp.verifyClientData(clientDataJSON, "webauthn.create", challenge)
+	if err != nil {
+		return nil, err
 	}
 
 	attObj, err := parseAttestationObject(attestationObject)
@@ -175,6 +306,9 @@ func (rp *RelyingParty) VerifyAttestationPacked(challenge, clientDataJSON, attes
 	if err != nil {
 		return nil, fmt.Errorf("parsing authenticator data: %v", err)
 	}
+	if err := rp.verifyAttestationData(cd, data.AttestationData); err != nil {
+		return nil, err
+	}
 	return data, nil
 }
 
@@ -183,21 +317,24 @@ func (rp *RelyingParty) VerifyAttestationPacked(challenge, clientDataJSON, attes
 // The challenge is the value passed to the frontend to sign. authenticatorData,
 // clientDataJSON, and signature should be the values returned by the credential
 // asserstion.
+//
+// Assertions scoped to the relying party's AppID are rejected, since accepting
+// them requires the client's "appid" extension output. Use
+// [RelyingParty.VerifyAuthenticationResponse] to authenticate with U2F
+// credentials.
 func (rp *RelyingParty) VerifyAssertion(pub crypto.PublicKey, alg Algorithm, challenge, clientDataJSON, authData, sig []byte) (*Assertion, error) {
+	return rp.verifyAssertion(pub, alg, challenge, clientDataJSON, authData, sig, false)
+}
+
+// verifyAssertion validates an authentication assertion. appID reports if the
+// client returned true for the "appid" extension, in which case the assertion
+// may be scoped to either the relying party's RP ID or its AppID.
+func (rp *RelyingParty) verifyAssertion(pub crypto.PublicKey, alg Algorithm, challenge, clientDataJSON, authData, sig []byte, appID bool) (*Assertion, error) {
 	clientDataHash := sha256.Sum256(clientDataJSON)
 
-	var clientData clientData
-	if err := json.Unmarshal(clientDataJSON, &clientData); err != nil {
-		return nil, fmt.Errorf("parsing client data: %v", err)
-	}
-	if clientData.Type != "webauthn.get" {
-		return nil, fmt.Errorf("invalid client data type, expected 'webauthn.get', got '%s'", clientData.Type)
-	}
-	if clientData.Origin != rp.Origin {
-		return nil, fmt.Errorf("invalid client data origin, expected '%s', got '%s'", rp.Origin, clientData.Origin)
-	}
-	if !clientData.Challenge.Equal(challenge) {
-		return nil, fmt.Errorf("invalid client data challenge")
+	cd, err := rp.verifyClientData(clientDataJSON, "webauthn.get", challenge)
+	if err != nil {
+		return nil, err
 	}
 
 	data := append([]byte{}, authData...)
@@ -206,25 +343,58 @@ func (rp *RelyingParty) VerifyAssertion(pub crypto.PublicKey, alg Algorithm, cha
 		return nil, fmt.Errorf("invalid signature: %v", err)
 	}
 
-	rpIDHash := sha256.Sum256([]byte(rp.ID))
 	if len(authData) < 32 {
 		return nil, fmt.Errorf("not enough bytes for rpid hash")
 	}
+	rpIDHash := sha256.Sum256([]byte(rp.ID))
+	usedAppID := false
 	if !bytes.Equal(rpIDHash[:], authData[:32]) {
-		return nil, fmt.Errorf("assertion issued for different relying party")
+		// Credentials registered with the FIDO U2F APIs are scoped to the
+		// AppID. When the client reports using the "appid" extension, the
+		// authenticator may have used either identifier, so accept both.
+		//
+		// https://www.w3.org/TR/webauthn-3/#sctn-appid-extension
+		if !appID {
+			return nil, fmt.Errorf("assertion issued for different relying party")
+		}
+		if rp.AppID == "" {
+			return nil, fmt.Errorf("client used appid extension, but relying party has no AppID")
+		}
+		appIDHash := sha256.Sum256([]byte(rp.AppID))
+		if !bytes.Equal(appIDHash[:], authData[:32]) {
+			return nil, fmt.Errorf("assertion issued for different relying party")
+		}
+		usedAppID = true
 	}
 	if len(authData) < 32+1 {
 		return nil, fmt.Errorf("not enough bytes for flag")
 	}
 	flags := Flags(authData[32])
+	if err := rp.verifyFlags(flags); err != nil {
+		return nil, err
+	}
 	if len(authData) < 32+1+4 {
 		return nil, fmt.Errorf("not enough bytes for counter")
 	}
 
 	counter := binary.BigEndian.Uint32(authData[32+1 : 32+1+4])
+
+	var extensions []byte
+	if rest := authData[32+1+4:]; len(rest) > 0 {
+		extensions = rest
+	}
+	ext, err := verifyExtensions(flags, extensions)
+	if err != nil {
+		return nil, fmt.Errorf("parsing extensions: %v", err)
+	}
 	return &Assertion{
-		Flags:   flags,
-		Counter: counter,
+		Flags:                   flags,
+		Counter:                 counter,
+		AppID:                   usedAppID,
+		CrossOrigin:             cd.CrossOrigin,
+		TopOrigin:               cd.TopOrigin,
+		Extensions:              extensions,
+		AuthenticatorExtensions: ext,
 	}, nil
 }
 
@@ -246,7 +416,8 @@ type PackedOptions struct {
 	AllowSelfAttested bool
 
 	// GetRoots returns the root certificates for a given AAGUID. For example, by
-	// parsing the FIDO Alliance Metadata Service.
+	// parsing the FIDO Alliance Metadata Service. The RootProvider type of the
+	// metadata package implements this function.
 	//
 	// https://fidoalliance.org/metadata/
 	GetRoots func(aaguid AAGUID) (*x509.CertPool, error)
@@ -278,30 +449,38 @@ type Packed struct {
 //
 // https://www.w3.org/TR/webauthn-3/#sctn-packed-attestation
 func (o *attestationObject) VerifyPacked(rpid string, clientDataJSON []byte, opts *PackedOptions) (*Packed, error) {
+	ad, err := parseAuthData(o.authData, rpid)
+	if err != nil {
+		return nil, fmt.Errorf("invalid auth data: %v", err)
+	}
+	p, _, err := verifyPacked(ad, o.authData, o.attestationStatement, clientDataJSON, opts)
+	return p, err
+}
+
+// verifyPacked validates a packed attestation statement against already parsed
+// authenticator data. It returns the verified certificate chain, leaf first,
+// which is empty for self attestation.
+func verifyPacked(ad *Attestation, authData, attStmt, clientDataJSON []byte, opts *PackedOptions) (*Packed, []*x509.Certificate, error) {
 	if opts == nil {
-		return nil, fmt.Errorf("options must be provided")
+		return nil, nil, fmt.Errorf("options must be provided")
 	}
 	if !opts.AllowSelfAttested && opts.GetRoots == nil {
-		return nil, fmt.Errorf("self attested not allowed and no root certificates provided")
+		return nil, nil, fmt.Errorf("self attested not allowed and no root certificates provided")
 	}
 
-	p, err := parsePacked(o.attestationStatement)
+	p, err := parsePacked(attStmt)
 	if err != nil {
-		return nil, fmt.Errorf("invalid attestation statement: %v", err)
-	}
-	ad, err := parseAuthData(o.authData, rpid)
-	if err != nil {
-		return nil, fmt.Errorf("invalid auth data: %v", err)
+		return nil, nil, fmt.Errorf("invalid attestation statement: %v", err)
 	}
 
 	// https://www.w3.org/TR/webauthn-3/#collectedclientdata-hash-of-the-serialized-client-data
 	clientDataHash := sha256.Sum256(clientDataJSON)
-	data := append([]byte{}, o.authData...)
+	data := append([]byte{}, authData...)
 	data = append(data, clientDataHash[:]...)
 
 	if len(p.x5c) == 0 {
 		if !opts.AllowSelfAttested {
-			return nil, fmt.Errorf("attestation statement is self attested, which is not permitted by packed validation config")
+			return nil, nil, fmt.Errorf("attestation statement is self attested, which is not permitted by packed validation config")
 		}
 
 		// "If self attestation is in use, the authenticator produces sig by
@@ -311,21 +490,17 @@ func (o *attestationObject) VerifyPacked(rpid string, clientDataJSON []byte, opt
 		//
 		// https://www.w3.org/TR/webauthn-3/#sctn-packed-attestation
 		if err := verifySignature(ad.PublicKey, ad.Algorithm, data, p.sig); err != nil {
-			return nil, fmt.Errorf("verifying self-attested data: %v", err)
+			return nil, nil, fmt.Errorf("verifying self-attested data: %v", err)
 		}
 		return &Packed{
 			AttestationData: ad,
 			SelfAttested:    true,
-		}, nil
+		}, nil, nil
 	}
 
-	var x5c []*x509.Certificate
-	for _, rawCert := range p.x5c {
-		cert, err := x509.ParseCertificate(rawCert)
-		if err != nil {
-			return nil, fmt.Errorf("invalid certificate: %v", err)
-		}
-		x5c = append(x5c, cert)
+	x5c, err := parseCertificates(p.x5c)
+	if err != nil {
+		return nil, nil, err
 	}
 
 	// "Verify that sig is a valid signature over the concatenation of
@@ -336,7 +511,7 @@ func (o *attestationObject) VerifyPacked(rpid string, clientDataJSON []byte, opt
 
 	pub := attCert.PublicKey
 	if err := verifySignature(pub, Algorithm(p.alg), data, p.sig); err != nil {
-		return nil, fmt.Errorf("verifying with attestation certificate: %v", err)
+		return nil, nil, fmt.Errorf("verifying with attestation certificate: %v", err)
 	}
 
 	// "Verify that attestnCert meets the requirements in § 8.2.1 Packed
@@ -346,44 +521,34 @@ func (o *attestationObject) VerifyPacked(rpid string, clientDataJSON []byte, opt
 
 	if attCert.Version != 3 {
 		// Version MUST be set to 3 (which is indicated by an ASN.1 INTEGER with value 2).
-		return nil, fmt.Errorf("attestation certificate uses version %d, must be version 3", attCert.Version)
+		return nil, nil, fmt.Errorf("attestation certificate uses version %d, must be version 3", attCert.Version)
 	}
 
 	ou := attCert.Subject.OrganizationalUnit
 	if len(ou) != 1 || ou[0] != "Authenticator Attestation" {
-		return nil, fmt.Errorf("attestation certificate Subject-OU must be set to the string 'Authenticator Attestation': %s", ou)
+		return nil, nil, fmt.Errorf("attestation certificate Subject-OU must be set to the string 'Authenticator Attestation': %s", ou)
 	}
 	if attCert.IsCA {
-		return nil, fmt.Errorf("attestation certificate basic constraints CA value must be set to false")
+		return nil, nil, fmt.Errorf("attestation certificate basic constraints CA value must be set to false")
 	}
 
-	var aaguidExt []byte
-	for _, ext := range attCert.Extensions {
-		if ext.Id.Equal(idFIDOGenCEAAGUIDOID) {
-			aaguidExt = ext.Value
-			break
-		}
-	}
-	if len(aaguidExt) == 0 {
-		return nil, fmt.Errorf("no id-fido-gen-ce-aaguid extension in attestation certifiate")
-	}
-	var aaguidRaw []byte
-	if _, err := asn1.Unmarshal(aaguidExt, &aaguidRaw); err != nil {
-		return nil, fmt.Errorf("failed to parse id-fido-gen-ce-aaguid extension in attestation certifiate: %v", err)
+	aaguid, ok, err := certificateAAGUID(attCert)
+	if err != nil {
+		return nil, nil, err
 	}
-	if len(aaguidRaw) != 16 {
-		return nil, fmt.Errorf("expected id-fido-gen-ce-aaguid extension to be a 16 byte value, got %d", len(aaguidRaw))
+	if !ok {
+		return nil, nil, fmt.Errorf("no id-fido-gen-ce-aaguid extension in attestation certifiate")
 	}
-	var aaguid AAGUID
-	copy(aaguid[:], aaguidRaw[:])
-
 	if aaguid != ad.AAGUID {
-		return nil, fmt.Errorf("authenticator data aaguid (%s) doesn't match packed certificate aaguid (%s)", ad.AAGUID, aaguid)
+		return nil, nil, fmt.Errorf("authenticator data aaguid (%s) doesn't match packed certificate aaguid (%s)", ad.AAGUID, aaguid)
 	}
 
+	if opts.GetRoots == nil {
+		return nil, nil, fmt.Errorf("attestation statement contains a certificate chain, but no root certificates provided")
+	}
 	roots, err := opts.GetRoots(aaguid)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	v := x509.VerifyOptions{
@@ -396,12 +561,53 @@ func (o *attestationObject) VerifyPacked(rpid string, clientDataJSON []byte, opt
 		}
 	}
 	if _, err := attCert.Verify(v); err != nil {
-		return nil, fmt.Errorf("failed to verify attestation certificate for provider %s: %v", aaguid, err)
+		return nil, nil, fmt.Errorf("failed to verify attestation certificate for provider %s: %v", aaguid, err)
 	}
 	return &Packed{
 		AttestationData:        ad,
 		AttestationCertificate: attCert,
-	}, nil
+	}, x5c, nil
+}
+
+// parseCertificates parses the DER encoded "x5c" certificate chain of an
+// attestation statement.
+func parseCertificates(x5c [][]byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	for _, rawCert := range x5c {
+		cert, err := x509.ParseCertificate(rawCert)
+		if err != nil {
+			return nil, fmt.Errorf("invalid certificate: %v", err)
+		}
+		certs = append(certs, cert)
+	}
+	return certs, nil
+}
+
+// certificateAAGUID returns the value of the id-fido-gen-ce-aaguid extension of
+// an attestation certificate, or false if the extension isn't present.
+//
+// https://www.w3.org/TR/webauthn-3/#sctn-packed-attestation-cert-requirements
+func certificateAAGUID(cert *x509.Certificate) (AAGUID, bool, error) {
+	var aaguidExt []byte
+	for _, ext := range cert.Extensions {
+		if ext.Id.Equal(idFIDOGenCEAAGUIDOID) {
+			aaguidExt = ext.Value
+			break
+		}
+	}
+	if len(aaguidExt) == 0 {
+		return AAGUID{}, false, nil
+	}
+	var aaguidRaw []byte
+	if _, err := asn1.Unmarshal(aaguidExt, &aaguidRaw); err != nil {
+		return AAGUID{}, false, fmt.Errorf("failed to parse id-fido-gen-ce-aaguid extension in attestation certifiate: %v", err)
+	}
+	if len(aaguidRaw) != 16 {
+		return AAGUID{}, false, fmt.Errorf("expected id-fido-gen-ce-aaguid extension to be a 16 byte value, got %d", len(aaguidRaw))
+	}
+	var aaguid AAGUID
+	copy(aaguid[:], aaguidRaw[:])
+	return aaguid, true, nil
 }
 
 func verifySignature(pub crypto.PublicKey, alg Algorithm, data, sig []byte) error {
@@ -437,11 +643,16 @@ func verifySignature(pub crypto.PublicKey, alg Algorithm, data, sig []byte) erro
 			return fmt.Errorf("invalid ES512 signature")
 		}
 	case EdDSA:
-		ed25519Pub, ok := pub.(*ed25519.PublicKey)
-		if !ok {
+		var ed25519Pub ed25519.PublicKey
+		switch pub := pub.(type) {
+		case ed25519.PublicKey:
+			ed25519Pub = pub
+		case *ed25519.PublicKey:
+			ed25519Pub = *pub
+		default:
 			return fmt.Errorf("invalid public key type for EdDSA algorithm: %T", pub)
 		}
-		if !ed25519.Verify(*ed25519Pub, data, sig) {
+		if !ed25519.Verify(ed25519Pub, data, sig) {
 			return fmt.Errorf("invalid EdDSA signature")
 		}
 	case RS256:
@@ -585,6 +796,26 @@ type Assertion struct {
 	//
 	// https://www.w3.org/TR/webauthn-3/#sctn-sign-counter
 	Counter uint32
+
+	// AppID reports if the assertion was scoped to the relying party's AppID
+	// rather than its RP ID, indicating a credential registered through the
+	// FIDO U2F APIs.
+	AppID bool
+
+	// CrossOrigin reports if the ceremony was performed in an iframe whose
+	// origin differs from the top-level page, and TopOrigin holds the origin
+	// of that page. See [RelyingParty.AllowCrossOrigin].
+	//
+	// https://www.w3.org/TR/webauthn-3/#dom-collectedclientdata-crossorigin
+	// https://www.w3.org/TR/webauthn-3/#dom-collectedclientdata-toporigin
+	CrossOrigin bool
+	TopOrigin   string
+
+	// Raw extension data.
+	Extensions []byte
+	// AuthenticatorExtensions holds the parsed extension data, or nil if the
+	// authenticator didn't return extensions.
+	AuthenticatorExtensions *AuthenticatorExtensions
 }
 
 // Attestation holds information about an individual credential. This data is
@@ -634,8 +865,20 @@ type Attestation struct {
 	// serialize this value.
 	PublicKey crypto.PublicKey
 
+	// CrossOrigin reports if the ceremony was performed in an iframe whose
+	// origin differs from the top-level page, and TopOrigin holds the origin
+	// of that page. See [RelyingParty.AllowCrossOrigin].
+	//
+	// https://www.w3.org/TR/webauthn-3/#dom-collectedclientdata-crossorigin
+	// https://www.w3.org/TR/webauthn-3/#dom-collectedclientdata-toporigin
+	CrossOrigin bool
+	TopOrigin   string
+
 	// Raw extension data.
 	Extensions []byte
+	// AuthenticatorExtensions holds the parsed extension data, or nil if the
+	// authenticator didn't return extensions.
+	AuthenticatorExtensions *AuthenticatorExtensions
 }
 
 // https://developers.yubico.com/FIDO/yubico-metadata.json
@@ -786,6 +1029,11 @@ func parseAuthData(b []byte, rpid string) (*Attestation, error) {
 	if !d.Done() {
 		ad.Extensions = d.Rest()
 	}
+	ext, err := verifyExtensions(ad.Flags, ad.Extensions)
+	if err != nil {
+		return nil, fmt.Errorf("parsing extensions: %v", err)
+	}
+	ad.AuthenticatorExtensions = ext
 	return &ad, nil
 }
 
@@ -1,13 +1,22 @@
 package webauthn
 
 import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
 	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
 	"encoding/base64"
-	"encoding/json"
-	"fmt"
-	"os"
+	"encoding/binary"
+	"math/big"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestVerifyAttestation(t *testing.T) {
@@ -89,76 +98,6 @@ func TestVerifyAttestation(t *testing.T) {
 	}
 }
 
-func TestVerifyAttestationPacked(t *testing.T) {
-	testCases := []struct {
-		name              string
-		rp                *RelyingParty
-		challenge         string
-		clientData        string
-		attestationObject string
-	}{
-		{
-			name: "YubiKey 5 Series",
-			rp: &RelyingParty{
-				ID:     "localhost",
-				Origin: "http://localhost:8080",
-			},
-			challenge:         "-ium4NdjLD6Acqy9p66NtA",
-			clientData:        `{"type":"webauthn.create","challenge":"-ium4NdjLD6Acqy9p66NtA","origin":"http://localhost:8080","crossOrigin":false}`,
-			attestationObject: "o2NmbXRmcGFja2VkZ2F0dFN0bXSjY2FsZyZjc2lnWEgwRgIhAL7ex0WTU1ZpLSRhoTxNxaYbwYcaNEA/h9eJEp0weJEqAiEA1vMTwi4bkvkE/gzQDO1seRyw0SupYth902MWOpZ0TDpjeDVjgVkC3TCCAtkwggHBoAMCAQICCQCkQGRCP4Vr/DANBgkqhkiG9w0BAQsFADAuMSwwKgYDVQQDEyNZdWJpY28gVTJGIFJvb3QgQ0EgU2VyaWFsIDQ1NzIwMDYzMTAgFw0xNDA4MDEwMDAwMDBaGA8yMDUwMDkwNDAwMDAwMFowbzELMAkGA1UEBhMCU0UxEjAQBgNVBAoMCVl1YmljbyBBQjEiMCAGA1UECwwZQXV0aGVudGljYXRvciBBdHRlc3RhdGlvbjEoMCYGA1UEAwwfWXViaWNvIFUyRiBFRSBTZXJpYWwgMTExMzg2NjQwNDBZMBMGByqGSM49AgEGCCqGSM49AwEHA0IABPkOtta+hbyNLleVf1puWkTqbHzBJz+y42wVbN881zPGfYHty7riyxT4c3fcoXK+bl1/XE7f/2D3I3WT9ILQVYOjgYEwfzATBgorBgEEAYLECg0BBAUEAwUHATAiBgkrBgEEAYLECgIEFTEuMy42LjEuNC4xLjQxNDgyLjEuNzATBgsrBgEEAYLlHAIBAQQEAwIFIDAhBgsrBgEEAYLlHAEBBAQSBBAZCDw9g4NLGLwDjxyasv0bMAwGA1UdEwEB/wQCMAAwDQYJKoZIhvcNAQELBQADggEBAHzCOWZTA+e+ni1+kmfydBAZgdLyWGbYLQxlJtjd00qbh6M41UaYuRm12eKm3uYDgPT1BnVqqGN69k/1+P91O+knuRBfb48El12Up1hfzyON1UKGgBA6IdmghqYbK+X5baMMLGdsZ1nLKEWjVRecjLg79GwHy9HJ25j+Gb7+yNZMJdfgMJvfrecD35Tgmw+3fTCbzpnlW9Sp/LNdkHjdECaicue3MdhtrwaVmNfyVNvU5mqHzQAH2zf4/TsTZKdx2aIDFmqZZAartwD7RskFfQpnN0CWU6uCaBS0ECgDPLLW3q39mfvJ/y2rHPhaSWue85+2lNK+NJPP43ZsNrA7Rw5oYXV0aERhdGFYwkmWDeWIDoxodDQXD2R2YFuP5K65ooYyx5lc87qDHZdjxQAAAAMZCDw9g4NLGLwDjxyasv0bADDC4gNtuVFFZvyU4A2YDTFDSAOHTXQfTVUeXPpK2xTdoFx6LnSx3o2dcheLtBrEj0ylAQIDJiABIVggwuIDbblRRWb8lOANmAK3w9dppoKQXC2rw7yY6c9W/C4iWCBp5XU3NpH55RWYheccEtji/4Yc+zscmwMQN+KrQ/o7/qFrY3JlZFByb3RlY3QD",
-		},
-		{
-			name: "Chrome local",
-			rp: &RelyingParty{
-				ID:     "localhost",
-				Origin: "http://localhost:8080",
-			},
-			challenge:         "8XJI5cQqW-VqtSPO7JIpUg",
-			clientData:        `{"type":"webauthn.create","challenge":"8XJI5cQqW-VqtSPO7JIpUg","origin":"http://localhost:8080","crossOrigin":false}`,
-			attestationObject: "o2NmbXRmcGFja2VkZ2F0dFN0bXSiY2FsZyZjc2lnWEcwRQIhAJdhPjKXQAoWBgBDw+tu8q2WpTrXLULwFBgpJGu0SLI7AiA493f+tIVJkf9oeSX24FsSHJqkNKYmph2IAD7wSzTMAGhhdXRoRGF0YVikSZYN5YgOjGh0NBcPZHZgW4/krrmihjLHmVzzuoMdl2NFAAAAAK3OAAI1vMYKZIsLJfHwVQMAIGfNA5n4RSq0gsGzIB6kmazzLLe0goRP+1QG4uixw+zTpQECAyYgASFYIJtUv3C9FxTn1i7xALbGQJjzDkyFECHaHQ5+KYom9eh9IlggCfXDLnVZU9KEKuhqdPInGHcfAlZSCTOeRWSUzrSkkHo=",
-		},
-	}
-
-	blobData, err := os.ReadFile("testdata/blob.jwt")
-	if err != nil {
-		t.Fatalf("loading metadata blob: %v", err)
-	}
-	parts := strings.Split(string(blobData), ".")
-	if len(parts) != 3 {
-		t.Fatalf("Failed to parse blob JWT, expected 3 parts got %d", len(parts))
-	}
-	mdRaw, err := base64.RawURLEncoding.DecodeString(parts[1])
-	if err != nil {
-		t.Fatalf("Failed to decode JWT: %v", err)
-	}
-
-	md := &metadata{}
-	if err := json.Unmarshal(mdRaw, md); err != nil {
-		t.Fatalf("parsing metadata blob: %v", err)
-	}
-	opts := &PackedOptions{
-		GetRoots:          md.getRoots,
-		AllowSelfAttested: true,
-	}
-
-	for _, tc := range testCases {
-		t.Run(tc.name, func(t *testing.T) {
-			challenge, err := base64.RawURLEncoding.DecodeString(tc.challenge)
-			if err != nil {
-				t.Fatalf("Parsing challenge: %v", err)
-			}
-			attestationObject, err := base64.StdEncoding.DecodeString(tc.attestationObject)
-			if err != nil {
-				t.Fatalf("Parsing attestation object: %v", err)
-			}
-			clientDataJSON := []byte(tc.clientData)
-			if _, err := tc.rp.VerifyAttestationPacked(challenge, clientDataJSON, attestationObject, opts); err != nil {
-				t.Errorf("Verifying attestation: %v", err)
-			}
-		})
-	}
-}
-
 func TestVerifyAuthentication(t *testing.T) {
 	const (
 		UP   = 1
@@ -271,48 +210,370 @@ func TestVerifyAuthentication(t *testing.T) {
 	}
 }
 
-// metadata is a parsed FIDO metadata Service BLOB, and can be used to validate
-// the certificate chain of "packed" attestations.
-//
-// https://fidoalliance.org/metadata/
-type metadata struct {
-	Entries []*metadataEntry `json:"entries"`
-}
-
-func (m *metadata) getRoots(aaguid AAGUID) (*x509.CertPool, error) {
-	for _, ent := range m.Entries {
-		if ent.Metadata.AAGUID != aaguid {
-			continue
-		}
+func TestVerifyAssertionAppID(t *testing.T) {
+	const (
+		rpID   = "example.com"
+		origin = "https://example.com"
+		appID  = "https://example.com/app-id.json"
+	)
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Generating key: %v", err)
+	}
+	credID := []byte("credential")
+	challenge := []byte("challenge")
+	clientDataJSON := testClientDataJSON("webauthn.get", origin, challenge)
 
-		pool := x509.NewCertPool()
-		for _, cert := range ent.Metadata.AttestationRootCertificates {
-			data, err := base64.StdEncoding.DecodeString(cert)
-			if err != nil {
-				return nil, fmt.Errorf("decoding certificate base64 for aaguid %s: %v", aaguid, err)
+	testCases := []struct {
+		name      string
+		appID     string
+		scope     string
+		clientExt *ClientExtensionResults
+		wantAppID bool
+		wantErr   string
+	}{
+		{
+			name:  "RP ID",
+			appID: appID,
+			scope: rpID,
+		},
+		{
+			name:      "RP ID with appid false",
+			appID:     appID,
+			scope:     rpID,
+			clientExt: &ClientExtensionResults{AppID: newBool(false)},
+		},
+		{
+			name:      "AppID",
+			appID:     appID,
+			scope:     appID,
+			clientExt: &ClientExtensionResults{AppID: newBool(true)},
+			wantAppID: true,
+		},
+		{
+			name:    "AppID without client output",
+			appID:   appID,
+			scope:   appID,
+			wantErr: "different relying party",
+		},
+		{
+			name:      "AppID with appid false",
+			appID:     appID,
+			scope:     appID,
+			clientExt: &ClientExtensionResults{AppID: newBool(false)},
+			wantErr:   "different relying party",
+		},
+		{
+			name:      "RP ID with appid true",
+			appID:     appID,
+			scope:     rpID,
+			clientExt: &ClientExtensionResults{AppID: newBool(true)},
+		},
+		{
+			name:      "AppID not configured",
+			scope:     appID,
+			clientExt: &ClientExtensionResults{AppID: newBool(true)},
+			wantErr:   "relying party has no AppID",
+		},
+		{
+			name:      "Different AppID",
+			appID:     "https://other.example.com/app-id.json",
+			scope:     appID,
+			clientExt: &ClientExtensionResults{AppID: newBool(true)},
+			wantErr:   "different relying party",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rp := &RelyingParty{ID: rpID, Origin: origin, AppID: tc.appID}
+			authData, sig := testAssertion(t, key, tc.scope, 0x01, nil, clientDataJSON)
+			resp := &AuthenticationResponse{
+				ID:    base64.RawURLEncoding.EncodeToString(credID),
+				RawID: credID,
+				Type:  PublicKeyCredentialType,
+				Response: AuthenticatorAssertionResponse{
+					ClientDataJSON:    clientDataJSON,
+					AuthenticatorData: authData,
+					Signature:         sig,
+				},
+				ClientExtensionResults: tc.clientExt,
+			}
+			got, err := rp.VerifyAuthenticationResponse(&key.PublicKey, ES256, challenge, resp)
+			if tc.wantErr != "" {
+				if err == nil {
+					t.Fatalf("Expected error verifying assertion")
+				}
+				if !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("Verifying assertion returned unexpected error, got=%v, want=%s", err, tc.wantErr)
+				}
+				return
 			}
-			cert, err := x509.ParseCertificate(data)
 			if err != nil {
-				return nil, fmt.Errorf("parsing certificate for aaguid %s: %v", aaguid, err)
+				t.Fatalf("Verifying assertion: %v", err)
+			}
+			if got.AppID != tc.wantAppID {
+				t.Errorf("Unexpected AppID match, got=%t, want=%t", got.AppID, tc.wantAppID)
 			}
-			pool.AddCert(cert)
+		})
+	}
+
+	// Without client extension results, VerifyAssertion only accepts the RP
+	// ID.
+	rp := &RelyingParty{ID: rpID, Origin: origin, AppID: appID}
+	authData, sig := testAssertion(t, key, appID, 0x01, nil, clientDataJSON)
+	if _, err := rp.VerifyAssertion(&key.PublicKey, ES256, challenge, clientDataJSON, authData, sig); err == nil {
+		t.Errorf("Expected error verifying AppID scoped assertion without client extension results")
+	}
+}
+
+func TestVerifyAssertionEdDSA(t *testing.T) {
+	rp := &RelyingParty{ID: "localhost", Origin: "http://localhost:8080"}
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Generating key: %v", err)
+	}
+	challenge := []byte("challenge")
+	clientDataJSON := testClientDataJSON("webauthn.get", rp.Origin, challenge)
+	rpIDHash := sha256.Sum256([]byte(rp.ID))
+	authData := append(rpIDHash[:], 0x01, 0, 0, 0, 1)
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	sig := ed25519.Sign(priv, append(append([]byte{}, authData...), clientDataHash[:]...))
+
+	// Public keys parsed from attestation objects are values, but accept
+	// pointers as well.
+	for _, key := range []crypto.PublicKey{pub, &pub} {
+		if _, err := rp.VerifyAssertion(key, EdDSA, challenge, clientDataJSON, authData, sig); err != nil {
+			t.Errorf("Verifying assertion with %T: %v", key, err)
 		}
-		return pool, nil
 	}
-	return nil, fmt.Errorf("no certificates found for aaguid: %s", aaguid)
 }
 
-// https://fidoalliance.org/specs/mds/fido-metadata-service-v3.0-ps-20210518.html
-type metadataEntry struct {
-	AAID     string            `json:"aaid"`
-	AAGUID   AAGUID            `json:"aaguid"`
-	KeyIDs   []string          `json:"attestationCertificateKeyIdentifiers"`
-	Metadata metadataStatement `json:"metadataStatement"`
+// The following helpers construct attestation objects for formats where
+// captured test vectors aren't available, such as TPM or Android devices.
+
+func cborHeader(typ byte, n uint64) []byte {
+	switch {
+	case n < 24:
+		return []byte{typ<<5 | byte(n)}
+	case n <= 0xff:
+		return []byte{typ<<5 | 24, byte(n)}
+	case n <= 0xffff:
+		return binary.BigEndian.AppendUint16([]byte{typ<<5 | 25}, uint16(n))
+	case n <= 0xffffffff:
+		return binary.BigEndian.AppendUint32([]byte{typ<<5 | 26}, uint32(n))
+	default:
+		return binary.BigEndian.AppendUint64([]byte{typ<<5 | 27}, n)
+	}
+}
+
+func cborInt(n int64) []byte {
+	if n < 0 {
+		return cborHeader(1, uint64(-1-n))
+	}
+	return cborHeader(0, uint64(n))
+}
+
+func cborBytes(b []byte) []byte {
+	return append(cborHeader(2, uint64(len(b))), b...)
+}
+
+func cborString(s string) []byte {
+	return append(cborHeader(3, uint64(len(s))), s...)
+}
+
+func cborBool(b bool) []byte {
+	if b {
+		return []byte{0xf5}
+	}
+	return []byte{0xf4}
+}
+
+func cborArray(vals ...[]byte) []byte {
+	b := cborHeader(4, uint64(len(vals)))
+	for _, v := range vals {
+		b = append(b, v...)
+	}
+	return b
+}
+
+// cborMap encodes a map from alternating keys and values.
+func cborMap(kvs ...[]byte) []byte {
+	b := cborHeader(5, uint64(len(kvs)/2))
+	for _, kv := range kvs {
+		b = append(b, kv...)
+	}
+	return b
+}
+
+func cborCertificates(certs ...*x509.Certificate) []byte {
+	var vals [][]byte
+	for _, cert := range certs {
+		vals = append(vals, cborBytes(cert.Raw))
+	}
+	return cborArray(vals...)
+}
+
+// coseKey encodes a public key in its COSE representation.
+//
+// https://www.w3.org/TR/webauthn-3/#sctn-encoded-credPubKey-examples
+func coseKey(t *testing.T, pub crypto.PublicKey, alg Algorithm) []byte {
+	t.Helper()
+	switch pub := pub.(type) {
+	case *ecdsa.PublicKey:
+		crv := map[elliptic.Curve]int64{
+			elliptic.P256(): 1,
+			elliptic.P384(): 2,
+			elliptic.P521(): 3,
+		}[pub.Curve]
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		return cborMap(
+			cborInt(1), cborInt(2),
+			cborInt(3), cborInt(int64(alg)),
+			cborInt(-1), cborInt(crv),
+			cborInt(-2), cborBytes(pub.X.FillBytes(make([]byte, size))),
+			cborInt(-3), cborBytes(pub.Y.FillBytes(make([]byte, size))),
+		)
+	case *rsa.PublicKey:
+		return cborMap(
+			cborInt(1), cborInt(3),
+			cborInt(3), cborInt(int64(alg)),
+			cborInt(-1), cborBytes(pub.N.Bytes()),
+			cborInt(-2), cborBytes(big.NewInt(int64(pub.E)).Bytes()),
+		)
+	default:
+		t.Fatalf("Unsupported public key type: %T", pub)
+		return nil
+	}
+}
+
+// testAuthData constructs authenticator data with attested credential data.
+//
+// https://www.w3.org/TR/webauthn-3/#sctn-authenticator-data
+func testAuthData(t *testing.T, rpID string, flags Flags, aaguid AAGUID, credID []byte, pub crypto.PublicKey, alg Algorithm) []byte {
+	t.Helper()
+	rpIDHash := sha256.Sum256([]byte(rpID))
+	b := append([]byte{}, rpIDHash[:]...)
+	b = append(b, byte(flags|Flags(1<<6)))
+	b = binary.BigEndian.AppendUint32(b, 0)
+	b = append(b, aaguid[:]...)
+	b = binary.BigEndian.AppendUint16(b, uint16(len(credID)))
+	b = append(b, credID...)
+	b = append(b, coseKey(t, pub, alg)...)
+	return b
+}
+
+// testAssertion constructs authenticator data for an assertion scoped to the
+// provided identifier, returning the data and its signature over the client
+// data.
+func testAssertion(t *testing.T, key *ecdsa.PrivateKey, id string, flags Flags, ext, clientDataJSON []byte) (authData, sig []byte) {
+	t.Helper()
+	idHash := sha256.Sum256([]byte(id))
+	authData = append([]byte{}, idHash[:]...)
+	authData = append(authData, byte(flags))
+	authData = binary.BigEndian.AppendUint32(authData, 1)
+	authData = append(authData, ext...)
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	digest := sha256.Sum256(append(append([]byte{}, authData...), clientDataHash[:]...))
+	sig, err := ecdsa.SignASN1(rand.Reader, key, digest[:])
+	if err != nil {
+		t.Fatalf("Signing assertion: %v", err)
+	}
+	return authData, sig
+}
+
+func testAttestationObject(format string, attStmt, authData []byte) []byte {
+	return cborMap(
+		cborString("fmt"), cborString(format),
+		cborString("attStmt"), attStmt,
+		cborString("authData"), cborBytes(authData),
+	)
+}
+
+func testClientDataJSON(typ, origin string, challenge []byte) []byte {
+	return []byte(`{"type":"` + typ + `","challenge":"` + base64.RawURLEncoding.EncodeToString(challenge) + `","origin":"` + origin + `","crossOrigin":false}`)
+}
+
+// testCA is a certificate authority used to issue attestation certificates.
+type testCA struct {
+	cert *x509.Certificate
+	key  crypto.Signer
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Generating key: %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test Attestation Root"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, key.Public(), key)
+	if err != nil {
+		t.Fatalf("Creating certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("Parsing certificate: %v", err)
+	}
+	return &testCA{cert, key}
+}
+
+func (ca *testCA) pool() *x509.CertPool {
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+	return pool
+}
+
+// issue signs a leaf certificate for the provided public key.
+func (ca *testCA) issue(t *testing.T, tmpl *x509.Certificate, pub crypto.PublicKey) *x509.Certificate {
+	t.Helper()
+	if tmpl.SerialNumber == nil {
+		tmpl.SerialNumber = big.NewInt(2)
+	}
+	if tmpl.NotBefore.IsZero() {
+		tmpl.NotBefore = ca.cert.NotBefore
+	}
+	if tmpl.NotAfter.IsZero() {
+		tmpl.NotAfter = ca.cert.NotAfter
+	}
+	tmpl.BasicConstraintsValid = true
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, pub, ca.key)
+	if err != nil {
+		t.Fatalf("Creating certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("Parsing certificate: %v", err)
+	}
+	return cert
+}
+
+func aaguidExtension(t *testing.T, aaguid AAGUID) pkix.Extension {
+	t.Helper()
+	val, err := asn1.Marshal(aaguid[:])
+	if err != nil {
+		t.Fatalf("Encoding aaguid extension: %v", err)
+	}
+	return pkix.Extension{Id: idFIDOGenCEAAGUIDOID, Value: val}
+}
+
+func signES256(t *testing.T, key *ecdsa.PrivateKey, data []byte) []byte {
+	t.Helper()
+	h := sha256.Sum256(data)
+	sig, err := ecdsa.SignASN1(rand.Reader, key, h[:])
+	if err != nil {
+		t.Fatalf("Signing data: %v", err)
+	}
+	return sig
 }
 
-// https://fidoalliance.org/specs/mds/fido-metadata-statement-v3.0-ps-20210518.html#metadata-keys
-type metadataStatement struct {
-	AAGUID                      AAGUID   `json:"aaguid"`
-	Description                 string   `json:"description"`
-	AttestationRootCertificates []string `json:"attestationRootCertificates"`
+func newBool(b bool) *bool {
+	return &b
 }